@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// errAttachmentTooLarge means the object is over MAX_ATTACH_BYTES and is shared as a link instead
+var errAttachmentTooLarge = errors.New("object exceeds MAX_ATTACH_BYTES")
+
+// s3ObjectAPI is the subset of the S3 client used to download attachments and TEMPLATE_S3_URI
+type s3ObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// newS3ObjectClient creates the S3 client for object downloads; tests replace it with a stub
+var newS3ObjectClient = func(ctx context.Context) (s3ObjectAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(awsCfg), nil
+}
+
+// canAttach reports whether the file should be uploaded to Discord rather than linked
+func (d *Dispatcher) canAttach(payload FilePayload) bool {
+	return d.Config.AttachFiles &&
+		d.Config.Platform == platformDiscord &&
+		!payload.isDeleted() &&
+		payload.Bucket != "" &&
+		payload.FileName != ""
+}
+
+// buildDiscordAttachment downloads the object and builds a multipart/form-data
+// Discord message with the usual JSON in payload_json and the file in files[0].
+// The size limit is checked against the payload and the object's reported
+// length before downloading, and enforced again while copying.
+func buildDiscordAttachment(ctx context.Context, cfg Config, payload FilePayload) ([]byte, string, error) {
+	if payload.FileSize > cfg.MaxAttachBytes {
+		return nil, "", errAttachmentTooLarge
+	}
+
+	messageJSON, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := newS3ObjectClient(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	object, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(payload.Bucket),
+		Key:    aws.String(payload.FileName),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download s3://%s/%s: %w", payload.Bucket, payload.FileName, err)
+	}
+	defer object.Body.Close()
+
+	if object.ContentLength != nil && *object.ContentLength > cfg.MaxAttachBytes {
+		return nil, "", errAttachmentTooLarge
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="payload_json"`)
+	header.Set("Content-Type", jsonContentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(messageJSON); err != nil {
+		return nil, "", err
+	}
+
+	part, err = writer.CreateFormFile("files[0]", path.Base(payload.FileName))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Stream the object into the form, reading one byte past the limit to detect oversized objects
+	n, err := io.Copy(part, io.LimitReader(object.Body, cfg.MaxAttachBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download s3://%s/%s: %w", payload.Bucket, payload.FileName, err)
+	}
+	if n > cfg.MaxAttachBytes {
+		return nil, "", errAttachmentTooLarge
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
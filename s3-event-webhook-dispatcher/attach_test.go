@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3Objects serves objects from memory, keyed by bucket/key
+type fakeS3Objects struct {
+	objects map[string]string
+	gets    int
+}
+
+func (f *fakeS3Objects) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.gets++
+	data, ok := f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, fmt.Errorf("NoSuchKey: %s/%s", aws.ToString(params.Bucket), aws.ToString(params.Key))
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(strings.NewReader(data)),
+		ContentLength: aws.Int64(int64(len(data))),
+	}, nil
+}
+
+// stubS3Objects makes newS3ObjectClient return fake until the test ends
+func stubS3Objects(t *testing.T, fake *fakeS3Objects) {
+	t.Helper()
+	old := newS3ObjectClient
+	newS3ObjectClient = func(context.Context) (s3ObjectAPI, error) { return fake, nil }
+	t.Cleanup(func() { newS3ObjectClient = old })
+}
+
+func TestDispatchAttachesSmallFile(t *testing.T) {
+	stubS3Objects(t, &fakeS3Objects{objects: map[string]string{"logs/app/today.log": "hello world"}})
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.AttachFiles = true
+	cfg.MaxAttachBytes = 100
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "app/today.log", FileURL: "https://example.com/today.log", Bucket: "logs", FileSize: 11}); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	mediaType, params, err := mime.ParseMediaType(requests[0].Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("Content-Type = %q, want multipart/form-data", requests[0].Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(requests[0].Body), params["boundary"])
+	var names []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		names = append(names, part.FormName())
+		switch part.FormName() {
+		case "payload_json":
+			if len(decodeDiscordMessage(t, data).Embeds) != 1 {
+				t.Errorf("payload_json = %s, want the embed", data)
+			}
+		case "files[0]":
+			if string(data) != "hello world" || part.FileName() != "today.log" {
+				t.Errorf("file part = %q named %q", data, part.FileName())
+			}
+		}
+	}
+	if strings.Join(names, ",") != "payload_json,files[0]" {
+		t.Errorf("parts = %v, want payload_json and files[0]", names)
+	}
+}
+
+func TestBuildDiscordAttachmentTooLarge(t *testing.T) {
+	fake := &fakeS3Objects{objects: map[string]string{"logs/big.log": "hello world"}}
+	stubS3Objects(t, fake)
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.MaxAttachBytes = 5
+
+	// The payload size rules the file out before anything is downloaded
+	if _, _, err := buildDiscordAttachment(context.Background(), cfg, FilePayload{FileName: "big.log", Bucket: "logs", FileSize: 11}); !errors.Is(err, errAttachmentTooLarge) {
+		t.Errorf("err = %v, want errAttachmentTooLarge", err)
+	}
+	if fake.gets != 0 {
+		t.Errorf("downloaded an object over the size limit %d time(s)", fake.gets)
+	}
+
+	// Without a payload size the object's length decides
+	if _, _, err := buildDiscordAttachment(context.Background(), cfg, FilePayload{FileName: "big.log", Bucket: "logs"}); !errors.Is(err, errAttachmentTooLarge) {
+		t.Errorf("err = %v, want errAttachmentTooLarge", err)
+	}
+}
+
+func TestDispatchLargeFileFallsBackToLink(t *testing.T) {
+	stubS3Objects(t, &fakeS3Objects{})
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.AttachFiles = true
+	cfg.MaxAttachBytes = 5
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "big.log", FileURL: "https://example.com/big.log", Bucket: "logs", FileSize: 11}); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.received()
+	if len(requests) != 1 || requests[0].Header.Get("Content-Type") != jsonContentType {
+		t.Fatalf("want one JSON request with the link, got %d", len(requests))
+	}
+	if !strings.Contains(string(requests[0].Body), "https://example.com/big.log") {
+		t.Errorf("body %s doesn't link the file", requests[0].Body)
+	}
+}
+
+func TestDispatchAttachmentOverBodyLimitFallsBackToLink(t *testing.T) {
+	stubS3Objects(t, &fakeS3Objects{objects: map[string]string{"logs/app.log": strings.Repeat("x", 4000)}})
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.AttachFiles = true
+	cfg.MaxAttachBytes = 8000
+	cfg.MaxBodyBytes = 2000
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "app.log", FileURL: "https://example.com/app.log", Bucket: "logs"}); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.received()
+	if len(requests) != 1 || requests[0].Header.Get("Content-Type") != jsonContentType {
+		t.Fatalf("want one JSON request with the link, got %d", len(requests))
+	}
+}
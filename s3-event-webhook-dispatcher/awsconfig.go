@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// awsConfigCache holds the AWS SDK configuration for the lifetime of the
+// container, so the credential chain is resolved once rather than for every
+// client. The credentials provider in it refreshes expiring credentials.
+var awsConfigCache struct {
+	sync.Mutex
+	cfg    aws.Config
+	loaded bool
+}
+
+// loadAWSConfig returns the cached AWS SDK configuration, loading it on first
+// use. A failed load is not cached so the next call tries again.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	awsConfigCache.Lock()
+	defer awsConfigCache.Unlock()
+
+	if !awsConfigCache.loaded {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return aws.Config{}, err
+		}
+		awsConfigCache.cfg, awsConfigCache.loaded = cfg, true
+	}
+	return awsConfigCache.cfg, nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+// transportSettings are the Config fields that shape the HTTP client, used to
+// decide whether the cached client can be reused
+type transportSettings struct {
+	AllowPrivateTargets bool
+	EnableXRay          bool
+	ProxyURL            string
+	CACertPEM           string
+	InsecureSkipVerify  bool
+	ClientCertPEM       string
+	ClientKeyPEM        string
+	FollowRedirects     bool
+}
+
+// httpClientCache holds the client shared by warm invocations so idle
+// connections and TLS sessions are reused between events
+var httpClientCache struct {
+	sync.Mutex
+	settings transportSettings
+	client   *http.Client
+}
+
+// sharedHTTPClient returns the container-wide client for cfg, creating it on
+// first use or when the transport settings change
+func sharedHTTPClient(cfg Config) *http.Client {
+	settings := transportSettings{
+		AllowPrivateTargets: cfg.AllowPrivateTargets,
+		EnableXRay:          cfg.EnableXRay,
+		ProxyURL:            cfg.ProxyURL,
+		CACertPEM:           cfg.CACertPEM,
+		InsecureSkipVerify:  cfg.TLSInsecureSkipVerify,
+		ClientCertPEM:       cfg.ClientCertPEM,
+		ClientKeyPEM:        cfg.ClientKeyPEM,
+		FollowRedirects:     cfg.FollowRedirects && !cfg.AcceptedStatusCodes.acceptsRedirect(),
+	}
+
+	httpClientCache.Lock()
+	defer httpClientCache.Unlock()
+
+	if httpClientCache.client == nil || httpClientCache.settings != settings {
+		httpClientCache.client = newHTTPClient(settings)
+		httpClientCache.settings = settings
+	}
+	return httpClientCache.client
+}
+
+// newHTTPClient creates a client with a tuned transport. It has no overall
+// timeout; each request is bounded by its context instead, so batched sends
+// can share it.
+func newHTTPClient(settings transportSettings) *http.Client {
+	// WEBHOOK_PROXY_URL replaces HTTP_PROXY, HTTPS_PROXY and NO_PROXY. Either
+	// was validated at startup, and the guarded dialer must still reach the
+	// proxy a request is routed through.
+	proxy := http.ProxyFromEnvironment
+	if proxyURL, err := url.Parse(settings.ProxyURL); err == nil && settings.ProxyURL != "" {
+		proxy = http.ProxyURL(proxyURL)
+	}
+	proxies := &resolvedProxies{}
+
+	transport := &http.Transport{
+		Proxy:               proxies.record(proxy),
+		DialContext:         dialContextFor(settings.AllowPrivateTargets, proxies),
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	// Only replace the default TLS settings when something asks for it
+	if settings.CACertPEM != "" || settings.InsecureSkipVerify || settings.ClientCertPEM != "" {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: settings.InsecureSkipVerify}
+	}
+
+	// Trust the private CA alongside the system roots; it was validated by loadConfig
+	if settings.CACertPEM != "" {
+		if pool, err := rootCAs(settings.CACertPEM); err == nil {
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+
+	// Present the client certificate to gateways that require mutual TLS
+	if settings.ClientCertPEM != "" {
+		if cert, err := tls.X509KeyPair([]byte(settings.ClientCertPEM), []byte(settings.ClientKeyPEM)); err == nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	client := &http.Client{Transport: transport}
+
+	// Following a redirect would resend the signed body to wherever it points, so
+	// unless FOLLOW_REDIRECTS is on the redirect response is the final answer
+	if !settings.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	// Trace each outbound request as an X-Ray subsegment when enabled
+	if settings.EnableXRay {
+		client.Transport = xray.RoundTripper(xrayURLRedactor{base: transport})
+	}
+	return client
+}
+
+// proxyDialAddr returns the host:port the transport dials for a proxy URL,
+// filling in the scheme's default port like net/http does
+func proxyDialAddr(proxyURL *url.URL) string {
+	if port := proxyURL.Port(); port != "" {
+		return net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	port := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxyURL.Scheme]
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// resolvedProxies remembers the host:port of every proxy the transport routed
+// a request through, for the guarded dialer to let through
+type resolvedProxies struct {
+	addrs sync.Map
+}
+
+// record wraps the transport's proxy function to remember the proxies it returns
+func (p *resolvedProxies) record(proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(req)
+		if err == nil && proxyURL != nil {
+			p.addrs.Store(proxyDialAddr(proxyURL), true)
+		}
+		return proxyURL, err
+	}
+}
+
+// has reports whether address is a proxy the transport resolved
+func (p *resolvedProxies) has(address string) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.addrs.Load(address)
+	return ok
+}
+
+// validateEnvironmentProxies checks HTTPS_PROXY and HTTP_PROXY, or their
+// lowercase forms, the way http.ProxyFromEnvironment reads them: a value
+// without a scheme is an http:// proxy. Like parseProxyURL, errors leave out
+// the value since it may carry proxy credentials.
+func validateEnvironmentProxies() error {
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY"} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			raw = os.Getenv(strings.ToLower(name))
+		}
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			if fallback, fallbackErr := url.Parse("http://" + raw); fallbackErr == nil {
+				u, err = fallback, nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, stripURL(err))
+		}
+		if err := checkProxyURL(name, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseProxyURL validates a WEBHOOK_PROXY_URL value. The URL is left out of
+// errors since it may carry proxy credentials.
+func parseProxyURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid WEBHOOK_PROXY_URL: %w", stripURL(err))
+	}
+	if err := checkProxyURL("WEBHOOK_PROXY_URL", u); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// checkProxyURL reports a proxy URL the transport can't dial, naming the variable it came from
+func checkProxyURL(name string, u *url.URL) error {
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid %s: scheme must be http, https or socks5, got %q", name, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid %s: no host", name)
+	}
+	return nil
+}
+
+// rootCAs returns the system certificate pool with the PEM certificates added
+func rootCAs(pemData string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(pemData)) {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return pool, nil
+}
+
+// loadCACert reads the extra CA bundle from CA_CERT_PEM or the file at
+// CA_CERT_PATH and checks that it holds at least one certificate
+func loadCACert(path, inline string) (string, error) {
+	source, pemData, err := readPEM("CA_CERT_PATH", path, "CA_CERT_PEM", inline)
+	if err != nil || pemData == "" {
+		return "", err
+	}
+
+	if _, err := rootCAs(pemData); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", source, err)
+	}
+	return pemData, nil
+}
+
+// loadClientCert reads the mutual TLS certificate and private key, each from a
+// file or inline PEM, and checks that they form a usable pair
+func loadClientCert(certPath, certInline, keyPath, keyInline string) (string, string, error) {
+	certSource, certPEM, err := readPEM("CLIENT_CERT_PATH", certPath, "CLIENT_CERT_PEM", certInline)
+	if err != nil {
+		return "", "", err
+	}
+	keySource, keyPEM, err := readPEM("CLIENT_KEY_PATH", keyPath, "CLIENT_KEY_PEM", keyInline)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case certPEM == "" && keyPEM == "":
+		return "", "", nil
+	case keyPEM == "":
+		return "", "", fmt.Errorf("%s requires CLIENT_KEY_PATH or CLIENT_KEY_PEM", certSource)
+	case certPEM == "":
+		return "", "", fmt.Errorf("%s requires CLIENT_CERT_PATH or CLIENT_CERT_PEM", keySource)
+	}
+
+	if _, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM)); err != nil {
+		return "", "", fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return certPEM, keyPEM, nil
+}
+
+// readPEM returns PEM data given inline or read from a file, along with the
+// name of the variable it came from. Setting both is an error.
+func readPEM(pathVar, path, inlineVar, inline string) (string, string, error) {
+	if path != "" && inline != "" {
+		return "", "", fmt.Errorf("set either %s or %s, not both", pathVar, inlineVar)
+	}
+	if path == "" {
+		return inlineVar, inline, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", pathVar, err)
+	}
+	return pathVar, string(data), nil
+}
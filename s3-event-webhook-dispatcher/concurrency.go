@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// acquireSlot waits for one of the MAX_CONCURRENCY request slots, or returns
+// the context error if ctx is done first. A Dispatcher without slots is unbounded.
+func (d *Dispatcher) acquireSlot(ctx context.Context) error {
+	if d.slots == nil {
+		return nil
+	}
+	select {
+	case d.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseSlot returns a slot taken by acquireSlot
+func (d *Dispatcher) releaseSlot() {
+	if d.slots != nil {
+		<-d.slots
+	}
+}
+
+// forEachConcurrently calls fn for every index in [0, n) on its own goroutine
+// and waits for all of them. Requests stay bounded by the dispatcher's slots.
+func forEachConcurrently(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
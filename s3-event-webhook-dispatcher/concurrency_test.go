@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandleSQSBoundsConcurrency(t *testing.T) {
+	const limit = 3
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	cfg.WebhookURLs = []string{srv.URL + "/a", srv.URL + "/b"}
+	cfg.MaxConcurrency = limit
+	d := NewDispatcher(cfg)
+	d.Client = srv.Client()
+	d.Logger = newLogger(slogQuiet)
+
+	bodies := make([]string, 30)
+	for i := range bodies {
+		bodies[i] = uploadEvent(fmt.Sprintf("file-%d.txt", i))
+	}
+	batch := sqsBatch(append(bodies, "{not json")...)
+
+	response := d.handleSQS(context.Background(), batch)
+	if got := peak.Load(); got > limit {
+		t.Errorf("%d requests in flight, want at most %d", got, limit)
+	}
+	if got := peak.Load(); got < 2 {
+		t.Errorf("peak of %d requests in flight, want the batch sent concurrently", got)
+	}
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "msg-31" {
+		t.Errorf("BatchItemFailures = %+v, want only the malformed message", response.BatchItemFailures)
+	}
+}
+
+func TestAcquireSlotHonorsContext(t *testing.T) {
+	d := &Dispatcher{slots: make(chan struct{}, 1)}
+	if err := d.acquireSlot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.acquireSlot(ctx); err != context.Canceled {
+		t.Errorf("acquireSlot with every slot taken = %v, want context.Canceled", err)
+	}
+
+	d.releaseSlot()
+	if err := d.acquireSlot(context.Background()); err != nil {
+		t.Errorf("acquireSlot after release = %v", err)
+	}
+}
+
+func TestLoadConfigMaxConcurrencyDefault(t *testing.T) {
+	setenvConfig(t, nil)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxConcurrency != defaultMaxConcurrency {
+		t.Errorf("MaxConcurrency = %d, want %d", cfg.MaxConcurrency, defaultMaxConcurrency)
+	}
+}
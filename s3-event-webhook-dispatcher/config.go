@@ -0,0 +1,808 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default values applied when the corresponding environment variable is not set
+const (
+	defaultMaxRetries       = 3
+	defaultRetryBaseDelayMS = 500
+	defaultURLExpirationSec = 86400
+	defaultRequestTimeout   = 10
+	defaultMaxAttachBytes   = 8 << 20
+	defaultIdempotencyTTL   = 86400
+	defaultMaxConcurrency   = 5
+	defaultMaxBodyBytes     = 8 << 20
+)
+
+// randomEmbedColor marks EmbedColor as unset, picking a rainbow color per message
+const randomEmbedColor = -1
+
+// hashEmbedColor derives the color from the payload's bucket, so each bucket keeps its own
+const hashEmbedColor = -2
+
+// defaultEmbedColor is Discord's blurple-blue, used when EMBED_COLOR is invalid
+const defaultEmbedColor = 3447003
+
+// Config holds the dispatcher settings read from environment variables
+type Config struct {
+	WebhookURLs     []string
+	Platform        string
+	EmbedColor      int
+	MessageTemplate *template.Template
+	TitleTemplate   *template.Template
+
+	DeleteMessageTemplate *template.Template
+	DeleteTitleTemplate   *template.Template
+	DeleteEmbedColor      int
+	DeleteEmbedColorSet   bool
+
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RequestTimeout time.Duration
+	LogLevel       slog.Level
+
+	AllowPrivateTargets bool
+
+	GeneratePresignedURL bool
+	URLExpiration        time.Duration
+
+	SigningSecret             string
+	SignatureHeader           string
+	SignatureIncludeTimestamp bool
+
+	CustomHeaders map[string]string
+
+	BatchMessages bool
+
+	ExtensionStyles map[string]ExtensionStyle
+
+	DecodeFileNames bool
+
+	TimestampSource string
+
+	DiscordUsername  string
+	DiscordAvatarURL string
+
+	MentionContent string
+	AllowEveryone  bool
+
+	BodyTemplate     *template.Template
+	ValidateJSONBody bool
+
+	EmitMetrics bool
+
+	EnableXRay bool
+
+	DryRun bool
+
+	AttachFiles    bool
+	MaxAttachBytes int64
+
+	TelegramChatID string
+
+	GoogleChatSimple bool
+
+	EscapeMarkdown bool
+
+	HTTPMethod  string
+	ContentType string
+
+	AuthBearerToken string
+	AuthBasicUser   string
+	AuthBasicPass   string
+
+	SigV4Service string
+	SigV4Region  string
+
+	KeyPrefixFilter []string
+	KeySuffixFilter []string
+
+	AllowedExtensions map[string]bool
+	BlockedExtensions map[string]bool
+	AllowNoExtension  bool
+
+	DedupWindow time.Duration
+
+	IdempotencyTable string
+	IdempotencyTTL   time.Duration
+
+	FailureTopicARN string
+
+	FailureBucket string
+	FailurePrefix string
+
+	MaxConcurrency int
+
+	UseEmbed       bool
+	EmbedFields    bool
+	TitleLinksFile bool
+
+	AuthorTemplate *template.Template
+	AuthorURL      string
+	AuthorIconURL  string
+
+	FooterIconURL string
+
+	MaxBodyBytes int
+
+	ProxyURL string
+
+	CACertPEM             string
+	TLSInsecureSkipVerify bool
+
+	ClientCertPEM string
+	ClientKeyPEM  string
+
+	CompressBody string
+
+	PagerDutyRoutingKey string
+	PagerDutySeverity   string
+
+	OpsgenieAPIKey   string
+	OpsgeniePriority string
+
+	RoutingRules []RoutingRule
+
+	FooterText   string
+	Destinations []Destination
+
+	AcceptedStatusCodes statusRanges
+	FollowRedirects     bool
+
+	UserAgent string
+
+	DetailEncoding string
+
+	RequiredFields []string
+
+	ExpiryWarn time.Duration
+
+	ShortenURL     bool
+	ShortenerURL   string
+	ShortenerToken string
+
+	AttemptTimeout time.Duration
+
+	SlackBlocks bool
+
+	DiscordThreadID   string
+	DiscordThreadName string
+
+	DiscordWait bool
+
+	MentionParse *DiscordAllowedMentions
+
+	DisplayTimezone *time.Location
+	TimestampLayout string
+
+	Locale string
+
+	ShowImageThumbnail bool
+	ImageExtensions    map[string]bool
+
+	ShowImagePreview bool
+
+	MIMEEmojis []MIMEEmoji
+
+	SkipZeroByte     bool
+	SkipNamePatterns []*regexp.Regexp
+
+	KeyRegex *regexp.Regexp
+
+	FooterTemplate      *template.Template
+	FooterShowTimestamp bool
+	EmbedTimestamp      bool
+
+	MattermostChannel  string
+	MattermostUsername string
+
+	HTTPInvokeEnabled bool
+
+	// CustomMessageTemplate is set when MESSAGE_TEMPLATE or TEMPLATE_S3_URI
+	// supplies the template, rather than the platform default
+	CustomMessageTemplate bool
+}
+
+// loadConfig reads and validates the dispatcher configuration from the environment
+func loadConfig(ctx context.Context) (Config, error) {
+	// A webhook URL stored in Secrets Manager or Parameter Store takes
+	// precedence over WEBHOOK_URL, with the secret winning if both are set
+	webhookURL := os.Getenv("WEBHOOK_URL")
+	if arn := os.Getenv("WEBHOOK_SECRET_ARN"); arn != "" {
+		secretURL, err := resolveWebhookSecret(ctx, arn)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %w", ErrWebhookLookup, err)
+		}
+		webhookURL = secretURL
+	} else if name := os.Getenv("WEBHOOK_URL_SSM_PARAM"); name != "" {
+		paramURL, err := resolveWebhookParam(ctx, name)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %w", ErrWebhookLookup, err)
+		}
+		webhookURL = paramURL
+	}
+
+	cfg := Config{
+		WebhookURLs: parseWebhookURLs(webhookURL, os.Getenv("WEBHOOK_URLS")),
+	}
+
+	platform, err := parsePlatform(os.Getenv("PLATFORM"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Platform = platform
+	if strings.TrimSpace(os.Getenv("PLATFORM")) == "" && len(cfg.WebhookURLs) > 0 {
+		cfg.Platform = detectPlatform(cfg.WebhookURLs[0])
+	}
+
+	// Alerting APIs have a fixed endpoint, so a webhook URL is optional for them
+	// unless DESTINATIONS already says where to send
+	if fixed := defaultWebhookURL(cfg.Platform); len(cfg.WebhookURLs) == 0 && fixed != "" && os.Getenv("DESTINATIONS") == "" {
+		cfg.WebhookURLs = []string{fixed}
+	}
+
+	color, err := parseEmbedColor(os.Getenv("EMBED_COLOR"))
+	if err != nil {
+		slog.Warn("invalid EMBED_COLOR; using the default color",
+			slog.String("error", err.Error()),
+			slog.Int("color", defaultEmbedColor))
+		color = defaultEmbedColor
+	}
+	cfg.EmbedColor = color
+
+	// LOCALE picks MESSAGE_TEMPLATE_<LOCALE>, TITLE_TEMPLATE_<LOCALE> and
+	// FOOTER_TEXT_<LOCALE> over the base variables when they are set
+	cfg.Locale, err = parseLocale(os.Getenv("LOCALE"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	// Templates are compiled once per container and reused by warm invocations
+	templates, err := loadTemplates()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.MessageTemplate = templates.message
+	cfg.TitleTemplate = templates.title
+
+	// Delete events fall back to the settings above when no override is set
+	cfg.DeleteMessageTemplate = templates.deleteMessage
+	cfg.DeleteTitleTemplate = templates.deleteTitle
+	if raw := os.Getenv("DELETE_EMBED_COLOR"); raw != "" {
+		deleteColor, err := parseEmbedColor(raw)
+		if err != nil {
+			slog.Warn("invalid DELETE_EMBED_COLOR; using EMBED_COLOR",
+				slog.String("error", err.Error()))
+		} else {
+			cfg.DeleteEmbedColor = deleteColor
+			cfg.DeleteEmbedColorSet = true
+		}
+	}
+
+	maxRetries, err := getEnvInt("MAX_RETRIES", defaultMaxRetries)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxRetries < 0 {
+		return Config{}, fmt.Errorf("MAX_RETRIES must not be negative, got %d", maxRetries)
+	}
+	cfg.MaxRetries = maxRetries
+
+	baseDelayMS, err := getEnvInt("RETRY_BASE_DELAY_MS", defaultRetryBaseDelayMS)
+	if err != nil {
+		return Config{}, err
+	}
+	if baseDelayMS < 0 {
+		return Config{}, fmt.Errorf("RETRY_BASE_DELAY_MS must not be negative, got %d", baseDelayMS)
+	}
+	cfg.RetryBaseDelay = time.Duration(baseDelayMS) * time.Millisecond
+
+	timeoutSec, err := getEnvInt("REQUEST_TIMEOUT_SECONDS", defaultRequestTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	if timeoutSec <= 0 {
+		return Config{}, fmt.Errorf("REQUEST_TIMEOUT_SECONDS must be positive, got %d", timeoutSec)
+	}
+	cfg.RequestTimeout = time.Duration(timeoutSec) * time.Second
+
+	cfg.LogLevel, err = parseLogLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	allowPrivate, err := getEnvBool("ALLOW_PRIVATE_TARGETS", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.AllowPrivateTargets = allowPrivate
+
+	presign, err := getEnvBool("GENERATE_PRESIGNED_URL", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.GeneratePresignedURL = presign
+
+	expirationSec, err := getEnvInt("URL_EXPIRATION_SECONDS", defaultURLExpirationSec)
+	if err != nil {
+		return Config{}, err
+	}
+	if expirationSec <= 0 {
+		return Config{}, fmt.Errorf("URL_EXPIRATION_SECONDS must be positive, got %d", expirationSec)
+	}
+	cfg.URLExpiration = time.Duration(expirationSec) * time.Second
+
+	cfg.SigningSecret = os.Getenv("WEBHOOK_SIGNING_SECRET")
+	cfg.SignatureHeader = os.Getenv("SIGNATURE_HEADER")
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = defaultSignatureHeader
+	}
+	includeTimestamp, err := getEnvBool("SIGNATURE_INCLUDE_TIMESTAMP", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.SignatureIncludeTimestamp = includeTimestamp
+
+	headers, err := parseCustomHeaders(os.Getenv("CUSTOM_HEADERS"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.CustomHeaders = headers
+
+	batch, err := getEnvBool("BATCH_MESSAGES", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.BatchMessages = batch
+
+	styles, err := parseExtensionStyles(os.Getenv("EXTENSION_STYLES"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ExtensionStyles = styles
+
+	decode, err := getEnvBool("DECODE_FILE_NAMES", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.DecodeFileNames = decode
+
+	source, err := parseTimestampSource(os.Getenv("TIMESTAMP_SOURCE"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.TimestampSource = source
+
+	cfg.DiscordUsername = os.Getenv("DISCORD_USERNAME")
+	cfg.DiscordAvatarURL = os.Getenv("DISCORD_AVATAR_URL")
+
+	cfg.MentionContent = os.Getenv("MENTION_CONTENT")
+	allowEveryone, err := getEnvBool("ALLOW_EVERYONE", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.AllowEveryone = allowEveryone
+
+	cfg.BodyTemplate = templates.body
+	validateJSON, err := getEnvBool("VALIDATE_JSON_BODY", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ValidateJSONBody = validateJSON
+
+	emitMetrics, err := getEnvBool("EMIT_METRICS", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.EmitMetrics = emitMetrics
+
+	enableXRay, err := getEnvBool("ENABLE_XRAY", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.EnableXRay = enableXRay
+
+	dryRun, err := getEnvBool("DRY_RUN", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.DryRun = dryRun
+
+	attach, err := getEnvBool("ATTACH_FILES", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.AttachFiles = attach
+	maxAttach, err := getEnvInt("MAX_ATTACH_BYTES", defaultMaxAttachBytes)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxAttach <= 0 {
+		return Config{}, fmt.Errorf("MAX_ATTACH_BYTES must be positive, got %d", maxAttach)
+	}
+	cfg.MaxAttachBytes = int64(maxAttach)
+
+	cfg.TelegramChatID = strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_ID"))
+
+	googleChatSimple, err := getEnvBool("GOOGLECHAT_SIMPLE", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.GoogleChatSimple = googleChatSimple
+
+	escapeMarkdown, err := getEnvBool("ESCAPE_MARKDOWN", true)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.EscapeMarkdown = escapeMarkdown
+
+	cfg.HTTPMethod, err = parseHTTPMethod(os.Getenv("HTTP_METHOD"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ContentType, err = parseContentType(os.Getenv("CONTENT_TYPE"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.AuthBearerToken = os.Getenv("AUTH_BEARER_TOKEN")
+	cfg.AuthBasicUser = os.Getenv("AUTH_BASIC_USER")
+	cfg.AuthBasicPass = os.Getenv("AUTH_BASIC_PASS")
+
+	cfg.SigV4Service = strings.TrimSpace(os.Getenv("AWS_SIGV4_SERVICE"))
+	cfg.SigV4Region = os.Getenv("AWS_REGION")
+
+	cfg.KeyPrefixFilter = parseFilterList(os.Getenv("KEY_PREFIX_FILTER"))
+	cfg.KeySuffixFilter = parseFilterList(os.Getenv("KEY_SUFFIX_FILTER"))
+
+	cfg.AllowedExtensions = parseExtensionSet(os.Getenv("ALLOWED_EXTENSIONS"))
+	cfg.BlockedExtensions = parseExtensionSet(os.Getenv("BLOCKED_EXTENSIONS"))
+	allowNoExt, err := getEnvBool("ALLOW_NO_EXTENSION", true)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.AllowNoExtension = allowNoExt
+
+	dedupSec, err := getEnvInt("DEDUP_WINDOW_SECONDS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if dedupSec < 0 {
+		return Config{}, fmt.Errorf("DEDUP_WINDOW_SECONDS must not be negative, got %d", dedupSec)
+	}
+	cfg.DedupWindow = time.Duration(dedupSec) * time.Second
+
+	cfg.IdempotencyTable = strings.TrimSpace(os.Getenv("IDEMPOTENCY_TABLE"))
+	ttlSec, err := getEnvInt("IDEMPOTENCY_TTL_SECONDS", defaultIdempotencyTTL)
+	if err != nil {
+		return Config{}, err
+	}
+	if ttlSec <= 0 {
+		return Config{}, fmt.Errorf("IDEMPOTENCY_TTL_SECONDS must be positive, got %d", ttlSec)
+	}
+	cfg.IdempotencyTTL = time.Duration(ttlSec) * time.Second
+
+	cfg.FailureTopicARN = strings.TrimSpace(os.Getenv("FAILURE_SNS_TOPIC_ARN"))
+
+	cfg.FailureBucket = strings.TrimSpace(os.Getenv("FAILURE_BUCKET"))
+	cfg.FailurePrefix = "failures/"
+	if prefix, ok := os.LookupEnv("FAILURE_PREFIX"); ok {
+		cfg.FailurePrefix = prefix
+	}
+
+	cfg.MaxConcurrency, err = getEnvInt("MAX_CONCURRENCY", defaultMaxConcurrency)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxConcurrency < 1 {
+		return Config{}, fmt.Errorf("MAX_CONCURRENCY must be at least 1, got %d", cfg.MaxConcurrency)
+	}
+
+	cfg.UseEmbed, err = getEnvBool("USE_EMBED", true)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.EmbedFields, err = getEnvBool("EMBED_FIELDS", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.CustomMessageTemplate = templates.messageText != ""
+	if !cfg.CustomMessageTemplate {
+		cfg.MessageTemplate = platformMessageTemplate(cfg)
+	}
+
+	cfg.TitleLinksFile, err = getEnvBool("TITLE_LINKS_FILE", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.AuthorTemplate = templates.author
+	cfg.AuthorURL = strings.TrimSpace(os.Getenv("AUTHOR_URL"))
+	cfg.AuthorIconURL = strings.TrimSpace(os.Getenv("AUTHOR_ICON_URL"))
+
+	cfg.FooterIconURL = strings.TrimSpace(os.Getenv("FOOTER_ICON_URL"))
+
+	cfg.MaxBodyBytes, err = getEnvInt("MAX_BODY_BYTES", defaultMaxBodyBytes)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxBodyBytes < 0 {
+		return Config{}, fmt.Errorf("MAX_BODY_BYTES must not be negative, got %d", cfg.MaxBodyBytes)
+	}
+
+	cfg.ProxyURL, err = parseProxyURL(os.Getenv("WEBHOOK_PROXY_URL"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.CACertPEM, err = loadCACert(os.Getenv("CA_CERT_PATH"), os.Getenv("CA_CERT_PEM"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	// Only the exact value "true" counts, unlike other booleans, so this can't
+	// be switched on by a stray "1" or "yes"
+	cfg.TLSInsecureSkipVerify = os.Getenv("TLS_INSECURE_SKIP_VERIFY") == "true"
+
+	cfg.ClientCertPEM, cfg.ClientKeyPEM, err = loadClientCert(
+		os.Getenv("CLIENT_CERT_PATH"), os.Getenv("CLIENT_CERT_PEM"),
+		os.Getenv("CLIENT_KEY_PATH"), os.Getenv("CLIENT_KEY_PEM"),
+	)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.CompressBody, err = parseCompressBody(os.Getenv("COMPRESS_BODY"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.PagerDutyRoutingKey = strings.TrimSpace(os.Getenv("PAGERDUTY_ROUTING_KEY"))
+	cfg.PagerDutySeverity, err = parsePagerDutySeverity(os.Getenv("PAGERDUTY_SEVERITY"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.OpsgenieAPIKey = strings.TrimSpace(os.Getenv("OPSGENIE_API_KEY"))
+	cfg.OpsgeniePriority, err = parseOpsgeniePriority(os.Getenv("OPSGENIE_PRIORITY"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.RoutingRules = templates.routingRules
+
+	cfg.FooterText = footerText
+	footerOverride, _ := localeEnv(cfg.Locale, "FOOTER_TEXT")
+	if text := strings.TrimSpace(footerOverride); text != "" {
+		cfg.FooterText = text
+	}
+	cfg.Destinations = templates.destinations
+
+	cfg.AcceptedStatusCodes, err = parseAcceptedStatusCodes(os.Getenv("ACCEPTED_STATUS_CODES"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.FollowRedirects, err = getEnvBool("FOLLOW_REDIRECTS", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.UserAgent = defaultUserAgent()
+	if agent := strings.TrimSpace(os.Getenv("USER_AGENT")); agent != "" {
+		cfg.UserAgent = agent
+	}
+
+	// A centrally managed template replaces MESSAGE_TEMPLATE and any platform default
+	if uri := strings.TrimSpace(os.Getenv("TEMPLATE_S3_URI")); uri != "" {
+		cfg.MessageTemplate, err = loadTemplateObject(ctx, uri)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.CustomMessageTemplate = true
+	}
+
+	cfg.DetailEncoding, err = parseDetailEncoding(os.Getenv("DETAIL_ENCODING"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.RequiredFields, err = parseRequiredFields(os.Getenv("REQUIRED_FIELDS"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	expiryWarnSec, err := getEnvInt("EXPIRY_WARN_SECONDS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if expiryWarnSec < 0 {
+		return Config{}, fmt.Errorf("EXPIRY_WARN_SECONDS must not be negative, got %d", expiryWarnSec)
+	}
+	cfg.ExpiryWarn = time.Duration(expiryWarnSec) * time.Second
+
+	cfg.ShortenURL, err = getEnvBool("SHORTEN_URL", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ShortenerURL = strings.TrimSpace(os.Getenv("SHORTENER_URL"))
+	cfg.ShortenerToken = strings.TrimSpace(os.Getenv("SHORTENER_TOKEN"))
+
+	attemptTimeoutSec, err := getEnvInt("PER_ATTEMPT_TIMEOUT_SECONDS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if attemptTimeoutSec < 0 {
+		return Config{}, fmt.Errorf("PER_ATTEMPT_TIMEOUT_SECONDS must not be negative, got %d", attemptTimeoutSec)
+	}
+	cfg.AttemptTimeout = time.Duration(attemptTimeoutSec) * time.Second
+
+	cfg.SlackBlocks, err = getEnvBool("SLACK_BLOCKS", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.DiscordThreadID = strings.TrimSpace(os.Getenv("DISCORD_THREAD_ID"))
+	cfg.DiscordThreadName = strings.TrimSpace(os.Getenv("DISCORD_THREAD_NAME"))
+
+	cfg.DiscordWait, err = getEnvBool("DISCORD_WAIT", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.MentionParse, err = parseMentionParse(os.Getenv("MENTION_PARSE"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.DisplayTimezone, err = parseDisplayTimezone(os.Getenv("DISPLAY_TIMEZONE"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.TimestampLayout = strings.TrimSpace(os.Getenv("TIMESTAMP_LAYOUT"))
+	if cfg.TimestampLayout == "" {
+		cfg.TimestampLayout = defaultTimestampLayout
+	}
+
+	cfg.ShowImageThumbnail, err = getEnvBool("SHOW_IMAGE_THUMBNAIL", false)
+	if err != nil {
+		return Config{}, err
+	}
+	imageExtensions := os.Getenv("IMAGE_EXTENSIONS")
+	if strings.TrimSpace(imageExtensions) == "" {
+		imageExtensions = defaultImageExtensions
+	}
+	cfg.ImageExtensions = parseExtensionSet(imageExtensions)
+
+	cfg.ShowImagePreview, err = getEnvBool("SHOW_IMAGE_PREVIEW", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.MIMEEmojis, err = parseMIMEEmojiMap(os.Getenv("MIME_EMOJI_MAP"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.SkipZeroByte, err = getEnvBool("SKIP_ZERO_BYTE", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.SkipNamePatterns, err = parseSkipPatterns(os.Getenv("SKIP_NAME_PATTERNS"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.KeyRegex, err = parseKeyRegex(os.Getenv("KEY_REGEX"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.FooterTemplate = templates.footer
+	cfg.FooterShowTimestamp, err = getEnvBool("FOOTER_SHOW_TIMESTAMP", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.EmbedTimestamp, err = getEnvBool("EMBED_TIMESTAMP", true)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.MattermostChannel = strings.TrimSpace(os.Getenv("MATTERMOST_CHANNEL"))
+	cfg.MattermostUsername = strings.TrimSpace(os.Getenv("MATTERMOST_USERNAME"))
+
+	cfg.HTTPInvokeEnabled, err = getEnvBool("HTTP_INVOKE_ENABLED", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := ValidateConfig(cfg); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// getEnvInt returns the integer value of an environment variable, or def when it is unset
+func getEnvInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// getEnvBool returns the boolean value of an environment variable, or def when it is unset
+func getEnvBool(name string, def bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// parseEmbedColor parses a color as hex when prefixed with "#" or "0x",
+// otherwise as decimal, and checks it is a valid 24-bit RGB value. The
+// keywords random and hash select a rainbow color per message or one derived
+// from the bucket name.
+func parseEmbedColor(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	switch strings.ToLower(raw) {
+	case "", "random":
+		return randomEmbedColor, nil
+	case "hash":
+		return hashEmbedColor, nil
+	}
+
+	digits, base := raw, 10
+	lower := strings.ToLower(raw)
+	switch {
+	case strings.HasPrefix(lower, "#"):
+		digits, base = raw[1:], 16
+	case strings.HasPrefix(lower, "0x"):
+		digits, base = raw[2:], 16
+	}
+
+	value, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color value %q", raw)
+	}
+	if value < 0 || value > 0xFFFFFF {
+		return 0, fmt.Errorf("color %q is outside 0-16777215", raw)
+	}
+	return int(value), nil
+}
+
+// parseWebhookURLs combines the single WEBHOOK_URL with the comma-separated
+// WEBHOOK_URLS list, dropping blanks and duplicates while preserving order
+func parseWebhookURLs(single, list string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, raw := range append([]string{single}, strings.Split(list, ",")...) {
+		u := strings.TrimSpace(raw)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	return urls
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseEmbedColor(t *testing.T) {
+	for raw, want := range map[string]int{
+		"#3498DB":  3447003,
+		"#3498db":  3447003,
+		"0x3498DB": 3447003,
+		"0X3498DB": 3447003,
+		"3447003":  3447003,
+		"0":        0,
+		"16777215": 0xFFFFFF,
+	} {
+		if got, err := parseEmbedColor(raw); err != nil || got != want {
+			t.Errorf("parseEmbedColor(%q) = %d, %v; want %d", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"16777216", "#1000000", "-1", "blue", "#GGGGGG"} {
+		if _, err := parseEmbedColor(raw); err == nil {
+			t.Errorf("parseEmbedColor(%q): want an error", raw)
+		}
+	}
+}
+
+func TestLoadConfigEmbedColor(t *testing.T) {
+	setenvConfig(t, map[string]string{"EMBED_COLOR": "#3498DB"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.EmbedColor != 3447003 {
+		t.Errorf("EmbedColor = %d, want 3447003", cfg.EmbedColor)
+	}
+
+	// An out-of-range color falls back to the default instead of failing
+	setenvConfig(t, map[string]string{"EMBED_COLOR": "99999999"})
+	cfg, err = loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.EmbedColor != defaultEmbedColor {
+		t.Errorf("EmbedColor = %d, want the default %d", cfg.EmbedColor, defaultEmbedColor)
+	}
+}
+
+func TestParseEmbedColorModes(t *testing.T) {
+	for raw, want := range map[string]int{"": randomEmbedColor, "random": randomEmbedColor, " Hash ": hashEmbedColor} {
+		if got, err := parseEmbedColor(raw); err != nil || got != want {
+			t.Errorf("parseEmbedColor(%q) = %d, %v; want %d", raw, got, err, want)
+		}
+	}
+}
+
+func TestHashEmbedColorStablePerBucket(t *testing.T) {
+	cfg := Config{EmbedColor: hashEmbedColor}
+	invoices := embedColor(cfg, FilePayload{Bucket: "invoices"})
+	for i := 0; i < 10; i++ {
+		if got := embedColor(cfg, FilePayload{Bucket: "invoices", FileName: "other.pdf"}); got != invoices {
+			t.Fatalf("hash color for invoices changed from %#x to %#x", invoices, got)
+		}
+	}
+	if invoices < 0 || invoices > 0xFFFFFF {
+		t.Errorf("hash color %d is outside 0-16777215", invoices)
+	}
+	if logs := embedColor(cfg, FilePayload{Bucket: "logs"}); logs == invoices {
+		t.Error("buckets invoices and logs share a hash color")
+	}
+}
+
+func TestRandomEmbedColorVaries(t *testing.T) {
+	cfg := Config{EmbedColor: randomEmbedColor}
+	seen := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		color := embedColor(cfg, FilePayload{Bucket: "invoices"})
+		if color < 0 || color > 0xFFFFFF {
+			t.Fatalf("random color %d is outside 0-16777215", color)
+		}
+		seen[color] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("100 random colors were all %v, want them to vary", seen)
+	}
+}
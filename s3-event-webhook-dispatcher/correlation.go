@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// dispatchIDs identify one dispatch in our logs and to the receiver. The
+// request ID is generated per dispatch; the correlation ID is carried over
+// from the event, when it has one.
+type dispatchIDs struct {
+	RequestID     string
+	CorrelationID string
+}
+
+// dispatchIDsKey is the context key under which a dispatch's IDs are stored
+type dispatchIDsKey struct{}
+
+// withDispatchIDs returns a context carrying ids, which are sent with every
+// webhook request and logged with every record made under it
+func withDispatchIDs(ctx context.Context, ids dispatchIDs) context.Context {
+	return context.WithValue(ctx, dispatchIDsKey{}, ids)
+}
+
+// dispatchIDsFrom returns the IDs of the dispatch ctx belongs to, if any
+func dispatchIDsFrom(ctx context.Context) (dispatchIDs, bool) {
+	ids, ok := ctx.Value(dispatchIDsKey{}).(dispatchIDs)
+	return ids, ok
+}
+
+// newRequestID returns a random version 4 UUID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// setDispatchIDHeaders sends the dispatch's request and correlation IDs as
+// X-Request-Id and X-Correlation-Id
+func setDispatchIDHeaders(req *http.Request) {
+	ids, ok := dispatchIDsFrom(req.Context())
+	if !ok {
+		return
+	}
+	if ids.RequestID != "" {
+		req.Header.Set("X-Request-Id", ids.RequestID)
+	}
+	if ids.CorrelationID != "" {
+		req.Header.Set("X-Correlation-Id", ids.CorrelationID)
+	}
+}
+
+// dispatchIDHandler adds the IDs of the dispatch a record was logged under
+// to the record, so every line about a dispatch can be found by its request ID
+type dispatchIDHandler struct {
+	slog.Handler
+}
+
+// Handle adds requestId and correlationId before passing the record on
+func (h dispatchIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ids, ok := dispatchIDsFrom(ctx); ok {
+		if ids.RequestID != "" {
+			r.AddAttrs(slog.String("requestId", ids.RequestID))
+		}
+		if ids.CorrelationID != "" {
+			r.AddAttrs(slog.String("correlationId", ids.CorrelationID))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs keeps the wrapper around the handler with the added attributes
+func (h dispatchIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return dispatchIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the wrapper around the handler with the group opened
+func (h dispatchIDHandler) WithGroup(name string) slog.Handler {
+	return dispatchIDHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// uuidPattern matches a lowercase version 4 UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewRequestID(t *testing.T) {
+	first, second := newRequestID(), newRequestID()
+	if !uuidPattern.MatchString(first) {
+		t.Errorf("newRequestID() = %q, want a version 4 UUID", first)
+	}
+	if first == second {
+		t.Error("newRequestID returned the same ID twice")
+	}
+}
+
+func TestDispatchSendsRequestAndCorrelationIDs(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	event := events.CloudWatchEvent{
+		DetailType: "File Uploaded",
+		Detail:     json.RawMessage(`{"fileName":"a.txt","fileUrl":"https://example.com/a","correlationId":"order-9f2c"}`),
+	}
+	if err := d.handleEvent(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	header := srv.received()[0].Header
+	requestID := header.Get("X-Request-Id")
+	if !uuidPattern.MatchString(requestID) {
+		t.Errorf("X-Request-Id = %q, want a generated UUID", requestID)
+	}
+	if got := header.Get("X-Correlation-Id"); got != "order-9f2c" {
+		t.Errorf("X-Correlation-Id = %q, want the event's correlationId", got)
+	}
+
+	// The dispatch log record carries both IDs
+	var record map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record["msg"] == "webhook dispatched" {
+			break
+		}
+	}
+	if record["requestId"] != requestID || record["correlationId"] != "order-9f2c" {
+		t.Errorf("dispatch log record = %v, want requestId %s and correlationId order-9f2c", record, requestID)
+	}
+}
+
+func TestDispatchWithoutCorrelationID(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	payload := FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := srv.received()
+	if _, ok := requests[0].Header["X-Correlation-Id"]; ok {
+		t.Error("X-Correlation-Id sent for an event without a correlation ID")
+	}
+	if requests[0].Header.Get("X-Request-Id") == requests[1].Header.Get("X-Request-Id") {
+		t.Error("two dispatches shared a request ID")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// snsAPI is the subset of the SNS client used to publish failure notices
+type snsAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// s3ArchiveAPI is the subset of the S3 client used to archive failure notices
+type s3ArchiveAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// newS3ArchiveClient creates the S3 client for FAILURE_BUCKET; tests replace it with a stub
+var newS3ArchiveClient = func(ctx context.Context) (s3ArchiveAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(awsCfg), nil
+}
+
+// newSNSClient creates the SNS client for FAILURE_SNS_TOPIC_ARN; tests replace it with a stub
+var newSNSClient = func(ctx context.Context) (snsAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sns.NewFromConfig(awsCfg), nil
+}
+
+// FailureNotice describes a file that could not be delivered. It is
+// published to FAILURE_SNS_TOPIC_ARN and archived under FAILURE_PREFIX.
+type FailureNotice struct {
+	Payload    FilePayload `json:"payload"`
+	FailedAt   string      `json:"failedAt"`
+	StatusCode int         `json:"statusCode,omitempty"`
+	Error      string      `json:"error"`
+}
+
+// newFailureNotice records dispatchErr for payload, taking the status code
+// from the webhook's error response if there was one
+func newFailureNotice(payload FilePayload, dispatchErr error, now time.Time) FailureNotice {
+	notice := FailureNotice{
+		Payload:  payload,
+		FailedAt: now.UTC().Format(time.RFC3339),
+		Error:    dispatchErr.Error(),
+	}
+	var statusErr *WebhookStatusError
+	if errors.As(dispatchErr, &statusErr) {
+		notice.StatusCode = statusErr.StatusCode
+	}
+	return notice
+}
+
+// reportFailure archives and publishes a file that could not be delivered and
+// reports whether the failure was handled by publishing it to
+// FAILURE_SNS_TOPIC_ARN. The archive is best effort and never handles it, so
+// the dispatch error still stands when only FAILURE_BUCKET is set.
+func (d *Dispatcher) reportFailure(ctx context.Context, payload FilePayload, dispatchErr error) bool {
+	if d.Config.FailureBucket == "" && d.Config.FailureTopicARN == "" {
+		return false
+	}
+
+	notice := newFailureNotice(payload, dispatchErr, time.Now())
+	message, err := json.Marshal(notice)
+	if err != nil {
+		d.Logger.ErrorContext(ctx, "failed to marshal failure notice to JSON",
+			slog.String("fileName", payload.FileName),
+			slog.String("error", err.Error()))
+		return false
+	}
+
+	d.archiveFailure(ctx, payload, message)
+	return d.publishFailure(ctx, payload, message, dispatchErr)
+}
+
+// publishFailure sends the failure notice to FAILURE_SNS_TOPIC_ARN and reports
+// whether it was published. When the topic is unset or the publish fails, the
+// dispatch error stands and the event is retried as usual.
+func (d *Dispatcher) publishFailure(ctx context.Context, payload FilePayload, message []byte, dispatchErr error) bool {
+	if d.Config.FailureTopicARN == "" {
+		return false
+	}
+
+	if err := publishFailureNotice(ctx, d.Config, message); err != nil {
+		d.Logger.ErrorContext(ctx, "failed to publish failure notification",
+			slog.String("fileName", payload.FileName),
+			slog.String("error", err.Error()))
+		return false
+	}
+	d.Logger.WarnContext(ctx, "published undeliverable file to failure topic",
+		slog.String("fileName", payload.FileName),
+		slog.String("error", dispatchErr.Error()))
+	return true
+}
+
+// publishFailureNotice publishes a serialized FailureNotice to FAILURE_SNS_TOPIC_ARN
+func publishFailureNotice(ctx context.Context, cfg Config, message []byte) error {
+	client, err := newSNSClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create SNS client: %w", err)
+	}
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(cfg.FailureTopicARN),
+		Message:  aws.String(string(message)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", cfg.FailureTopicARN, err)
+	}
+	return nil
+}
+
+// archiveFailure writes the failure notice to FAILURE_BUCKET for later replay.
+// Errors are only logged so they never mask the dispatch error.
+func (d *Dispatcher) archiveFailure(ctx context.Context, payload FilePayload, message []byte) {
+	if d.Config.FailureBucket == "" {
+		return
+	}
+
+	key := failureKey(d.Config, payload)
+	if err := putFailureObject(ctx, d.Config, key, message); err != nil {
+		d.Logger.ErrorContext(ctx, "failed to archive undeliverable file",
+			slog.String("fileName", payload.FileName),
+			slog.String("error", err.Error()))
+		return
+	}
+	d.Logger.InfoContext(ctx, "archived undeliverable file",
+		slog.String("fileName", payload.FileName),
+		slog.String("failureKey", key))
+}
+
+// failureKey names the archived notice after the event, so redeliveries overwrite it
+func failureKey(cfg Config, payload FilePayload) string {
+	return cfg.FailurePrefix + eventKey(payload) + ".json"
+}
+
+// putFailureObject stores a serialized FailureNotice in FAILURE_BUCKET
+func putFailureObject(ctx context.Context, cfg Config, key string, message []byte) error {
+	client, err := newS3ArchiveClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.FailureBucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(message),
+		ContentType: aws.String(jsonContentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write s3://%s/%s: %w", cfg.FailureBucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// fakeSNS records published messages, failing every publish when err is set
+type fakeSNS struct {
+	topics   []string
+	messages []string
+	err      error
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.topics = append(f.topics, aws.ToString(params.TopicArn))
+	f.messages = append(f.messages, aws.ToString(params.Message))
+	return &sns.PublishOutput{}, nil
+}
+
+// stubSNS makes newSNSClient return fake until the test ends
+func stubSNS(t *testing.T, fake *fakeSNS) {
+	t.Helper()
+	old := newSNSClient
+	newSNSClient = func(context.Context) (snsAPI, error) { return fake, nil }
+	t.Cleanup(func() { newSNSClient = old })
+}
+
+const testFailureTopic = "arn:aws:sns:us-east-1:123456789012:webhook-failures"
+
+func TestDispatchPublishesExhaustedFailure(t *testing.T) {
+	fake := &fakeSNS{}
+	stubSNS(t, fake)
+	srv := newWebhookServer(t, http.StatusServiceUnavailable)
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 1
+	cfg.FailureTopicARN = testFailureTopic
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", Bucket: "uploads"}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatalf("published failure returned %v, want it handled", err)
+	}
+	if n := len(srv.received()); n != 2 {
+		t.Errorf("got %d attempts, want the retries exhausted first", n)
+	}
+	if len(fake.messages) != 1 || fake.topics[0] != testFailureTopic {
+		t.Fatalf("published %d message(s) to %v, want one to the failure topic", len(fake.messages), fake.topics)
+	}
+
+	var notice FailureNotice
+	if err := json.Unmarshal([]byte(fake.messages[0]), &notice); err != nil {
+		t.Fatal(err)
+	}
+	if notice.Payload.FileName != "a.txt" || notice.Payload.Bucket != "uploads" {
+		t.Errorf("notice payload = %+v", notice.Payload)
+	}
+	if notice.StatusCode != http.StatusServiceUnavailable || notice.Error == "" || notice.FailedAt == "" {
+		t.Errorf("notice = %+v, want the last status and error", notice)
+	}
+}
+
+func TestDispatchPublishFailureKeepsError(t *testing.T) {
+	stubSNS(t, &fakeSNS{err: errors.New("AuthorizationError")})
+	srv := newWebhookServer(t, http.StatusServiceUnavailable)
+	cfg := testConfig(t, srv.URL)
+	cfg.FailureTopicARN = testFailureTopic
+	d := newTestDispatcher(cfg, srv)
+
+	err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	var statusErr *WebhookStatusError
+	if !errors.As(err, &statusErr) {
+		t.Errorf("err = %v, want the dispatch error when the publish fails", err)
+	}
+}
+
+func TestDispatchSuccessPublishesNothing(t *testing.T) {
+	fake := &fakeSNS{}
+	stubSNS(t, fake)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.FailureTopicARN = testFailureTopic
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.messages) != 0 {
+		t.Errorf("published %d message(s) for a delivered file", len(fake.messages))
+	}
+}
+
+// fakeS3Archive records the objects written to FAILURE_BUCKET, failing every write when err is set
+type fakeS3Archive struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (f *fakeS3Archive) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	body, _ := io.ReadAll(params.Body)
+	if f.objects == nil {
+		f.objects = make(map[string][]byte)
+	}
+	f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+// stubS3Archive makes newS3ArchiveClient return fake until the test ends
+func stubS3Archive(t *testing.T, fake *fakeS3Archive) {
+	t.Helper()
+	old := newS3ArchiveClient
+	newS3ArchiveClient = func(context.Context) (s3ArchiveAPI, error) { return fake, nil }
+	t.Cleanup(func() { newS3ArchiveClient = old })
+}
+
+func TestDispatchArchivesFailure(t *testing.T) {
+	fake := &fakeS3Archive{}
+	stubS3Archive(t, fake)
+	srv := newWebhookServer(t, http.StatusBadRequest)
+	cfg := testConfig(t, srv.URL)
+	cfg.FailureBucket = "failed-dispatches"
+	cfg.FailurePrefix = "failures/"
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", Bucket: "uploads", EventID: "evt-1"}
+	err := d.Dispatch(context.Background(), payload)
+	var statusErr *WebhookStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want the dispatch error, which the archive doesn't handle", err)
+	}
+
+	object, ok := fake.objects["failed-dispatches/failures/evt-1.json"]
+	if !ok {
+		t.Fatalf("archived objects %v, want failures/evt-1.json", fake.objects)
+	}
+	var notice FailureNotice
+	if err := json.Unmarshal(object, &notice); err != nil {
+		t.Fatal(err)
+	}
+	if notice.Payload.EventID != "evt-1" || notice.StatusCode != http.StatusBadRequest || notice.Error == "" || notice.FailedAt == "" {
+		t.Errorf("notice = %+v", notice)
+	}
+}
+
+func TestDispatchArchiveErrorKeepsDispatchError(t *testing.T) {
+	stubS3Archive(t, &fakeS3Archive{err: errors.New("AccessDenied")})
+	srv := newWebhookServer(t, http.StatusBadRequest)
+	cfg := testConfig(t, srv.URL)
+	cfg.FailureBucket = "failed-dispatches"
+	d := newTestDispatcher(cfg, srv)
+
+	err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	var statusErr *WebhookStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("err = %v, want the original dispatch error", err)
+	}
+}
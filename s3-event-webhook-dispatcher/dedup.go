@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxDedupEntries bounds the number of event identities remembered per container
+const maxDedupEntries = 1000
+
+// dedupCache remembers recently dispatched events for DEDUP_WINDOW_SECONDS so
+// duplicate deliveries within a warm container are skipped. Entries are kept
+// in insertion order, which is also expiry order since the window is fixed.
+type dedupCache struct {
+	sync.Mutex
+	seen  map[string]time.Time
+	order []string
+}
+
+// recentEvents is the container-wide dedup cache
+var recentEvents = &dedupCache{seen: make(map[string]time.Time)}
+
+// claim records key as seen at now and reports whether it was already seen
+// within window. The oldest entries are evicted once the cache is full.
+func (c *dedupCache) claim(key string, now time.Time, window time.Duration) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	// Drop expired entries
+	for len(c.order) > 0 && now.Sub(c.seen[c.order[0]]) >= window {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+
+	if seenAt, ok := c.seen[key]; ok && now.Sub(seenAt) < window {
+		return true
+	}
+
+	c.seen[key] = now
+	c.order = append(c.order, key)
+	for len(c.seen) > maxDedupEntries {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+	return false
+}
+
+// forget removes key so a failed dispatch can be retried within the window.
+// The key leaves order too, so eviction never drops a later claim of it.
+func (c *dedupCache) forget(key string) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.seen[key]; !ok {
+		return
+	}
+	delete(c.seen, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// eventIdentity returns the key that identifies repeated deliveries of the
+// same event: its eventId when known, otherwise bucket, key and timestamp
+func eventIdentity(payload FilePayload) string {
+	if payload.EventID != "" {
+		return "id:" + payload.EventID
+	}
+	return "file:" + payload.Bucket + "\x00" + payload.FileName + "\x00" + payload.Timestamp
+}
+
+// eventKey returns a stable name for the event that is safe in object keys and
+// alert dedup keys: its eventId when known, otherwise a hash of its identity
+func eventKey(payload FilePayload) string {
+	if payload.EventID != "" {
+		return payload.EventID
+	}
+	sum := sha256.Sum256([]byte(eventIdentity(payload)))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimEvent reports whether the payload should be dispatched. Duplicates
+// within DEDUP_WINDOW_SECONDS in this container, or already claimed in
+// IDEMPOTENCY_TABLE by any invocation, are logged and skipped.
+func (d *Dispatcher) claimEvent(ctx context.Context, payload FilePayload) (bool, error) {
+	key := eventIdentity(payload)
+	if d.Config.DedupWindow > 0 && recentEvents.claim(key, time.Now(), d.Config.DedupWindow) {
+		d.logDuplicate(ctx, payload, "memory")
+		return false, nil
+	}
+	if d.Config.IdempotencyTable == "" {
+		return true, nil
+	}
+
+	claimed, err := claimIdempotencyKey(ctx, d.Config, key, time.Now())
+	if err != nil {
+		// Let the in-memory entry go so the redelivered event isn't skipped
+		d.releaseMemory(key)
+		return false, err
+	}
+	if claimed {
+		d.logDuplicate(ctx, payload, "idempotencyTable")
+		return false, nil
+	}
+	return true, nil
+}
+
+// logDuplicate records why a duplicate event was skipped
+func (d *Dispatcher) logDuplicate(ctx context.Context, payload FilePayload, store string) {
+	d.Logger.InfoContext(ctx, "skipping duplicate event",
+		slog.String("fileName", payload.FileName),
+		slog.String("bucket", payload.Bucket),
+		slog.String("eventId", payload.EventID),
+		slog.String("store", store))
+}
+
+// releaseMemory forgets key in the container cache when DEDUP_WINDOW_SECONDS is on
+func (d *Dispatcher) releaseMemory(key string) {
+	if d.Config.DedupWindow > 0 {
+		recentEvents.forget(key)
+	}
+}
+
+// releaseEvent lets a payload whose dispatch failed be sent again by a retry.
+// Failing to clear the idempotency item is only logged, since the dispatch
+// error is what gets reported.
+func (d *Dispatcher) releaseEvent(ctx context.Context, payload FilePayload) {
+	key := eventIdentity(payload)
+	d.releaseMemory(key)
+	if d.Config.IdempotencyTable == "" {
+		return
+	}
+	if err := releaseIdempotencyKey(ctx, d.Config, key); err != nil {
+		d.Logger.WarnContext(ctx, "retry of failed event will be skipped",
+			slog.String("fileName", payload.FileName),
+			slog.String("error", err.Error()))
+	}
+}
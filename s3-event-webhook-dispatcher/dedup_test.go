@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// resetRecentEvents gives the test an empty container dedup cache
+func resetRecentEvents(t *testing.T) {
+	t.Helper()
+	original := recentEvents
+	recentEvents = &dedupCache{seen: make(map[string]time.Time)}
+	t.Cleanup(func() { recentEvents = original })
+}
+
+func TestDedupCacheWindow(t *testing.T) {
+	c := &dedupCache{seen: make(map[string]time.Time)}
+	now := time.Now()
+
+	if c.claim("a", now, time.Minute) {
+		t.Fatal("first claim reported a duplicate")
+	}
+	if !c.claim("a", now.Add(30*time.Second), time.Minute) {
+		t.Error("claim within the window not reported as a duplicate")
+	}
+	if c.claim("a", now.Add(2*time.Minute), time.Minute) {
+		t.Error("claim after the window reported as a duplicate")
+	}
+}
+
+func TestDedupCacheForgetKeepsLaterClaim(t *testing.T) {
+	c := &dedupCache{seen: make(map[string]time.Time)}
+	now := time.Now()
+
+	c.claim("retried", now, time.Hour)
+	c.forget("retried")
+	c.claim("retried", now, time.Hour)
+	if len(c.order) != len(c.seen) {
+		t.Fatalf("order has %d entries for %d seen keys", len(c.order), len(c.seen))
+	}
+
+	// Filling the cache evicts in claim order; the retried key is now the oldest
+	for i := 0; i < maxDedupEntries-1; i++ {
+		c.claim(fmt.Sprintf("key-%d", i), now, time.Hour)
+	}
+	if !c.claim("retried", now, time.Hour) {
+		t.Error("re-claimed key evicted before the cache was full")
+	}
+	c.claim("overflow", now, time.Hour)
+	if len(c.seen) != maxDedupEntries || len(c.order) != maxDedupEntries {
+		t.Errorf("cache holds %d seen, %d ordered; want %d", len(c.seen), len(c.order), maxDedupEntries)
+	}
+}
+
+func TestDispatchSkipsDuplicateWithinWindow(t *testing.T) {
+	resetRecentEvents(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.DedupWindow = time.Minute
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf", EventID: "evt-1"}
+	for i := 0; i < 2; i++ {
+		if err := d.Dispatch(context.Background(), payload); err != nil {
+			t.Fatalf("dispatch %d: %v", i+1, err)
+		}
+	}
+	if got := len(srv.received()); got != 1 {
+		t.Errorf("webhook received %d requests, want 1", got)
+	}
+}
+
+func TestDispatchRetriesFailedEventWithinWindow(t *testing.T) {
+	resetRecentEvents(t)
+	srv := newWebhookServer(t, 400, 204)
+	cfg := testConfig(t, srv.URL)
+	cfg.DedupWindow = time.Minute
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf", EventID: "evt-2"}
+	if err := d.Dispatch(context.Background(), payload); err == nil {
+		t.Fatal("dispatch to a failing webhook: want an error")
+	}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if got := len(srv.received()); got != 2 {
+		t.Errorf("webhook received %d requests, want 2", got)
+	}
+}
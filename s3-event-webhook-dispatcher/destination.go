@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+)
+
+// Destination is one DESTINATIONS entry: a webhook with its own platform,
+// message template, embed color and footer. Unset fields take the global
+// PLATFORM, MESSAGE_TEMPLATE, EMBED_COLOR and FOOTER_TEXT.
+type Destination struct {
+	URL      string
+	Platform string
+
+	// MessageTemplate is nil, ColorSet false and Footer empty when they keep
+	// the global settings
+	MessageTemplate *template.Template
+	Color           int
+	ColorSet        bool
+	Footer          string
+}
+
+// parseDestinations decodes DESTINATIONS, a JSON array of
+// {"url": ..., "platform": ..., "template": ..., "color": ..., "footer": ...}
+// objects. The url may only be omitted for platforms with a fixed endpoint.
+func parseDestinations(raw string) ([]Destination, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var entries []struct {
+		URL      string          `json:"url"`
+		Platform string          `json:"platform"`
+		Template string          `json:"template"`
+		Color    json.RawMessage `json:"color"`
+		Footer   string          `json:"footer"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("invalid DESTINATIONS: %w", err)
+	}
+
+	destinations := make([]Destination, 0, len(entries))
+	for i, entry := range entries {
+		dest := Destination{
+			URL:    strings.TrimSpace(entry.URL),
+			Footer: entry.Footer,
+		}
+
+		if strings.TrimSpace(entry.Platform) != "" {
+			platform, err := parsePlatform(entry.Platform)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DESTINATIONS entry %d: %w", i, err)
+			}
+			dest.Platform = platform
+		}
+
+		var err error
+		dest.Color, dest.ColorSet, err = parseJSONColor(entry.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DESTINATIONS color for entry %d: %w", i, err)
+		}
+
+		if entry.Template != "" {
+			dest.MessageTemplate, err = parseTemplate(fmt.Sprintf("DESTINATIONS entry %d template", i), entry.Template)
+			if err != nil {
+				return nil, err
+			}
+		}
+		destinations = append(destinations, dest)
+	}
+	return destinations, nil
+}
+
+// destinationConfig returns the configuration for delivering to dest alone,
+// with the global settings filling in whatever it leaves unset
+func destinationConfig(cfg Config, dest Destination) Config {
+	cfg.Destinations = nil
+	cfg.RoutingRules = nil
+	if dest.Platform != "" {
+		cfg.Platform = dest.Platform
+	}
+	cfg.WebhookURLs = []string{dest.URL}
+	if dest.URL == "" {
+		cfg.WebhookURLs = nil
+		if fixed := defaultWebhookURL(cfg.Platform); fixed != "" {
+			cfg.WebhookURLs = []string{fixed}
+		}
+	}
+	// Without a template of its own, a destination on another platform gets
+	// that platform's default unless MESSAGE_TEMPLATE is set
+	if dest.MessageTemplate != nil {
+		cfg.MessageTemplate = dest.MessageTemplate
+	} else if dest.Platform != "" && !cfg.CustomMessageTemplate {
+		cfg.MessageTemplate = platformMessageTemplate(cfg)
+	}
+	if dest.ColorSet {
+		cfg.EmbedColor = dest.Color
+	}
+	// A destination's own footer replaces FOOTER_TEMPLATE as well as FOOTER_TEXT
+	if dest.Footer != "" {
+		cfg.FooterText = dest.Footer
+		cfg.FooterTemplate = nil
+	}
+	return cfg
+}
+
+// describeDestination names a DESTINATIONS entry by position and host
+func describeDestination(i int, cfg Config) string {
+	host := "no URL"
+	if len(cfg.WebhookURLs) > 0 {
+		host = webhookHost(cfg.WebhookURLs[0])
+	}
+	return fmt.Sprintf("destination #%d (%s)", i+1, host)
+}
+
+// deliverAll delivers the file to WEBHOOK_URL/WEBHOOK_URLS and every
+// DESTINATIONS entry concurrently, building a separate body for each
+// destination. As with WEBHOOK_URLS alone, an error is returned only when
+// every one of them failed. A panic while delivering fails the destination
+// with ErrPanic instead of crashing the invocation.
+func (d *Dispatcher) deliverAll(ctx context.Context, payload FilePayload) (err error) {
+	defer recoverPanic(ctx, &err)
+	if len(d.Config.Destinations) == 0 {
+		return d.deliver(ctx, payload)
+	}
+
+	var targets []*Dispatcher
+	var names []string
+	if len(d.Config.WebhookURLs) > 0 {
+		base := *d
+		base.Config.Destinations = nil
+		targets = append(targets, &base)
+		names = append(names, "default webhooks")
+	}
+	for i, dest := range d.Config.Destinations {
+		target := *d
+		target.Config = destinationConfig(d.Config, dest)
+		targets = append(targets, &target)
+		names = append(names, describeDestination(i, target.Config))
+	}
+
+	errs := make([]error, len(targets))
+	forEachConcurrently(len(targets), func(i int) {
+		defer recoverPanic(ctx, &errs[i])
+		errs[i] = targets[i].deliver(ctx, payload)
+	})
+
+	var failures multiError
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", names[i], err))
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	if len(failures) < len(targets) {
+		d.Logger.WarnContext(ctx, "partial destination delivery",
+			slog.String("fileName", payload.FileName),
+			slog.Int("delivered", len(targets)-len(failures)),
+			slog.Int("destinations", len(targets)),
+			slog.String("failures", failures.Error()))
+		return nil
+	}
+	return fmt.Errorf("all %d destination(s) failed: %w", len(targets), failures)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDestinationConfigColor(t *testing.T) {
+	destinations, err := parseDestinations(`[
+		{"url": "https://a.example.com/hook"},
+		{"url": "https://b.example.com/hook", "color": "random"},
+		{"url": "https://c.example.com/hook", "color": 15158332}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, "https://default.example.com/hook")
+	cfg.EmbedColor = 0x3498DB
+
+	for i, want := range []int{0x3498DB, randomEmbedColor, 15158332} {
+		if got := destinationConfig(cfg, destinations[i]).EmbedColor; got != want {
+			t.Errorf("destination %d color = %#x, want %#x", i, got, want)
+		}
+	}
+}
+
+func TestDeliverAllToleratesFailedDestination(t *testing.T) {
+	logs := captureLogs(t)
+	ok := newWebhookServer(t)
+	failing := newWebhookServer(t, 400)
+	cfg := testConfig(t, ok.URL)
+	cfg.Destinations = []Destination{{URL: failing.URL}}
+	d := newTestDispatcher(cfg, ok)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	if err := d.deliverAll(context.Background(), FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf"}); err != nil {
+		t.Fatalf("deliverAll returned %v, want success while the default webhook delivers", err)
+	}
+	if len(ok.received()) != 1 {
+		t.Errorf("default webhook received %d requests, want 1", len(ok.received()))
+	}
+	out := logs.String()
+	for _, want := range []string{`"msg":"partial destination delivery"`, `"delivered":1`, `"destinations":2`, "destination #1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logs missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestDeliverAllFailsWhenEveryDestinationFails(t *testing.T) {
+	failing := newWebhookServer(t, 400)
+	cfg := testConfig(t, failing.URL)
+	cfg.Destinations = []Destination{{URL: failing.URL}}
+	d := newTestDispatcher(cfg, failing)
+
+	err := d.deliverAll(context.Background(), FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf"})
+	if err == nil || !strings.Contains(err.Error(), "all 2 destination(s) failed") {
+		t.Fatalf("deliverAll error = %v, want every destination reported", err)
+	}
+}
+
+func TestDispatchDestinationsWithOwnTemplates(t *testing.T) {
+	alerts := newWebhookServer(t)
+	audit := newWebhookServer(t)
+	setenvConfig(t, map[string]string{
+		"WEBHOOK_URL":           "",
+		"ALLOW_PRIVATE_TARGETS": "true",
+		"EMBED_COLOR":           "3447003",
+		"DESTINATIONS": fmt.Sprintf(`[
+			{"url": %q, "template": "ALERT {{.FileName}}", "color": 15158332, "footer": "alerts"},
+			{"url": %q, "template": "audit: {{.Bucket}}/{{.FileName}}"}
+		]`, alerts.URL, audit.URL),
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.MaxRetries = 0
+	d := newTestDispatcher(cfg, alerts)
+
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf", Bucket: "invoices"}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(alerts.received()) != 1 || len(audit.received()) != 1 {
+		t.Fatalf("destinations received %d and %d requests, want 1 each", len(alerts.received()), len(audit.received()))
+	}
+	alert := decodeDiscordMessage(t, alerts.received()[0].Body).Embeds[0]
+	if alert.Description != "ALERT report.pdf" || alert.Color != 15158332 || alert.Footer.Text != "alerts" {
+		t.Errorf("first destination got description %q, color %d, footer %q", alert.Description, alert.Color, alert.Footer.Text)
+	}
+	// The second destination keeps the global color and footer
+	entry := decodeDiscordMessage(t, audit.received()[0].Body).Embeds[0]
+	if entry.Description != "audit: invoices/report.pdf" || entry.Color != 3447003 || entry.Footer.Text != footerText {
+		t.Errorf("second destination got description %q, color %d, footer %q", entry.Description, entry.Color, entry.Footer.Text)
+	}
+}
+
+func TestParseDestinationsInvalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"url": "https://a.example.com"}`,
+		`[{"url": "https://a.example.com", "platform": "myspace"}]`,
+		`[{"url": "https://a.example.com", "template": "{{.Missing"}]`,
+		`[{"url": "https://a.example.com", "color": "chartreuse-ish"}]`,
+	} {
+		if _, err := parseDestinations(raw); err == nil {
+			t.Errorf("parseDestinations(%s): want an error", raw)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Supported values for the DETAIL_ENCODING environment variable
+const (
+	detailEncodingAuto   = "auto"
+	detailEncodingJSON   = "json"
+	detailEncodingBase64 = "base64"
+)
+
+// parseDetailEncoding reads DETAIL_ENCODING, defaulting to auto
+func parseDetailEncoding(raw string) (string, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(raw)); encoding {
+	case "":
+		return detailEncodingAuto, nil
+	case detailEncodingAuto, detailEncodingJSON, detailEncodingBase64:
+		return encoding, nil
+	default:
+		return "", fmt.Errorf("unsupported DETAIL_ENCODING %q (supported: auto, json, base64)", raw)
+	}
+}
+
+// decodeDetail unmarshals an event detail into payloads. Some relays deliver
+// the detail as a JSON string holding base64-encoded JSON, which auto accepts
+// when the detail isn't a JSON object itself.
+func decodeDetail(cfg Config, detail json.RawMessage, payloads *filePayloads) error {
+	if cfg.DetailEncoding == detailEncodingBase64 {
+		return decodeBase64Detail(detail, payloads)
+	}
+
+	err := json.Unmarshal(detail, payloads)
+	if err == nil || cfg.DetailEncoding == detailEncodingJSON {
+		return err
+	}
+	if base64Err := decodeBase64Detail(detail, payloads); base64Err != nil {
+		return err
+	}
+	return nil
+}
+
+// decodeBase64Detail unmarshals a detail that is a JSON string of base64-encoded JSON
+func decodeBase64Detail(detail json.RawMessage, payloads *filePayloads) error {
+	var encoded string
+	if err := json.Unmarshal(detail, &encoded); err != nil {
+		return fmt.Errorf("base64 detail must be a JSON string: %w", err)
+	}
+
+	encoded = strings.TrimSpace(encoded)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Tolerate unpadded input, which some encoders produce
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil {
+			return fmt.Errorf("invalid base64 detail: %w", err)
+		}
+	}
+	return json.Unmarshal(decoded, payloads)
+}
+
+// filePayloads decodes an event detail holding either one file payload or a
+// JSON array of them, as some producers batch several files into one event
+type filePayloads []FilePayload
+
+// UnmarshalJSON accepts a single payload object or a non-empty array of them
+func (p *filePayloads) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var list []FilePayload
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("detail is an empty array")
+		}
+		*p = list
+		return nil
+	}
+
+	var single FilePayload
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*p = filePayloads{single}
+	return nil
+}
+
+// UnmarshalJSON decodes a file payload, noting whether fileSize was sent so
+// SKIP_ZERO_BYTE leaves payloads without a size alone
+func (p *FilePayload) UnmarshalJSON(data []byte) error {
+	// The alias type drops this method so the fields decode as usual
+	type plainPayload FilePayload
+	var decoded plainPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	var probe struct {
+		FileSize json.RawMessage `json:"fileSize"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	*p = FilePayload(decoded)
+	p.fileSizeSet = len(probe.FileSize) > 0 && string(probe.FileSize) != "null"
+	return nil
+}
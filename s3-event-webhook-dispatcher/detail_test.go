@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// base64Detail encodes a JSON detail the way relaying pipes deliver it: as a
+// JSON string of base64
+func base64Detail(detail string) json.RawMessage {
+	return json.RawMessage(strconv.Quote(base64.StdEncoding.EncodeToString([]byte(detail))))
+}
+
+func TestParseDetailRawJSON(t *testing.T) {
+	for _, encoding := range []string{detailEncodingAuto, detailEncodingJSON} {
+		payloads, err := parseDetail(Config{DetailEncoding: encoding}, json.RawMessage(`{"fileName":"a.txt","bucket":"invoices"}`))
+		if err != nil {
+			t.Fatalf("DETAIL_ENCODING=%s: %v", encoding, err)
+		}
+		if len(payloads) != 1 || payloads[0].FileName != "a.txt" || payloads[0].Bucket != "invoices" {
+			t.Errorf("DETAIL_ENCODING=%s: payloads = %+v", encoding, payloads)
+		}
+	}
+
+	if _, err := parseDetail(Config{DetailEncoding: detailEncodingBase64}, json.RawMessage(`{"fileName":"a.txt"}`)); !errors.Is(err, ErrPayloadParse) {
+		t.Errorf("DETAIL_ENCODING=base64 with raw JSON: err = %v, want ErrPayloadParse", err)
+	}
+}
+
+func TestParseDetailBase64JSON(t *testing.T) {
+	detail := base64Detail(`{"fileName":"a.txt","bucket":"invoices"}`)
+	for _, encoding := range []string{detailEncodingAuto, detailEncodingBase64} {
+		payloads, err := parseDetail(Config{DetailEncoding: encoding}, detail)
+		if err != nil {
+			t.Fatalf("DETAIL_ENCODING=%s: %v", encoding, err)
+		}
+		if len(payloads) != 1 || payloads[0].FileName != "a.txt" || payloads[0].Bucket != "invoices" {
+			t.Errorf("DETAIL_ENCODING=%s: payloads = %+v", encoding, payloads)
+		}
+	}
+
+	// Unpadded base64 is accepted too
+	unpadded := json.RawMessage(strconv.Quote(base64.RawStdEncoding.EncodeToString([]byte(`{"fileName":"ab"}`))))
+	if payloads, err := parseDetail(Config{DetailEncoding: detailEncodingAuto}, unpadded); err != nil || payloads[0].FileName != "ab" {
+		t.Errorf("unpadded base64: payloads = %+v, err = %v", payloads, err)
+	}
+
+	if _, err := parseDetail(Config{DetailEncoding: detailEncodingJSON}, detail); !errors.Is(err, ErrPayloadParse) {
+		t.Errorf("DETAIL_ENCODING=json with base64: err = %v, want ErrPayloadParse", err)
+	}
+}
+
+func TestParseDetailInvalid(t *testing.T) {
+	for _, detail := range []string{
+		`"not base64 at all!"`,
+		`{"fileName":`,
+		string(base64Detail(`{"fileName":`)),
+		`42`,
+	} {
+		if _, err := parseDetail(Config{DetailEncoding: detailEncodingAuto}, json.RawMessage(detail)); !errors.Is(err, ErrPayloadParse) {
+			t.Errorf("parseDetail(%s): err = %v, want ErrPayloadParse", detail, err)
+		}
+	}
+}
+
+func TestParseDetailEncoding(t *testing.T) {
+	for raw, want := range map[string]string{"": detailEncodingAuto, "JSON": detailEncodingJSON, " base64 ": detailEncodingBase64} {
+		if got, err := parseDetailEncoding(raw); err != nil || got != want {
+			t.Errorf("parseDetailEncoding(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseDetailEncoding("hex"); err == nil {
+		t.Error("parseDetailEncoding accepted an unsupported encoding")
+	}
+
+	setenvConfig(t, map[string]string{"DETAIL_ENCODING": "gzip"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted an unsupported DETAIL_ENCODING")
+	}
+}
+
+func TestPayloadsFromEventSingleObject(t *testing.T) {
+	event := events.CloudWatchEvent{ID: "ev-1", Detail: json.RawMessage(`{"fileName":"a.txt"}`)}
+	payloads, err := payloadsFromEvent(Config{}, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 1 || payloads[0].FileName != "a.txt" || payloads[0].EventID != "ev-1" {
+		t.Errorf("payloads = %+v, want a.txt identified by the event ID", payloads)
+	}
+}
+
+func TestPayloadsFromEventArray(t *testing.T) {
+	event := events.CloudWatchEvent{ID: "ev-1", Detail: json.RawMessage(`[
+		{"fileName":"a.txt"},
+		{"fileName":"b.txt","eventId":"own-id"},
+		{"fileName":"c.txt"}
+	]`)}
+	payloads, err := payloadsFromEvent(Config{}, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 3 {
+		t.Fatalf("got %d payloads, want 3", len(payloads))
+	}
+	for i, want := range []struct{ name, id string }{{"a.txt", "ev-1#0"}, {"b.txt", "own-id"}, {"c.txt", "ev-1#2"}} {
+		if payloads[i].FileName != want.name || payloads[i].EventID != want.id {
+			t.Errorf("payload %d = %s (%s), want %s (%s)", i, payloads[i].FileName, payloads[i].EventID, want.name, want.id)
+		}
+	}
+
+	if _, err := parseDetail(Config{}, json.RawMessage(`[]`)); !errors.Is(err, ErrPayloadParse) {
+		t.Errorf("empty array: err = %v, want ErrPayloadParse", err)
+	}
+}
+
+func TestHandleEventDispatchesEachArrayElement(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	event := events.CloudWatchEvent{ID: "ev-1", Detail: json.RawMessage(`[
+		{"fileName":"a.txt","fileUrl":"https://example.com/a"},
+		{"fileName":"b.txt","fileUrl":"https://example.com/b"},
+		{"fileName":"c.txt","fileUrl":"https://example.com/c"}
+	]`)}
+	if err := d.handleEvent(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, request := range srv.received() {
+		description := decodeDiscordMessage(t, request.Body).Embeds[0].Description
+		for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			if strings.Contains(description, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "a.txt,b.txt,c.txt" {
+		t.Errorf("messages named %q, want one per file", names)
+	}
+}
+
+func TestHandleEventArrayReportsFailedFile(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	// The second file lacks the required fileUrl
+	event := events.CloudWatchEvent{Detail: json.RawMessage(`[
+		{"fileName":"a.txt","fileUrl":"https://example.com/a"},
+		{"fileName":"b.txt"}
+	]`)}
+	err := d.handleEvent(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 file(s) failed") || !strings.Contains(err.Error(), "b.txt") {
+		t.Errorf("err = %v, want b.txt reported as the failed file", err)
+	}
+}
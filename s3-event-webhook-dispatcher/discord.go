@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Discord message limits, counted in characters
+const (
+	maxDiscordEmbeds           = 10
+	maxDiscordDescriptionChars = 4096
+	maxDiscordEmbedTotalChars  = 6000
+	maxDiscordContentChars     = 2000
+	maxDiscordFieldValueChars  = 1024
+	maxDiscordAuthorNameChars  = 256
+)
+
+// ellipsis marks text that was shortened to fit a platform limit
+const ellipsis = "…"
+
+// discordMarkdownEscaper backslash-escapes the characters Discord treats as markdown formatting
+var discordMarkdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`,
+)
+
+// buildDiscordMessage formats the payload as a Discord webhook message with a single embed
+func buildDiscordMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	return buildDiscordBatch(cfg, []FilePayload{payload})
+}
+
+// buildDiscordBatch formats several payloads as one Discord webhook message with an embed per file.
+// Callers must keep the batch within maxDiscordEmbeds.
+func buildDiscordBatch(cfg Config, payloads []FilePayload) ([]byte, error) {
+	if !cfg.UseEmbed {
+		return buildDiscordContent(cfg, payloads)
+	}
+
+	message := DiscordMessage{
+		Content:         cfg.MentionContent,
+		Username:        cfg.DiscordUsername,
+		AvatarURL:       cfg.DiscordAvatarURL,
+		ThreadName:      cfg.DiscordThreadName,
+		AllowedMentions: discordAllowedMentions(cfg),
+		Embeds:          make([]DiscordEmbed, 0, len(payloads)),
+	}
+
+	// The total character limit applies across every embed in the message
+	budget := maxDiscordEmbedTotalChars
+	if len(payloads) > 1 {
+		budget /= len(payloads)
+	}
+
+	for _, payload := range payloads {
+		embed, err := buildDiscordEmbed(cfg, payload)
+		if err != nil {
+			return nil, err
+		}
+		if fitDiscordEmbed(&embed, budget) {
+			slog.Warn("truncated Discord embed to fit the character limit",
+				slog.String("fileName", payload.FileName),
+				slog.Int("limit", budget))
+		}
+		message.Embeds = append(message.Embeds, embed)
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
+
+// buildDiscordContent formats payloads as a plain Discord message with the
+// rendered template of each file on its own line in content and no embeds,
+// cut to Discord's 2000 character content limit
+func buildDiscordContent(cfg Config, payloads []FilePayload) ([]byte, error) {
+	var lines []string
+	if cfg.MentionContent != "" {
+		lines = append(lines, cfg.MentionContent)
+	}
+	for _, payload := range payloads {
+		rendered := payload
+		if cfg.EscapeMarkdown {
+			rendered = escapeDiscordMarkdown(payload)
+		}
+		text, err := renderMessage(cfg, rendered)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, text)
+	}
+
+	content := strings.Join(lines, "\n")
+	if utf8.RuneCountInString(content) > maxDiscordContentChars {
+		slog.Warn("truncated Discord content to fit the character limit",
+			slog.Int("limit", maxDiscordContentChars))
+		content = truncateText(content, maxDiscordContentChars)
+	}
+
+	messageJSON, err := json.Marshal(DiscordMessage{
+		Content:         content,
+		Username:        cfg.DiscordUsername,
+		AvatarURL:       cfg.DiscordAvatarURL,
+		ThreadName:      cfg.DiscordThreadName,
+		AllowedMentions: discordAllowedMentions(cfg),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
+
+// maxDiscordMessageResponseBytes bounds how much of the message object
+// returned with DISCORD_WAIT is read to find its ID
+const maxDiscordMessageResponseBytes = 64 << 10
+
+// discordWebhookURL adds the query parameters Discord reads from the webhook
+// URL: thread_id when DISCORD_THREAD_ID is set, so the message is posted into
+// that thread instead of the channel root, and wait=true when DISCORD_WAIT is
+// set, so Discord returns the created message instead of 204 No Content
+func discordWebhookURL(cfg Config, webhookURL string) string {
+	if cfg.Platform != platformDiscord || (cfg.DiscordThreadID == "" && !cfg.DiscordWait) {
+		return webhookURL
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return webhookURL
+	}
+	query := u.Query()
+	if cfg.DiscordThreadID != "" {
+		query.Set("thread_id", cfg.DiscordThreadID)
+	}
+	if cfg.DiscordWait {
+		query.Set("wait", "true")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// parseDiscordMessageID returns the id of the message object Discord returns
+// for a webhook executed with wait=true, or "" when the body has none
+func parseDiscordMessageID(body io.Reader) string {
+	var message struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, maxDiscordMessageResponseBytes)).Decode(&message); err != nil {
+		return ""
+	}
+	return message.ID
+}
+
+// buildDiscordEmbed creates the embed describing a single file
+func buildDiscordEmbed(cfg Config, payload FilePayload) (DiscordEmbed, error) {
+	// Keep markdown in file names from breaking the template's own formatting
+	rendered := payload
+	if cfg.EscapeMarkdown {
+		rendered = escapeDiscordMarkdown(payload)
+	}
+
+	// Create description from the message template
+	description, err := renderMessage(cfg, rendered)
+	if err != nil {
+		return DiscordEmbed{}, err
+	}
+
+	title, err := renderTitle(cfg, rendered)
+	if err != nil {
+		return DiscordEmbed{}, err
+	}
+
+	// Use the configured color unless the file extension has its own style
+	color := embedColor(cfg, payload)
+	if style, ok := extensionStyle(cfg, payload.FileName); ok && style.ColorSet {
+		color = resolveColor(style.Color, payload)
+	}
+	if emoji := titleEmoji(cfg, payload); emoji != "" && title != "" {
+		title = emoji + " " + title
+	}
+
+	footer, err := renderFooter(cfg, rendered)
+	if err != nil {
+		return DiscordEmbed{}, err
+	}
+
+	embed := DiscordEmbed{
+		Title:       title,
+		Description: description,
+		Color:       color,
+		Footer: EmbedItem{
+			Text:    footer,
+			IconURL: cfg.FooterIconURL,
+		},
+	}
+	if cfg.EmbedTimestamp {
+		embed.Timestamp = messageTime(cfg, payload).Format(time.RFC3339)
+	}
+	if cfg.EmbedFields {
+		embed.Fields = discordFields(rendered)
+	}
+	if cfg.TitleLinksFile && title != "" && isHTTPURL(payload.FileURL) {
+		embed.URL = payload.FileURL
+	}
+	if imageURL := embedImageURL(cfg, payload); imageURL != "" {
+		if cfg.ShowImageThumbnail {
+			embed.Thumbnail = &EmbedMedia{URL: imageURL}
+		}
+		if cfg.ShowImagePreview {
+			embed.Image = &EmbedMedia{URL: imageURL}
+		}
+	}
+	embed.Author, err = discordAuthor(cfg, payload)
+	if err != nil {
+		return DiscordEmbed{}, err
+	}
+	return embed, nil
+}
+
+// discordAuthor renders the AUTHOR_NAME block for the payload, or returns nil
+// when no author is configured or the name renders empty. Discord shows the
+// name as plain text, so it is rendered from the unescaped payload.
+func discordAuthor(cfg Config, payload FilePayload) (*EmbedAuthor, error) {
+	if cfg.AuthorTemplate == nil {
+		return nil, nil
+	}
+	name, err := renderTemplate(cfg, cfg.AuthorTemplate, payload)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	return &EmbedAuthor{
+		Name:    truncateText(name, maxDiscordAuthorNameChars),
+		URL:     cfg.AuthorURL,
+		IconURL: cfg.AuthorIconURL,
+	}, nil
+}
+
+// discordFields lists the bucket, size and link expiry as inline embed fields.
+// Unknown values are left out, since Discord rejects fields with empty values.
+func discordFields(payload FilePayload) []EmbedField {
+	var fields []EmbedField
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, EmbedField{Name: name, Value: truncateText(value, maxDiscordFieldValueChars), Inline: true})
+		}
+	}
+
+	add("Bucket", payload.Bucket)
+	add("File Size", payload.FileSizeHuman())
+	if payload.ExpirationTime != "" {
+		add("Expiration", "After "+payload.ExpirationTime)
+	}
+	return fields
+}
+
+// escapeDiscordMarkdown returns a copy of the payload for template rendering
+// with the text taken from the object escaped for Discord markdown. FileURL
+// stays usable as a link target while CleanURL, used as link text, is escaped.
+func escapeDiscordMarkdown(payload FilePayload) FilePayload {
+	return escapePayloadText(payload, discordMarkdownEscaper)
+}
+
+// fitDiscordEmbed shortens an embed to Discord's limits: the description is cut
+// to 4096 characters, then the footer is dropped and the description trimmed
+// further until the embed's text fits within budget. It reports whether
+// anything was changed.
+func fitDiscordEmbed(embed *DiscordEmbed, budget int) bool {
+	changed := false
+
+	if utf8.RuneCountInString(embed.Description) > maxDiscordDescriptionChars {
+		embed.Description = truncateText(embed.Description, maxDiscordDescriptionChars)
+		changed = true
+	}
+
+	if discordEmbedChars(*embed) > budget && embed.Footer.Text != "" {
+		// Discord rejects a footer icon without text, so both go
+		embed.Footer = EmbedItem{}
+		changed = true
+	}
+
+	if over := discordEmbedChars(*embed) - budget; over > 0 {
+		keep := utf8.RuneCountInString(embed.Description) - over
+		if keep < 0 {
+			keep = 0
+		}
+		embed.Description = truncateText(embed.Description, keep)
+		changed = true
+	}
+
+	return changed
+}
+
+// discordEmbedChars counts the characters Discord includes in its per-message embed limit
+func discordEmbedChars(embed DiscordEmbed) int {
+	chars := utf8.RuneCountInString(embed.Title) +
+		utf8.RuneCountInString(embed.Description) +
+		utf8.RuneCountInString(embed.Footer.Text)
+	for _, field := range embed.Fields {
+		chars += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if embed.Author != nil {
+		chars += utf8.RuneCountInString(embed.Author.Name)
+	}
+	return chars
+}
+
+// truncateText cuts s to at most max characters, ending with an ellipsis when shortened
+func truncateText(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + ellipsis
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// decodeDiscordMessage unmarshals a built Discord body, failing the test when it isn't valid JSON
+func decodeDiscordMessage(t *testing.T, body []byte) DiscordMessage {
+	t.Helper()
+	var message DiscordMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatalf("invalid Discord JSON %s: %v", body, err)
+	}
+	return message
+}
+
+func TestBuildDiscordMessageTruncatesDescription(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.MessageTemplate, _ = parseMessageTemplate("{{.FileName}}")
+
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: strings.Repeat("é", 5000), FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embed := decodeDiscordMessage(t, body).Embeds[0]
+	if n := utf8.RuneCountInString(embed.Description); n != maxDiscordDescriptionChars {
+		t.Errorf("description has %d characters, want %d", n, maxDiscordDescriptionChars)
+	}
+	if !strings.HasSuffix(embed.Description, ellipsis) {
+		t.Error("truncated description doesn't end with an ellipsis")
+	}
+	if embed.Footer.Text != footerText {
+		t.Errorf("footer = %q, dropped although the embed fits", embed.Footer.Text)
+	}
+}
+
+func TestFitDiscordEmbedDropsFooterFirst(t *testing.T) {
+	embed := DiscordEmbed{
+		Title:       "New File Uploaded",
+		Description: strings.Repeat("a", 3000),
+		Footer:      EmbedItem{Text: strings.Repeat("f", 100), IconURL: "https://example.com/icon.png"},
+	}
+	if !fitDiscordEmbed(&embed, 3010) {
+		t.Fatal("embed over budget reported as unchanged")
+	}
+	if embed.Footer != (EmbedItem{}) {
+		t.Errorf("footer = %+v, want it dropped", embed.Footer)
+	}
+	if n := discordEmbedChars(embed); n > 3010 {
+		t.Errorf("embed has %d characters, over the budget", n)
+	}
+	if !strings.HasSuffix(embed.Description, ellipsis) {
+		t.Error("description not trimmed to fit")
+	}
+
+	small := DiscordEmbed{Title: "t", Description: "d", Footer: EmbedItem{Text: "f"}}
+	if fitDiscordEmbed(&small, maxDiscordEmbedTotalChars) {
+		t.Error("embed within limits reported as changed")
+	}
+}
+
+func TestBuildDiscordBatchStaysWithinTotalLimit(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.MessageTemplate, _ = parseMessageTemplate("{{.FileName}}")
+	payloads := make([]FilePayload, 3)
+	for i := range payloads {
+		payloads[i] = FilePayload{FileName: strings.Repeat("x", 4000), FileURL: "https://example.com/a"}
+	}
+
+	body, err := buildDiscordBatch(cfg, payloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int
+	for _, embed := range decodeDiscordMessage(t, body).Embeds {
+		total += discordEmbedChars(embed)
+	}
+	if total > maxDiscordEmbedTotalChars {
+		t.Errorf("embeds total %d characters, over %d", total, maxDiscordEmbedTotalChars)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"truncated", 5, "trun" + ellipsis},
+		{"日本語のファイル", 4, "日本語" + ellipsis},
+		{"anything", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateText(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestBuildDiscordMessageSenderOverrides(t *testing.T) {
+	payload := FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}
+
+	setenvConfig(t, nil)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"username"`, `"avatar_url"`} {
+		if strings.Contains(string(body), field) {
+			t.Errorf("body %s has %s although it isn't configured", body, field)
+		}
+	}
+
+	setenvConfig(t, map[string]string{
+		"DISCORD_USERNAME":   "Invoice Bot",
+		"DISCORD_AVATAR_URL": "https://example.com/bot.png",
+	})
+	cfg, err = loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := decodeDiscordMessage(t, body)
+	if message.Username != "Invoice Bot" || message.AvatarURL != "https://example.com/bot.png" {
+		t.Errorf("username = %q, avatar_url = %q", message.Username, message.AvatarURL)
+	}
+}
+
+func TestEscapeDiscordMarkdown(t *testing.T) {
+	for _, c := range []string{"*", "_", "~", "`", "|", ">", `\`} {
+		escaped := escapeDiscordMarkdown(FilePayload{FileName: "a" + c + "b.pdf"})
+		if want := `a\` + c + "b.pdf"; escaped.FileName != want {
+			t.Errorf("FileName with %q escaped to %q, want %q", c, escaped.FileName, want)
+		}
+	}
+}
+
+func TestBuildDiscordMessageEscapesMarkdown(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.FileName}} [{{.CleanURL}}]({{.FileURL}})")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := FilePayload{FileName: "**invoice**_final*.pdf", FileURL: "https://example.com/a_b?sig=1"}
+
+	setenvConfig(t, nil)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.EscapeMarkdown {
+		t.Fatal("ESCAPE_MARKDOWN doesn't default to true")
+	}
+	cfg.MessageTemplate = tmpl
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `\*\*invoice\*\*\_final\*.pdf [https://example.com/a\_b](https://example.com/a_b?sig=1)`
+	if got := decodeDiscordMessage(t, body).Embeds[0].Description; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+
+	cfg.EscapeMarkdown = false
+	body, err = buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeDiscordMessage(t, body).Embeds[0].Description; !strings.HasPrefix(got, payload.FileName+" ") {
+		t.Errorf("description = %q, want the raw file name with ESCAPE_MARKDOWN off", got)
+	}
+}
+
+func TestBuildDiscordMessageContentOnly(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.UseEmbed = false
+	cfg.MessageTemplate, _ = parseMessageTemplate("{{.FileName}} is ready")
+
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "report.pdf", FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["embeds"]; ok {
+		t.Errorf("body %s has embeds with USE_EMBED=false", body)
+	}
+	for _, ignored := range []string{"New File Uploaded", footerText, `"color"`} {
+		if strings.Contains(string(body), ignored) {
+			t.Errorf("body %s contains %s, which only applies to embeds", body, ignored)
+		}
+	}
+	if content := decodeDiscordMessage(t, body).Content; content != "report.pdf is ready" {
+		t.Errorf("content = %q, want the rendered template", content)
+	}
+}
+
+func TestBuildDiscordMessageContentTruncated(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.UseEmbed = false
+	cfg.MessageTemplate, _ = parseMessageTemplate("{{.FileName}}")
+
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: strings.Repeat("é", 3000), FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := decodeDiscordMessage(t, body).Content
+	if n := utf8.RuneCountInString(content); n != maxDiscordContentChars {
+		t.Errorf("content has %d characters, want %d", n, maxDiscordContentChars)
+	}
+	if !strings.HasSuffix(content, ellipsis) {
+		t.Error("truncated content doesn't end with an ellipsis")
+	}
+}
+
+func TestBuildDiscordMessageEmbedFields(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	payload := FilePayload{FileName: "q1.pdf", FileURL: "https://example.com/a", Bucket: "invoices", FileSize: 2048, ExpirationTime: "24 hours"}
+
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), `"fields"`) {
+		t.Errorf("body %s has fields without EMBED_FIELDS", body)
+	}
+
+	cfg.EmbedFields = true
+	body, err = buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EmbedField{
+		{Name: "Bucket", Value: "invoices", Inline: true},
+		{Name: "File Size", Value: "2.00 KB", Inline: true},
+		{Name: "Expiration", Value: "After 24 hours", Inline: true},
+	}
+	if got := decodeDiscordMessage(t, body).Embeds[0].Fields; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiscordFieldsSkipsUnknownValues(t *testing.T) {
+	fields := discordFields(FilePayload{FileName: "a.txt", Bucket: "invoices"})
+	for _, field := range fields {
+		if field.Value == "" || field.Name == "Expiration" {
+			t.Errorf("field %+v sent for an unknown value", field)
+		}
+	}
+}
+
+func TestBuildDiscordMessageTitleLink(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	presigned := "https://example-bucket.s3.amazonaws.com/q1.pdf?X-Amz-Signature=abc"
+
+	build := func(cfg Config, fileURL string) DiscordEmbed {
+		t.Helper()
+		body, err := buildDiscordMessage(cfg, FilePayload{FileName: "q1.pdf", FileURL: fileURL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decodeDiscordMessage(t, body).Embeds[0]
+	}
+
+	if embed := build(cfg, presigned); embed.URL != "" {
+		t.Errorf("url = %q without TITLE_LINKS_FILE", embed.URL)
+	}
+	cfg.TitleLinksFile = true
+	if embed := build(cfg, presigned); embed.URL != presigned {
+		t.Errorf("url = %q, want the presigned URL", embed.URL)
+	}
+	if embed := build(cfg, "javascript:alert(1)"); embed.URL != "" {
+		t.Errorf("url = %q, want a non-http(s) link left out", embed.URL)
+	}
+}
+
+func TestBuildDiscordMessageTemplatedAuthor(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"AUTHOR_NAME":     "Source: {{.Bucket}}",
+		"AUTHOR_ICON_URL": "https://example.com/logo.png",
+		"AUTHOR_URL":      "https://example.com/uploads",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", Bucket: "my_bucket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The name is plain text in Discord, so markdown escaping leaves it alone
+	want := &EmbedAuthor{Name: "Source: my_bucket", URL: "https://example.com/uploads", IconURL: "https://example.com/logo.png"}
+	if got := decodeDiscordMessage(t, body).Embeds[0].Author; !reflect.DeepEqual(got, want) {
+		t.Errorf("author = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildDiscordMessageNoAuthor(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), `"author"`) {
+		t.Errorf("body %s has an author block without AUTHOR_NAME", body)
+	}
+}
+
+func TestBuildDiscordMessageFooterIcon(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	payload := FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}
+
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), `"icon_url"`) {
+		t.Errorf("body %s has a footer icon_url without FOOTER_ICON_URL", body)
+	}
+
+	cfg.FooterIconURL = "https://example.com/logo.png"
+	body, err = buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer := decodeDiscordMessage(t, body).Embeds[0].Footer
+	if footer.IconURL != cfg.FooterIconURL || footer.Text != footerText {
+		t.Errorf("footer = %+v, want the icon next to the text", footer)
+	}
+}
+
+func TestDiscordWebhookURLThreadID(t *testing.T) {
+	cfg := Config{Platform: platformDiscord, DiscordThreadID: "1234567890"}
+	for webhookURL, want := range map[string]string{
+		"https://discord.com/api/webhooks/1/token":               "https://discord.com/api/webhooks/1/token?thread_id=1234567890",
+		"https://discord.com/api/webhooks/1/token?with_comps=1":  "https://discord.com/api/webhooks/1/token?thread_id=1234567890&with_comps=1",
+		"https://discord.com/api/webhooks/1/token?thread_id=999": "https://discord.com/api/webhooks/1/token?thread_id=1234567890",
+	} {
+		if got := discordWebhookURL(cfg, webhookURL); got != want {
+			t.Errorf("discordWebhookURL(%q) = %q, want %q", webhookURL, got, want)
+		}
+	}
+
+	// Other platforms and unset options leave the URL alone
+	plain := "https://discord.com/api/webhooks/1/token"
+	if got := discordWebhookURL(Config{Platform: platformDiscord}, plain); got != plain {
+		t.Errorf("discordWebhookURL without a thread = %q", got)
+	}
+	if got := discordWebhookURL(Config{Platform: platformSlack, DiscordThreadID: "1"}, "https://hooks.slack.com/x"); got != "https://hooks.slack.com/x" {
+		t.Errorf("discordWebhookURL for Slack = %q", got)
+	}
+}
+
+func TestSendDiscordThread(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.DiscordThreadID = "1234567890"
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if query := srv.received()[0].Query; query != "thread_id=1234567890" {
+		t.Errorf("query = %q, want thread_id=1234567890", query)
+	}
+}
+
+func TestBuildDiscordMessageThreadName(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.DiscordThreadName = "Uploads"
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeDiscordMessage(t, body).ThreadName; got != "Uploads" {
+		t.Errorf("thread_name = %q, want Uploads", got)
+	}
+	if got := discordWebhookURL(cfg, "https://discord.com/api/webhooks/1/token"); strings.Contains(got, "thread") {
+		t.Errorf("DISCORD_THREAD_NAME added a query parameter: %s", got)
+	}
+}
+
+func TestLoadConfigDiscordThreadExclusive(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"DISCORD_THREAD_ID": "1234567890", "DISCORD_THREAD_NAME": "Uploads"},
+		{"DISCORD_THREAD_ID": "uploads"},
+	} {
+		setenvConfig(t, env)
+		if _, err := loadConfig(context.Background()); err == nil {
+			t.Errorf("loadConfig with %v: want an error", env)
+		}
+	}
+}
+
+func TestParseDiscordMessageID(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"id": "1178243911456817233", "channel_id": "1", "content": ""}`: "1178243911456817233",
+		``:           "",
+		`not json`:   "",
+		`{"id": 42}`: "",
+	} {
+		if got := parseDiscordMessageID(strings.NewReader(body)); got != want {
+			t.Errorf("parseDiscordMessageID(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestSendDiscordWaitLogsMessageID(t *testing.T) {
+	var query string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Write([]byte(`{"id": "1178243911456817233", "type": 0, "channel_id": "1"}`))
+	}))
+	defer srv.Close()
+
+	logs := captureLogs(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.DiscordWait = true
+	d := NewDispatcher(cfg)
+	d.Client = srv.Client()
+	d.Logger = newLogger(slog.LevelInfo)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if query != "wait=true" {
+		t.Errorf("query = %q, want wait=true", query)
+	}
+	if !strings.Contains(logs.String(), `"discordMessageId":"1178243911456817233"`) {
+		t.Errorf("logs = %s, want the created message ID", logs)
+	}
+}
+
+func TestSendDiscordWithoutWait(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if query := srv.received()[0].Query; query != "" {
+		t.Errorf("query = %q, want none with DISCORD_WAIT unset", query)
+	}
+	if strings.Contains(logs.String(), "discordMessageId") {
+		t.Errorf("logged a message ID for a fire-and-forget 204: %s", logs)
+	}
+}
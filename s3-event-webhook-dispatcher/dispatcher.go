@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// Dispatcher builds webhook messages from file payloads and delivers them
+type Dispatcher struct {
+	Config Config
+	Client *http.Client
+	Logger *slog.Logger
+
+	// slots holds a token for every webhook request in flight
+	slots chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher using the HTTP client shared across warm
+// invocations and a JSON logger at the configured LOG_LEVEL, allowing up to
+// MAX_CONCURRENCY webhook requests at once
+func NewDispatcher(cfg Config) *Dispatcher {
+	return &Dispatcher{
+		Config: cfg,
+		Client: sharedHTTPClient(cfg),
+		Logger: newLogger(cfg.LogLevel),
+		slots:  make(chan struct{}, cfg.MaxConcurrency),
+	}
+}
+
+// BuildMessage serializes the payload into the request body for the configured platform
+func (d *Dispatcher) BuildMessage(payload FilePayload) ([]byte, error) {
+	return buildMessage(d.Config, payload)
+}
+
+// Dispatch builds the message for a file and sends it to every webhook and
+// DESTINATIONS entry, or to the webhook of the first matching ROUTING_RULES
+// rule. Files excluded by the filters and duplicates within
+// DEDUP_WINDOW_SECONDS are skipped without error, as are events already
+// claimed in IDEMPOTENCY_TABLE; payloads missing REQUIRED_FIELDS fail before
+// any request is made.
+// A file that can't be delivered is archived to FAILURE_BUCKET, and is
+// handled once it is published to FAILURE_SNS_TOPIC_ARN. Each dispatch gets
+// its own request ID, sent as X-Request-Id and logged as requestId.
+func (d *Dispatcher) Dispatch(ctx context.Context, payload FilePayload) error {
+	ctx = withDispatchIDs(ctx, dispatchIDs{RequestID: newRequestID(), CorrelationID: payload.CorrelationID})
+	if d.skipFiltered(ctx, payload) {
+		return nil
+	}
+	if err := d.checkPayload(ctx, payload); err != nil {
+		return err
+	}
+	proceed, err := d.claimEvent(ctx, payload)
+	if err != nil || !proceed {
+		return err
+	}
+
+	err = d.routed(payload).deliverAll(ctx, d.shortenLink(ctx, payload))
+	if err != nil && d.reportFailure(ctx, payload, err) {
+		return nil
+	}
+	if err != nil {
+		d.releaseEvent(ctx, payload)
+	}
+	return err
+}
+
+// checkPayload logs and returns the error for a payload missing REQUIRED_FIELDS
+func (d *Dispatcher) checkPayload(ctx context.Context, payload FilePayload) error {
+	err := checkRequiredFields(d.Config, payload)
+	if err != nil {
+		d.Logger.WarnContext(ctx, "rejecting payload missing required fields",
+			slog.String("fileName", payload.FileName),
+			slog.String("bucket", payload.Bucket),
+			slog.String("error", err.Error()))
+	}
+	return err
+}
+
+// deliver builds and sends the message for one file. With ATTACH_FILES on
+// Discord, small files are uploaded with the message and anything that can't
+// be attached, including uploads over MAX_BODY_BYTES, falls back to the link.
+func (d *Dispatcher) deliver(ctx context.Context, payload FilePayload) error {
+	if d.canAttach(payload) {
+		body, contentType, err := buildDiscordAttachment(ctx, d.Config, payload)
+		if err == nil {
+			err = d.send(ctx, body, contentType, []FilePayload{payload})
+			if !errors.Is(err, ErrBodyTooLarge) {
+				return err
+			}
+		}
+		d.Logger.WarnContext(ctx, "sending link instead of attachment",
+			slog.String("fileName", payload.FileName),
+			slog.String("reason", err.Error()))
+	}
+
+	body, err := d.BuildMessage(payload)
+	if err != nil {
+		return err
+	}
+	return d.sendJSON(ctx, body, []FilePayload{payload})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDispatcherBuildMessage(t *testing.T) {
+	d := NewDispatcher(testConfig(t, "https://discord.com/api/webhooks/1/token"))
+
+	body, err := d.BuildMessage(FilePayload{
+		FileName:       "report.pdf",
+		FileURL:        "https://example.com/report.pdf",
+		Bucket:         "invoices",
+		ExpirationTime: "24 hours",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := decodeDiscordMessage(t, body)
+	if len(message.Embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(message.Embeds))
+	}
+	embed := message.Embeds[0]
+	if embed.Title != messageTitle {
+		t.Errorf("title = %q, want %q", embed.Title, messageTitle)
+	}
+	for _, want := range []string{"report.pdf", "https://example.com/report.pdf", "24 hours"} {
+		if !strings.Contains(embed.Description, want) {
+			t.Errorf("description %q doesn't contain %q", embed.Description, want)
+		}
+	}
+	if embed.Color != defaultEmbedColor || embed.Footer.Text != footerText {
+		t.Errorf("color = %d, footer = %q", embed.Color, embed.Footer.Text)
+	}
+}
+
+func TestDispatcherBuildMessageDoesNotSend(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	if _, err := d.BuildMessage(FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("BuildMessage made %d requests", n)
+	}
+}
+
+func TestDispatcherSend(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	body := []byte(`{"content":"hello"}`)
+	if err := d.Send(context.Background(), body); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if requests[0].Method != http.MethodPost || !bytes.Equal(requests[0].Body, body) {
+		t.Errorf("got %s %s, want POST %s", requests[0].Method, requests[0].Body, body)
+	}
+	if ct := requests[0].Header.Get("Content-Type"); ct != jsonContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, jsonContentType)
+	}
+}
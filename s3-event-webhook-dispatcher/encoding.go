@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// formContentType is the media type whose bodies are sent as form values instead of JSON
+const formContentType = "application/x-www-form-urlencoded"
+
+// compressGzip is the COMPRESS_BODY value that gzips message bodies
+const compressGzip = "gzip"
+
+// allowedHTTPMethods are the HTTP_METHOD values accepted for webhook requests
+var allowedHTTPMethods = []string{
+	http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	http.MethodGet, http.MethodHead, http.MethodOptions,
+}
+
+// parseHTTPMethod reads HTTP_METHOD, defaulting to POST, and checks it is a known verb
+func parseHTTPMethod(raw string) (string, error) {
+	method := strings.ToUpper(strings.TrimSpace(raw))
+	if method == "" {
+		return http.MethodPost, nil
+	}
+	for _, allowed := range allowedHTTPMethods {
+		if method == allowed {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported HTTP_METHOD %q (supported: %s)", raw, strings.Join(allowedHTTPMethods, ", "))
+}
+
+// parseContentType reads CONTENT_TYPE, defaulting to application/json, and checks it is a valid media type
+func parseContentType(raw string) (string, error) {
+	contentType := strings.TrimSpace(raw)
+	if contentType == "" {
+		return jsonContentType, nil
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return "", fmt.Errorf("invalid CONTENT_TYPE %q: %w", raw, err)
+	}
+	return contentType, nil
+}
+
+// parseCompressBody reads COMPRESS_BODY, which is either unset or "gzip"
+func parseCompressBody(raw string) (string, error) {
+	switch compression := strings.ToLower(strings.TrimSpace(raw)); compression {
+	case "", compressGzip:
+		return compression, nil
+	default:
+		return "", fmt.Errorf("unsupported COMPRESS_BODY %q (supported: gzip)", raw)
+	}
+}
+
+// gzipBody compresses a message body for Content-Encoding: gzip
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to gzip message body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to gzip message body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// isFormContentType reports whether the content type is form encoding
+func isFormContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == formContentType
+}
+
+// encodeMessage converts a built JSON message into the configured CONTENT_TYPE,
+// returning the body and the Content-Type header to send with it
+func encodeMessage(cfg Config, messageJSON []byte) ([]byte, string, error) {
+	if !isFormContentType(cfg.ContentType) {
+		return messageJSON, cfg.ContentType, nil
+	}
+
+	body, err := formEncode(messageJSON)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, cfg.ContentType, nil
+}
+
+// formEncode turns the top-level fields of a JSON object into form values.
+// Strings are sent as-is; other values, such as nested objects, as JSON.
+func formEncode(messageJSON []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(messageJSON, &fields); err != nil {
+		return nil, fmt.Errorf("form encoding requires the message to be a JSON object: %w", err)
+	}
+
+	values := url.Values{}
+	for name, raw := range fields {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			values.Set(name, text)
+			continue
+		}
+		values.Set(name, string(raw))
+	}
+	return []byte(values.Encode()), nil
+}
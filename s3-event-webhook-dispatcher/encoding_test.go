@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSendPutJSON(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.HTTPMethod = http.MethodPut
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Send(context.Background(), []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	request := srv.received()[0]
+	if request.Method != http.MethodPut || request.Header.Get("Content-Type") != jsonContentType || string(request.Body) != `{"a":1}` {
+		t.Errorf("got %s %q %s, want PUT with the JSON body", request.Method, request.Header.Get("Content-Type"), request.Body)
+	}
+}
+
+func TestSendPostForm(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.ContentType = formContentType
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Send(context.Background(), []byte(`{"text":"hi there","n":{"x":1}}`)); err != nil {
+		t.Fatal(err)
+	}
+	request := srv.received()[0]
+	if request.Method != http.MethodPost || request.Header.Get("Content-Type") != formContentType {
+		t.Fatalf("got %s %q, want a form POST", request.Method, request.Header.Get("Content-Type"))
+	}
+	values, err := url.ParseQuery(string(request.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.Get("text") != "hi there" || values.Get("n") != `{"x":1}` {
+		t.Errorf("form values = %v", values)
+	}
+}
+
+func TestParseHTTPMethod(t *testing.T) {
+	for raw, want := range map[string]string{"": http.MethodPost, "put": http.MethodPut, " PATCH ": http.MethodPatch} {
+		if got, err := parseHTTPMethod(raw); err != nil || got != want {
+			t.Errorf("parseHTTPMethod(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseHTTPMethod("FETCH"); err == nil {
+		t.Error("parseHTTPMethod accepted an unknown verb")
+	}
+}
+
+func TestParseContentType(t *testing.T) {
+	if got, err := parseContentType(""); err != nil || got != jsonContentType {
+		t.Errorf("parseContentType(\"\") = %q, %v, want %q", got, err, jsonContentType)
+	}
+	if _, err := parseContentType("not a/type;;"); err == nil {
+		t.Error("parseContentType accepted an invalid media type")
+	}
+}
+
+func TestSendGzipRoundTrip(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.Platform = platformGeneric
+	cfg.CompressBody = compressGzip
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelDebug)
+
+	original := []byte(`{"files":["` + strings.Repeat("a.txt", 200) + `"]}`)
+	if err := d.Send(context.Background(), original); err != nil {
+		t.Fatal(err)
+	}
+
+	request := srv.received()[0]
+	if got := request.Header.Get("Content-Encoding"); got != compressGzip {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := request.Header.Get("Content-Type"); got != jsonContentType {
+		t.Errorf("Content-Type = %q, want %q", got, jsonContentType)
+	}
+	if len(request.Body) >= len(original) {
+		t.Errorf("sent %d bytes for a %d-byte body, want it compressed", len(request.Body), len(original))
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(request.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, original) {
+		t.Errorf("decompressed body = %s, want %s", decompressed, original)
+	}
+
+	if !strings.Contains(logs.String(), "compressed message body") || !strings.Contains(logs.String(), `"originalBytes":`) {
+		t.Errorf("debug log missing the uncompressed size: %s", logs)
+	}
+}
+
+func TestSendUncompressedByDefault(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	if err := d.Send(context.Background(), []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	request := srv.received()[0]
+	if request.Header.Get("Content-Encoding") != "" || string(request.Body) != `{"a":1}` {
+		t.Errorf("got Content-Encoding %q and body %s, want the plain body", request.Header.Get("Content-Encoding"), request.Body)
+	}
+}
+
+func TestParseCompressBody(t *testing.T) {
+	for raw, want := range map[string]string{"": "", "gzip": compressGzip, " GZIP ": compressGzip} {
+		if got, err := parseCompressBody(raw); err != nil || got != want {
+			t.Errorf("parseCompressBody(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseCompressBody("br"); err == nil {
+		t.Error("parseCompressBody accepted an unsupported encoding")
+	}
+}
+
+func TestCompressBodyOnlyForGeneric(t *testing.T) {
+	setenvConfig(t, map[string]string{"COMPRESS_BODY": "gzip"})
+	if _, err := loadConfig(context.Background()); err == nil || !strings.Contains(err.Error(), "COMPRESS_BODY") {
+		t.Errorf("loadConfig with COMPRESS_BODY for Discord: err = %v, want a COMPRESS_BODY error", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrorBodyBytes bounds how much of a failed response body is kept in errors and logs
+const maxErrorBodyBytes = 500
+
+// tokenPattern matches long unbroken runs of token characters, such as
+// webhook tokens or API keys echoed back by the receiver
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9_\-]{32,}`)
+
+// Sentinel errors for failure modes callers branch on with errors.Is
+var (
+	// ErrNoWebhookURL means no webhook URL was configured by any source
+	ErrNoWebhookURL = errors.New("no webhook URL configured")
+
+	// ErrWebhookLookup means the webhook URL couldn't be read from Secrets
+	// Manager or Parameter Store; unlike other config errors it may be transient
+	ErrWebhookLookup = errors.New("failed to look up webhook URL")
+
+	// ErrPayloadParse means the invocation event or its file payload couldn't be
+	// decoded; retrying the same message won't help
+	ErrPayloadParse = errors.New("failed to parse payload")
+
+	// ErrBodyTooLarge means the serialized message is over MAX_BODY_BYTES and
+	// was not sent
+	ErrBodyTooLarge = errors.New("message body too large")
+
+	// ErrMissingFields means the file payload lacks a REQUIRED_FIELDS value;
+	// retrying the same message won't help
+	ErrMissingFields = errors.New("payload is missing required field(s)")
+
+	// ErrPanic means building or sending a message panicked; the panic was
+	// recovered and logged with its stack so the event is retried as a failure
+	ErrPanic = errors.New("recovered from panic")
+)
+
+// recoverPanic, deferred with the address of a function's error result,
+// turns a panic into an ErrPanic error and logs the stack that caused it
+func recoverPanic(ctx context.Context, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*err = fmt.Errorf("%w: %v", ErrPanic, r)
+	slog.ErrorContext(ctx, "recovered from panic",
+		slog.String("error", (*err).Error()),
+		slog.String("stack", string(debug.Stack())))
+}
+
+// WebhookStatusError reports a webhook response with a status code outside ACCEPTED_STATUS_CODES
+type WebhookStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error describes the status code and, when present, the response body
+func (e *WebhookStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook returned non-success status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook returned non-success status code: %d: %s", e.StatusCode, e.Body)
+}
+
+// Failure classes of a webhook delivery, logged as failureClass and used as
+// the FailureClass metric dimension so slow, rejecting and unreachable
+// webhooks can be alerted on separately
+const (
+	failureTimeout     = "timeout"
+	failureConnection  = "connection"
+	failureHTTP4xx     = "http_4xx"
+	failureHTTP5xx     = "http_5xx"
+	failureRateLimited = "rate_limited"
+	failureOther       = "other"
+)
+
+// classifyFailure returns the failure class of a delivery error, or "" when
+// the delivery succeeded. A status code decides first, since retries that
+// ran out of time still wrap the last response they got.
+func classifyFailure(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var statusErr *WebhookStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return failureRateLimited
+		case statusErr.StatusCode >= 500:
+			return failureHTTP5xx
+		default:
+			return failureHTTP4xx
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return failureTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return failureTimeout
+	case errors.As(err, &netErr):
+		return failureConnection
+	default:
+		return failureOther
+	}
+}
+
+// sanitizeResponseBody prepares a response body for errors and logs: invalid
+// UTF-8 is replaced, token-like substrings are masked, and the result is cut
+// to maxErrorBodyBytes without splitting a character
+func sanitizeResponseBody(body []byte) string {
+	text := strings.ToValidUTF8(string(body), "\uFFFD")
+	text = tokenPattern.ReplaceAllString(text, "[REDACTED]")
+	text = strings.TrimSpace(text)
+
+	if len(text) <= maxErrorBodyBytes {
+		return text
+	}
+	cut := maxErrorBodyBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + ellipsis
+}
+
+// multiError combines independent failures into one "; "-separated error that
+// errors.Is and errors.As can see through
+type multiError []error
+
+// Error joins the messages of every failure
+func (e multiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap exposes the individual failures to errors.Is and errors.As
+func (e multiError) Unwrap() []error {
+	return e
+}
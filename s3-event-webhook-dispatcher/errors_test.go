@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestDispatchReturnsWebhookStatusError(t *testing.T) {
+	srv := newWebhookServer(t, http.StatusNotFound)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	var statusErr *WebhookStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want a *WebhookStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestHandlerSentinelErrors(t *testing.T) {
+	setenvConfig(t, nil)
+	if _, err := Handler(context.Background(), []byte(`{"detail-type":"File Uploaded","detail":"not an object"}`)); !errors.Is(err, ErrPayloadParse) {
+		t.Errorf("err = %v, want ErrPayloadParse", err)
+	}
+
+	setenvConfig(t, map[string]string{"WEBHOOK_URL": ""})
+	if _, err := Handler(context.Background(), []byte(`{"detail":{"fileName":"a.txt"}}`)); !errors.Is(err, ErrNoWebhookURL) {
+		t.Errorf("err = %v, want ErrNoWebhookURL", err)
+	}
+}
+
+func TestWebhookStatusErrorMessage(t *testing.T) {
+	if got := (&WebhookStatusError{StatusCode: 502}).Error(); got != "webhook returned non-success status code: 502" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := (&WebhookStatusError{StatusCode: 400, Body: "bad"}).Error(); got != "webhook returned non-success status code: 400: bad" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestStatusErrorIncludesResponseBody(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "Invalid Form Body", "code": 50035, "token": "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"}`))
+	}))
+	defer srv.Close()
+	d := &Dispatcher{Config: testConfig(t, srv.URL), Client: srv.Client(), Logger: newLogger(slogQuiet)}
+
+	_, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType)
+	var statusErr *WebhookStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want a *WebhookStatusError", err)
+	}
+	if !strings.Contains(statusErr.Body, `"message": "Invalid Form Body"`) || !strings.Contains(err.Error(), "50035") {
+		t.Errorf("err = %v, want Discord's validation error in it", err)
+	}
+	if strings.Contains(err.Error(), "abcdefghijklmnop") {
+		t.Errorf("err = %v, want the token redacted", err)
+	}
+}
+
+func TestSanitizeResponseBody(t *testing.T) {
+	long := sanitizeResponseBody([]byte(strings.Repeat("é", 400) + "\xff"))
+	if !utf8.ValidString(long) {
+		t.Error("sanitized body isn't valid UTF-8")
+	}
+	if !strings.HasSuffix(long, ellipsis) || len(long) > maxErrorBodyBytes+len(ellipsis) {
+		t.Errorf("sanitized body has %d bytes, want it cut to %d", len(long), maxErrorBodyBytes)
+	}
+	if got := sanitizeResponseBody([]byte("bad \xff input")); got != "bad � input" {
+		t.Errorf("sanitizeResponseBody = %q", got)
+	}
+}
+
+func TestDispatchLogsResponseBody(t *testing.T) {
+	logs := captureLogs(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "Invalid Form Body"}`))
+	}))
+	defer srv.Close()
+	d := &Dispatcher{Config: testConfig(t, srv.URL), Client: srv.Client(), Logger: newLogger(slog.LevelInfo)}
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err == nil {
+		t.Fatal("want an error for the 400 response")
+	}
+	if !strings.Contains(logs.String(), `"responseBody":"{\"message\": \"Invalid Form Body\"}"`) {
+		t.Errorf("logs have no responseBody field:\n%s", logs)
+	}
+}
+
+// stubPanickingBuilder makes building a Discord message panic until the test ends
+func stubPanickingBuilder(t *testing.T) {
+	t.Helper()
+	original := messageBuilders[platformDiscord]
+	messageBuilders[platformDiscord] = func(Config, FilePayload) ([]byte, error) {
+		var payload *FilePayload
+		return []byte(payload.FileName), nil
+	}
+	t.Cleanup(func() { messageBuilders[platformDiscord] = original })
+}
+
+func TestDispatchRecoversBuildPanic(t *testing.T) {
+	logs := captureLogs(t)
+	original := slog.Default()
+	slog.SetDefault(newLogger(slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(original) })
+	stubPanickingBuilder(t)
+
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	if !errors.Is(err, ErrPanic) || !strings.Contains(err.Error(), "nil pointer dereference") {
+		t.Fatalf("err = %v, want ErrPanic describing the panic", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("webhook received %d requests after a panic, want none", n)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("log output %q: %v", logs, err)
+	}
+	if record["msg"] != "recovered from panic" || !strings.Contains(fmt.Sprint(record["stack"]), "stubPanickingBuilder") {
+		t.Errorf("log record = %v, want the panic logged with its stack", record)
+	}
+}
+
+func TestHandlerRecoversPanic(t *testing.T) {
+	// Handler makes its logger the default, so keep it from writing to stdout
+	logs := captureLogs(t)
+	original := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(original) })
+	setenvConfig(t, nil)
+	stubPanickingBuilder(t)
+
+	_, err := Handler(context.Background(), []byte(`{"detail-type":"File Uploaded","detail":{"fileName":"a.txt","fileUrl":"https://example.com/a"}}`))
+	if !errors.Is(err, ErrPanic) {
+		t.Errorf("Handler err = %v, want ErrPanic", err)
+	}
+	if !strings.Contains(logs.String(), `"stack":`) {
+		t.Errorf("panic logged without its stack: %s", logs)
+	}
+}
+
+// deliveryError returns the error of a single-attempt delivery to webhookURL
+func deliveryError(t *testing.T, webhookURL string, client *http.Client) error {
+	t.Helper()
+	cfg := testConfig(t, webhookURL)
+	cfg.RequestTimeout = 200 * time.Millisecond
+	d := &Dispatcher{Config: cfg, Client: client, Logger: newLogger(slogQuiet)}
+	_, err := d.sendWithRetry(context.Background(), webhookURL, []byte(`{}`), jsonContentType)
+	if err == nil {
+		t.Fatalf("delivery to %s succeeded, want a failure", webhookHost(webhookURL))
+	}
+	return err
+}
+
+func TestClassifyFailureStatusCodes(t *testing.T) {
+	for status, want := range map[int]string{
+		http.StatusBadRequest:          failureHTTP4xx,
+		http.StatusNotFound:            failureHTTP4xx,
+		http.StatusTooManyRequests:     failureRateLimited,
+		http.StatusInternalServerError: failureHTTP5xx,
+		http.StatusBadGateway:          failureHTTP5xx,
+	} {
+		srv := newWebhookServer(t, status)
+		if got := classifyFailure(deliveryError(t, srv.URL, srv.Client())); got != want {
+			t.Errorf("status %d classified as %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestClassifyFailureTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	if got := classifyFailure(deliveryError(t, srv.URL, srv.Client())); got != failureTimeout {
+		t.Errorf("slow webhook classified as %q, want %q", got, failureTimeout)
+	}
+	if got := classifyFailure(fmt.Errorf("waiting: %w", context.DeadlineExceeded)); got != failureTimeout {
+		t.Errorf("context.DeadlineExceeded classified as %q, want %q", got, failureTimeout)
+	}
+}
+
+func TestClassifyFailureConnection(t *testing.T) {
+	// A closed listener's address refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhookURL := "https://" + listener.Addr().String() + "/hook"
+	listener.Close()
+
+	if got := classifyFailure(deliveryError(t, webhookURL, http.DefaultClient)); got != failureConnection {
+		t.Errorf("refused connection classified as %q, want %q", got, failureConnection)
+	}
+}
+
+func TestClassifyFailureOther(t *testing.T) {
+	if got := classifyFailure(nil); got != "" {
+		t.Errorf("classifyFailure(nil) = %q, want \"\"", got)
+	}
+	if got := classifyFailure(ErrBodyTooLarge); got != failureOther {
+		t.Errorf("ErrBodyTooLarge classified as %q, want %q", got, failureOther)
+	}
+
+	// A response that ran out of retries is still classified by its status code
+	err := fmt.Errorf("deadline exceeded after 2 attempt(s): %w", &WebhookStatusError{StatusCode: 503})
+	if got := classifyFailure(err); got != failureHTTP5xx {
+		t.Errorf("exhausted retries after a 503 classified as %q, want %q", got, failureHTTP5xx)
+	}
+}
+
+func TestDispatchLogsFailureClass(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t, http.StatusTooManyRequests)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err == nil {
+		t.Fatal("Dispatch succeeded against a rate-limited webhook")
+	}
+	if !strings.Contains(logs.String(), `"failureClass":"rate_limited"`) {
+		t.Errorf("logs = %s, want failureClass rate_limited", logs)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Send delivers a serialized message to every configured webhook concurrently.
+// One failing endpoint doesn't stop delivery to the others; an error is
+// returned only when every webhook failed.
+func (d *Dispatcher) Send(ctx context.Context, body []byte) error {
+	return d.sendJSON(ctx, body, nil)
+}
+
+// sendJSON converts a built JSON message to the configured CONTENT_TYPE,
+// compresses it when COMPRESS_BODY is set, and sends it. Dry runs log the
+// uncompressed body so it stays readable.
+func (d *Dispatcher) sendJSON(ctx context.Context, messageJSON []byte, files []FilePayload) error {
+	body, contentType, err := encodeMessage(d.Config, messageJSON)
+	if err != nil {
+		return err
+	}
+
+	if d.Config.CompressBody == compressGzip && !d.Config.DryRun {
+		compressed, err := gzipBody(body)
+		if err != nil {
+			return err
+		}
+		d.Logger.DebugContext(ctx, "compressed message body",
+			slog.Int("originalBytes", len(body)),
+			slog.Int("compressedBytes", len(compressed)))
+		body = compressed
+	}
+	return d.send(ctx, body, contentType, files)
+}
+
+// send is Send for a body of any content type built from files, which are
+// named in the dispatch log records
+func (d *Dispatcher) send(ctx context.Context, body []byte, contentType string, files []FilePayload) error {
+	cfg := d.Config
+
+	// Receivers reject oversized bodies with opaque errors, so say so up front
+	if cfg.MaxBodyBytes > 0 && len(body) > cfg.MaxBodyBytes {
+		attrs := append(fileAttrs(files),
+			slog.Int("bodyBytes", len(body)),
+			slog.Int("maxBodyBytes", cfg.MaxBodyBytes),
+		)
+		d.Logger.LogAttrs(ctx, slog.LevelWarn, "message body exceeds MAX_BODY_BYTES", attrs...)
+		return fmt.Errorf("%w: %d bytes exceeds MAX_BODY_BYTES of %d", ErrBodyTooLarge, len(body), cfg.MaxBodyBytes)
+	}
+
+	// In dry-run mode the finished body is logged instead of being sent
+	if cfg.DryRun {
+		hosts := make([]string, len(cfg.WebhookURLs))
+		for i, webhookURL := range cfg.WebhookURLs {
+			hosts[i] = webhookHost(webhookURL)
+		}
+		attrs := append(fileAttrs(files),
+			slog.String("webhookHosts", strings.Join(hosts, ", ")),
+			slog.String("body", string(body)),
+		)
+		d.Logger.LogAttrs(ctx, slog.LevelInfo, "dry run: webhook request not sent", attrs...)
+		return nil
+	}
+	errs := make([]error, len(cfg.WebhookURLs))
+
+	var wg sync.WaitGroup
+	for i, webhookURL := range cfg.WebhookURLs {
+		wg.Add(1)
+		go func(i int, webhookURL string) {
+			defer wg.Done()
+			start := time.Now()
+			result, err := d.sendTraced(ctx, webhookURL, body, contentType)
+			elapsed := time.Since(start)
+			d.logDispatch(ctx, webhookURL, files, result, elapsed, err)
+			d.emitDispatchMetrics(webhookURL, elapsed, err)
+			errs[i] = err
+		}(i, webhookURL)
+	}
+	wg.Wait()
+
+	// Collect failures, naming each webhook without exposing its token
+	var failures multiError
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", describeWebhook(i, cfg.WebhookURLs[i]), err))
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	if len(failures) < len(cfg.WebhookURLs) {
+		d.Logger.WarnContext(ctx, "partial webhook delivery",
+			slog.Int("delivered", len(cfg.WebhookURLs)-len(failures)),
+			slog.Int("webhooks", len(cfg.WebhookURLs)),
+			slog.String("failures", failures.Error()))
+		return nil
+	}
+	return fmt.Errorf("all %d webhook(s) failed: %w", len(cfg.WebhookURLs), failures)
+}
+
+// logDispatch emits the structured record for one delivery to one webhook
+func (d *Dispatcher) logDispatch(ctx context.Context, webhookURL string, files []FilePayload, result deliveryResult, elapsed time.Duration, err error) {
+	attrs := append(fileAttrs(files),
+		slog.String("webhookHost", webhookHost(webhookURL)),
+		slog.Int("statusCode", result.StatusCode),
+		slog.Int("attempts", result.Attempts),
+		slog.Int64("durationMs", elapsed.Milliseconds()),
+	)
+	if result.MessageID != "" {
+		attrs = append(attrs, slog.String("discordMessageId", result.MessageID))
+	}
+	if err != nil {
+		attrs = append(attrs,
+			slog.String("error", err.Error()),
+			slog.String("failureClass", classifyFailure(err)))
+		var statusErr *WebhookStatusError
+		if errors.As(err, &statusErr) && statusErr.Body != "" {
+			attrs = append(attrs, slog.String("responseBody", statusErr.Body))
+		}
+		d.Logger.LogAttrs(ctx, slog.LevelError, "webhook dispatch failed", attrs...)
+		return
+	}
+	d.Logger.LogAttrs(ctx, slog.LevelInfo, "webhook dispatched", attrs...)
+}
+
+// describeWebhook identifies a webhook by position and host, since the full
+// URL usually embeds a secret token
+func describeWebhook(i int, webhookURL string) string {
+	return fmt.Sprintf("webhook #%d (%s)", i+1, webhookHost(webhookURL))
+}
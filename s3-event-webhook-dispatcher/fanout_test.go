@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSendToleratesPartialFailure(t *testing.T) {
+	logs := captureLogs(t)
+	ok := newWebhookServer(t)
+	failing := newWebhookServer(t, 400)
+	cfg := testConfig(t, ok.URL)
+	cfg.WebhookURLs = append(cfg.WebhookURLs, failing.URL)
+	d := newTestDispatcher(cfg, ok)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	if err := d.Send(context.Background(), []byte(`{"content":"hello"}`)); err != nil {
+		t.Fatalf("Send returned %v, want success while one webhook delivers", err)
+	}
+	if len(ok.received()) != 1 || len(failing.received()) != 1 {
+		t.Errorf("received %d and %d requests, want 1 each", len(ok.received()), len(failing.received()))
+	}
+	out := logs.String()
+	for _, want := range []string{`"msg":"partial webhook delivery"`, `"delivered":1`, `"webhooks":2`, "webhook #2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logs missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestSendFailsWhenEveryWebhookFails(t *testing.T) {
+	first := newWebhookServer(t, 400)
+	second := newWebhookServer(t, 500)
+	cfg := testConfig(t, first.URL)
+	cfg.WebhookURLs = append(cfg.WebhookURLs, second.URL)
+	d := newTestDispatcher(cfg, first)
+
+	err := d.Send(context.Background(), []byte(`{"content":"hello"}`))
+	if err == nil || !strings.Contains(err.Error(), "all 2 webhook(s) failed") {
+		t.Fatalf("Send error = %v, want every webhook reported", err)
+	}
+	if len(first.received()) != 1 || len(second.received()) != 1 {
+		t.Errorf("received %d and %d requests, want 1 each", len(first.received()), len(second.received()))
+	}
+}
+
+func TestDispatchDryRun(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.DryRun = true
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	payload := FilePayload{FileName: "dry.txt", FileURL: "https://example.com/dry.txt", Bucket: "invoices", Timestamp: "2025-05-17T10:00:00Z"}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("dry run made %d requests", n)
+	}
+
+	want, err := d.BuildMessage(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		Msg  string `json:"msg"`
+		Body string `json:"body"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("invalid log record %s: %v", logs, err)
+	}
+	if record.Body != string(want) {
+		t.Errorf("logged body = %s, want the serialized message %s", record.Body, want)
+	}
+}
+
+func TestSendRejectsOversizedBody(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxBodyBytes = 16
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelWarn)
+
+	err := d.Send(context.Background(), []byte(`{"content":"0123456789"}`))
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("err = %v, want ErrBodyTooLarge", err)
+	}
+	if !strings.Contains(err.Error(), "24 bytes") {
+		t.Errorf("err = %v, want the body size in it", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("oversized body sent %d requests", n)
+	}
+	if !strings.Contains(logs.String(), `"bodyBytes":24`) || !strings.Contains(logs.String(), `"maxBodyBytes":16`) {
+		t.Errorf("logs don't record the size:\n%s", logs)
+	}
+
+	if err := d.Send(context.Background(), []byte(`{"content":""}`)); err != nil {
+		t.Errorf("body within MAX_BODY_BYTES: %v", err)
+	}
+}
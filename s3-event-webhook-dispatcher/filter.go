@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// parseFilterList splits a comma-separated filter list into lowercase entries, dropping blanks
+func parseFilterList(raw string) []string {
+	var entries []string
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// matchesKeyFilters reports whether the decoded object key passes
+// KEY_PREFIX_FILTER and KEY_SUFFIX_FILTER. Each configured list must have at
+// least one case-insensitive match; an empty list allows every key.
+func matchesKeyFilters(cfg Config, key string) bool {
+	lower := strings.ToLower(key)
+	return matchesAny(lower, cfg.KeyPrefixFilter, strings.HasPrefix) &&
+		matchesAny(lower, cfg.KeySuffixFilter, strings.HasSuffix)
+}
+
+// matchesAny reports whether key matches one of patterns, or true when there are none
+func matchesAny(key string, patterns []string, match func(s, pattern string) bool) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKeyRegex compiles KEY_REGEX; an empty value disables the filter
+func parseKeyRegex(raw string) (*regexp.Regexp, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEY_REGEX: %w", err)
+	}
+	return pattern, nil
+}
+
+// Match returns the named capture groups of KEY_REGEX in the object key, for
+// templates such as {{.Match.year}}. The key is matched before escaping and
+// each capture escaped for the platform like FileName. A group that didn't
+// take part in the match is "", and without KEY_REGEX the map is empty.
+func (p FilePayload) Match() map[string]string {
+	captures := map[string]string{}
+	if p.keyRegex == nil {
+		return captures
+	}
+	key := p.FileName
+	if p.textEscaper != nil {
+		key = p.rawFileName
+	}
+	submatches := p.keyRegex.FindStringSubmatch(key)
+	for i, name := range p.keyRegex.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		captures[name] = ""
+		if submatches != nil {
+			captures[name] = submatches[i]
+			if p.textEscaper != nil {
+				captures[name] = p.textEscaper.Replace(submatches[i])
+			}
+		}
+	}
+	return captures
+}
+
+// parseExtensionSet reads a comma-separated extension list into a set of normalized extensions
+func parseExtensionSet(raw string) map[string]bool {
+	var set map[string]bool
+	for _, entry := range strings.Split(raw, ",") {
+		if ext := normalizeExtension(entry); ext != "" {
+			if set == nil {
+				set = make(map[string]bool)
+			}
+			set[ext] = true
+		}
+	}
+	return set
+}
+
+// matchesExtensionFilters reports whether the file's extension passes
+// BLOCKED_EXTENSIONS and ALLOWED_EXTENSIONS, with the blocklist taking
+// precedence. Files without an extension pass only with ALLOW_NO_EXTENSION.
+func matchesExtensionFilters(cfg Config, fileName string) bool {
+	ext := fileExtension(fileName)
+	if ext == "" {
+		return cfg.AllowNoExtension
+	}
+	if cfg.BlockedExtensions[ext] {
+		return false
+	}
+	return len(cfg.AllowedExtensions) == 0 || cfg.AllowedExtensions[ext]
+}
+
+// skipFiltered reports whether the payload is excluded by the key, regex,
+// extension or name filters, logging the skip
+func (d *Dispatcher) skipFiltered(ctx context.Context, payload FilePayload) bool {
+	var filter string
+	switch {
+	case !matchesKeyFilters(d.Config, payload.FileName):
+		filter = "key"
+	case d.Config.KeyRegex != nil && !d.Config.KeyRegex.MatchString(payload.FileName):
+		filter = "key-regex"
+	case !matchesExtensionFilters(d.Config, payload.FileName):
+		filter = "extension"
+	case d.Config.SkipZeroByte && isZeroByte(payload):
+		filter = "zero-byte"
+	case matchesSkipPattern(d.Config, payload.FileName):
+		filter = "name"
+	default:
+		return false
+	}
+
+	d.Logger.InfoContext(ctx, "skipping file excluded by filters",
+		slog.String("fileName", payload.FileName),
+		slog.String("bucket", payload.Bucket),
+		slog.String("filter", filter))
+	return true
+}
+
+// parseSkipPatterns compiles SKIP_NAME_PATTERNS, a JSON array of regular
+// expressions or, for patterns without commas, a comma-separated list
+func parseSkipPatterns(raw string) ([]*regexp.Regexp, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var sources []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &sources); err != nil {
+			return nil, fmt.Errorf("invalid SKIP_NAME_PATTERNS: %w", err)
+		}
+	} else {
+		sources = strings.Split(raw, ",")
+	}
+
+	var patterns []*regexp.Regexp
+	for _, source := range sources {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SKIP_NAME_PATTERNS pattern %q: %w", source, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// matchesSkipPattern reports whether the object key matches one of SKIP_NAME_PATTERNS
+func matchesSkipPattern(cfg Config, key string) bool {
+	for _, pattern := range cfg.SkipNamePatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isZeroByte reports whether an upload is known to be empty. A payload that
+// doesn't send fileSize isn't, and neither is a delete, which has no size.
+func isZeroByte(payload FilePayload) bool {
+	return payload.fileSizeSet && payload.FileSize == 0 && !payload.isDeleted()
+}
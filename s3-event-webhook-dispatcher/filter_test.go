@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestMatchesKeyFilters(t *testing.T) {
+	filtered := Config{KeyPrefixFilter: parseFilterList("public/, shared/"), KeySuffixFilter: parseFilterList(".PDF")}
+	tests := []struct {
+		name string
+		cfg  Config
+		key  string
+		want bool
+	}{
+		{"matched", filtered, "public/q1.pdf", true},
+		{"matched case-insensitively", filtered, "Shared/Q1.Pdf", true},
+		{"unmatched prefix", filtered, "private/q1.pdf", false},
+		{"unmatched suffix", filtered, "public/q1.docx", false},
+		{"no filters", Config{}, "anything/at/all.bin", true},
+		{"prefix only", Config{KeyPrefixFilter: parseFilterList("public/")}, "public/notes.txt", true},
+	}
+	for _, tt := range tests {
+		if got := matchesKeyFilters(tt.cfg, tt.key); got != tt.want {
+			t.Errorf("%s: matchesKeyFilters(%q) = %v, want %v", tt.name, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterList(t *testing.T) {
+	got := parseFilterList(" Public/ ,, .PDF,")
+	if len(got) != 2 || got[0] != "public/" || got[1] != ".pdf" {
+		t.Errorf("parseFilterList = %q, want [public/ .pdf]", got)
+	}
+	if got := parseFilterList(""); got != nil {
+		t.Errorf("parseFilterList(\"\") = %q, want nil", got)
+	}
+}
+
+func TestDispatchSkipsFilteredKey(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.KeyPrefixFilter = parseFilterList("public/")
+	cfg.KeySuffixFilter = parseFilterList(".pdf")
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "private/q1.pdf", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatalf("skipped file returned %v, want success", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Fatalf("unmatched key sent %d requests", n)
+	}
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "public/q1.pdf", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("matched key sent %d requests, want 1", n)
+	}
+}
+
+func TestHandleS3FiltersDecodedKey(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.KeyPrefixFilter = parseFilterList("public reports/")
+	d := newTestDispatcher(cfg, srv)
+
+	record := s3PutRecord("uploads", "public+reports/q1.pdf", 1)
+	if err := d.handleS3(context.Background(), events.S3Event{Records: []events.S3EventRecord{record}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("got %d requests, want the decoded key matched", n)
+	}
+}
+
+func TestMatchesExtensionFilters(t *testing.T) {
+	allow := parseExtensionSet("PDF, .csv")
+	block := parseExtensionSet(".tmp,part")
+	tests := []struct {
+		name     string
+		cfg      Config
+		fileName string
+		want     bool
+	}{
+		{"allowlisted", Config{AllowedExtensions: allow}, "q1.PDF", true},
+		{"not allowlisted", Config{AllowedExtensions: allow}, "q1.docx", false},
+		{"blocklisted", Config{BlockedExtensions: block}, "upload.part", false},
+		{"not blocklisted", Config{BlockedExtensions: block}, "q1.docx", true},
+		{"blocklist wins over allowlist", Config{AllowedExtensions: parseExtensionSet("tmp,pdf"), BlockedExtensions: block}, "a.tmp", false},
+		{"allowed and not blocked", Config{AllowedExtensions: allow, BlockedExtensions: block}, "a.csv", true},
+		{"no filters", Config{}, "a.anything", true},
+		{"no extension disallowed", Config{AllowedExtensions: allow}, "README", false},
+		{"no extension allowed", Config{AllowedExtensions: allow, AllowNoExtension: true}, "README", true},
+	}
+	for _, tt := range tests {
+		if got := matchesExtensionFilters(tt.cfg, tt.fileName); got != tt.want {
+			t.Errorf("%s: matchesExtensionFilters(%q) = %v, want %v", tt.name, tt.fileName, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigExtensionFilters(t *testing.T) {
+	setenvConfig(t, map[string]string{"ALLOWED_EXTENSIONS": "pdf", "BLOCKED_EXTENSIONS": ".TMP"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.AllowedExtensions["pdf"] || !cfg.BlockedExtensions["tmp"] {
+		t.Errorf("allowed = %v, blocked = %v", cfg.AllowedExtensions, cfg.BlockedExtensions)
+	}
+	if !cfg.AllowNoExtension {
+		t.Error("ALLOW_NO_EXTENSION doesn't default to true")
+	}
+}
+
+// detailEvent wraps a JSON detail in an EventBridge event
+func detailEvent(detail string) events.CloudWatchEvent {
+	return events.CloudWatchEvent{DetailType: "File Uploaded", Detail: json.RawMessage(detail)}
+}
+
+func TestDispatchSkipsZeroByteFile(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.SkipZeroByte = true
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	if err := d.handleEvent(context.Background(), detailEvent(`{"fileName":"upload.part","fileUrl":"https://example.com/a","fileSize":0}`)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("zero-byte file sent %d requests, want none", n)
+	}
+	if !strings.Contains(logs.String(), `"filter":"zero-byte"`) {
+		t.Errorf("logs = %s, want the skip logged", logs)
+	}
+
+	// Without a fileSize the size is unknown, so the file isn't skipped
+	if err := d.handleEvent(context.Background(), detailEvent(`{"fileName":"report.pdf","fileUrl":"https://example.com/a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("file without fileSize sent %d requests, want 1", n)
+	}
+}
+
+func TestDispatchSkipsNamePatterns(t *testing.T) {
+	logs := captureLogs(t)
+	patterns, err := parseSkipPatterns(`["\\.tmp$", "(^|/)\\.keep$"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.SkipNamePatterns = patterns
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	for _, name := range []string{"uploads/report.pdf.tmp", "uploads/.keep"} {
+		if err := d.Dispatch(context.Background(), FilePayload{FileName: name, FileURL: "https://example.com/a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("temp files sent %d requests, want none", n)
+	}
+	if !strings.Contains(logs.String(), `"filter":"name"`) {
+		t.Errorf("logs = %s, want the skip logged", logs)
+	}
+
+	// A normal file passes through, even one merely containing .tmp
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "uploads/report.tmp.pdf", FileURL: "https://example.com/a", FileSize: 2048}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("normal file sent %d requests, want 1", n)
+	}
+}
+
+func TestParseSkipPatterns(t *testing.T) {
+	patterns, err := parseSkipPatterns(`\.tmp$, ^_`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 2 || !patterns[0].MatchString("a.tmp") || !patterns[1].MatchString("_staging") {
+		t.Errorf("patterns = %v", patterns)
+	}
+
+	for _, raw := range []string{`["(unclosed"]`, `[*.tmp`, `a(b`} {
+		if _, err := parseSkipPatterns(raw); err == nil {
+			t.Errorf("parseSkipPatterns(%s): want an error", raw)
+		}
+	}
+	setenvConfig(t, map[string]string{"SKIP_NAME_PATTERNS": "*.tmp"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted an invalid SKIP_NAME_PATTERNS regex")
+	}
+}
+
+func TestIsZeroByteIgnoresDeletes(t *testing.T) {
+	var payload FilePayload
+	if err := json.Unmarshal([]byte(`{"fileName":"a.txt","fileSize":0,"eventType":"deleted"}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if isZeroByte(payload) {
+		t.Error("a delete, which has no size, counted as a zero-byte file")
+	}
+}
+
+const reportKeyRegex = `^reports/(?P<year>\d{4})/(?P<name>.+)$`
+
+func TestDispatchKeyRegexCaptures(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.KeyRegex = regexp.MustCompile(reportKeyRegex)
+	cfg.MessageTemplate, _ = parseMessageTemplate("{{.Match.name}} for {{.Match.year}}")
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "reports/2024/q1.pdf", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.received()
+	if len(requests) != 1 {
+		t.Fatalf("matched key sent %d requests, want 1", len(requests))
+	}
+	if got, want := decodeDiscordMessage(t, requests[0].Body).Embeds[0].Description, "q1.pdf for 2024"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+}
+
+func TestDispatchSkipsKeyRegexMismatch(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.KeyRegex = regexp.MustCompile(reportKeyRegex)
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelInfo)
+
+	for _, name := range []string{"uploads/2024/q1.pdf", "reports/latest/q1.pdf"} {
+		if err := d.Dispatch(context.Background(), FilePayload{FileName: name, FileURL: "https://example.com/a"}); err != nil {
+			t.Fatalf("skipped file returned %v, want success", err)
+		}
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("unmatched keys sent %d requests, want none", n)
+	}
+	if !strings.Contains(logs.String(), `"filter":"key-regex"`) {
+		t.Errorf("logs = %s, want the skip logged", logs)
+	}
+}
+
+func TestKeyRegexFromConfig(t *testing.T) {
+	message, _, _ := renderLocalized(t, map[string]string{
+		"KEY_REGEX":        `^(?P<base>[a-z]+)(?:_(?P<version>v\d+))?\.(?P<ext>pdf)$`,
+		"MESSAGE_TEMPLATE": "{{.Match.base}}|{{.Match.ext}}|{{.Match.version}}",
+	})
+	if message != "rechnung|pdf|" {
+		t.Errorf("message = %q, want the unmatched optional group empty", message)
+	}
+
+	if got := (FilePayload{FileName: "reports/2024/q1.pdf"}).Match(); len(got) != 0 {
+		t.Errorf("Match without KEY_REGEX = %v, want empty", got)
+	}
+
+	setenvConfig(t, map[string]string{"KEY_REGEX": "(?P<year>"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted an invalid KEY_REGEX")
+	}
+}
+
+func TestDispatchKeyRegexCapturesWithEscaping(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.EscapeMarkdown = true
+	cfg.KeyRegex = regexp.MustCompile(`^reports/(?P<year>\d{4})_(?P<name>.+)$`)
+	cfg.MessageTemplate, _ = parseMessageTemplate("Y={{.Match.year}} N={{.Match.name}}")
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "reports/2024_q3*final*.pdf", FileURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.received()
+	if len(requests) != 1 {
+		t.Fatalf("matched key sent %d requests, want 1", len(requests))
+	}
+	// The key is matched unescaped and the capture escaped like FileName
+	if got, want := decodeDiscordMessage(t, requests[0].Body).Embeds[0].Description, `Y=2024 N=q3\*final\*.pdf`; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+}
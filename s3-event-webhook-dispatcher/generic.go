@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// buildGenericMessage renders BODY_TEMPLATE as the complete request body,
+// optionally checking that the output is valid JSON
+func buildGenericMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	if cfg.BodyTemplate == nil {
+		return nil, fmt.Errorf("BODY_TEMPLATE must be set when PLATFORM is %q", platformGeneric)
+	}
+
+	body, err := renderTemplate(cfg, cfg.BodyTemplate, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.ValidateJSONBody && !json.Valid([]byte(body)) {
+		return nil, fmt.Errorf("BODY_TEMPLATE produced invalid JSON for %q", payload.FileName)
+	}
+	return []byte(body), nil
+}
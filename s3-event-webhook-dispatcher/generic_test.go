@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuildGenericMessage(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"PLATFORM":           platformGeneric,
+		"BODY_TEMPLATE":      `{"name": {{json .FileName}}, "bucket": "{{.Bucket}}"}`,
+		"VALIDATE_JSON_BODY": "true",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := buildMessage(cfg, FilePayload{FileName: `a"b.txt`, Bucket: "invoices"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name": "a\"b.txt", "bucket": "invoices"}`; string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestBuildGenericMessageInvalidJSON(t *testing.T) {
+	tmpl, err := parseTemplate("BODY_TEMPLATE", `{"name": "{{.FileName}}"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Platform: platformGeneric, BodyTemplate: tmpl}
+	payload := FilePayload{FileName: "a.txt"}
+
+	// Without VALIDATE_JSON_BODY the output is sent as rendered
+	if body, err := buildMessage(cfg, payload); err != nil || string(body) != `{"name": "a.txt"` {
+		t.Errorf("buildMessage = %s, %v", body, err)
+	}
+
+	cfg.ValidateJSONBody = true
+	if _, err := buildMessage(cfg, payload); err == nil {
+		t.Error("buildMessage accepted a body that isn't valid JSON")
+	}
+}
+
+func TestBuildGenericMessageRequiresTemplate(t *testing.T) {
+	if _, err := buildMessage(Config{Platform: platformGeneric}, FilePayload{FileName: "a.txt"}); err == nil {
+		t.Error("buildMessage succeeded without BODY_TEMPLATE")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+)
+
+// googleChatCardID identifies the card within a Google Chat message
+const googleChatCardID = "s3-file-notification"
+
+// GoogleChatHeader represents the title area of a Google Chat card
+type GoogleChatHeader struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+}
+
+// GoogleChatDecoratedText represents a labeled line of text in a card section
+type GoogleChatDecoratedText struct {
+	TopLabel string `json:"topLabel"`
+	Text     string `json:"text"`
+}
+
+// GoogleChatOpenLink represents the URL a button opens
+type GoogleChatOpenLink struct {
+	URL string `json:"url"`
+}
+
+// GoogleChatOnClick represents the action taken when a button is clicked
+type GoogleChatOnClick struct {
+	OpenLink GoogleChatOpenLink `json:"openLink"`
+}
+
+// GoogleChatButton represents a clickable button in a button list
+type GoogleChatButton struct {
+	Text    string            `json:"text"`
+	OnClick GoogleChatOnClick `json:"onClick"`
+}
+
+// GoogleChatButtonList represents a row of buttons in a card section
+type GoogleChatButtonList struct {
+	Buttons []GoogleChatButton `json:"buttons"`
+}
+
+// GoogleChatWidget represents one element of a card section; exactly one field is set
+type GoogleChatWidget struct {
+	DecoratedText *GoogleChatDecoratedText `json:"decoratedText,omitempty"`
+	ButtonList    *GoogleChatButtonList    `json:"buttonList,omitempty"`
+}
+
+// GoogleChatSection represents a group of widgets in a card
+type GoogleChatSection struct {
+	Widgets []GoogleChatWidget `json:"widgets"`
+}
+
+// GoogleChatCard represents a Google Chat card
+type GoogleChatCard struct {
+	Header   GoogleChatHeader    `json:"header"`
+	Sections []GoogleChatSection `json:"sections"`
+}
+
+// GoogleChatCardV2 wraps a card with the ID Google Chat requires for cardsV2
+type GoogleChatCardV2 struct {
+	CardID string         `json:"cardId"`
+	Card   GoogleChatCard `json:"card"`
+}
+
+// GoogleChatMessage represents the payload sent to a Google Chat incoming webhook
+type GoogleChatMessage struct {
+	Text    string             `json:"text,omitempty"`
+	CardsV2 []GoogleChatCardV2 `json:"cardsV2,omitempty"`
+}
+
+// buildGoogleChatMessage formats the payload as a Google Chat cardsV2 message
+// with the rendered message as the card's subtitle, or as plain text when
+// GOOGLECHAT_SIMPLE is set
+func buildGoogleChatMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	title, err := renderTitle(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var message GoogleChatMessage
+	if cfg.GoogleChatSimple {
+		text, err := renderMessage(cfg, escapePayloadText(payload, slackEscaper))
+		if err != nil {
+			return nil, err
+		}
+		message.Text = googleChatText(title, text)
+	} else {
+		subtitle, err := renderMessage(cfg, payload)
+		if err != nil {
+			return nil, err
+		}
+		message.CardsV2 = []GoogleChatCardV2{
+			{CardID: googleChatCardID, Card: googleChatCard(title, subtitle, payload)},
+		}
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Google Chat message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
+
+// googleChatCard builds the card: a header, the file details and a download button
+func googleChatCard(title, subtitle string, payload FilePayload) GoogleChatCard {
+	// The card header needs a title, so the subtitle moves up when the title is disabled
+	header := GoogleChatHeader{Title: title, Subtitle: subtitle}
+	if title == "" {
+		header = GoogleChatHeader{Title: subtitle}
+	}
+	if header.Title == "" {
+		header = GoogleChatHeader{Title: eventSummary(payload)}
+	}
+
+	// Card text is rendered as limited HTML
+	widgets := []GoogleChatWidget{
+		{DecoratedText: &GoogleChatDecoratedText{TopLabel: "File Name", Text: html.EscapeString(payload.FileName)}},
+	}
+	if payload.ExpirationTime != "" {
+		widgets = append(widgets, GoogleChatWidget{
+			DecoratedText: &GoogleChatDecoratedText{TopLabel: "Link Expires", Text: "After " + html.EscapeString(payload.ExpirationTime)},
+		})
+	}
+	if payload.FileURL != "" {
+		widgets = append(widgets, GoogleChatWidget{
+			ButtonList: &GoogleChatButtonList{Buttons: []GoogleChatButton{
+				{Text: "Download File", OnClick: GoogleChatOnClick{OpenLink: GoogleChatOpenLink{URL: payload.FileURL}}},
+			}},
+		})
+	}
+
+	return GoogleChatCard{
+		Header:   header,
+		Sections: []GoogleChatSection{{Widgets: widgets}},
+	}
+}
+
+// googleChatText builds the plain text form, the rendered message under the
+// title in bold. It uses *bold* and <url|text> links and escapes the same
+// characters as Slack.
+func googleChatText(title, text string) string {
+	if title == "" {
+		return text
+	}
+	return fmt.Sprintf("*%s*\n%s", slackEscaper.Replace(title), text)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// googleChatTestConfig returns a Google Chat configuration with the platform's default template
+func googleChatTestConfig(t *testing.T, simple bool) Config {
+	t.Helper()
+	cfg := testConfig(t, "https://chat.googleapis.com/v1/spaces/AAA/messages?key=k&token=t")
+	cfg.Platform = platformGoogleChat
+	cfg.GoogleChatSimple = simple
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+	return cfg
+}
+
+func TestBuildGoogleChatCard(t *testing.T) {
+	cfg := googleChatTestConfig(t, false)
+	presigned := "https://example-bucket.s3.amazonaws.com/a%3Cb%3E.txt?X-Amz-Signature=abc"
+
+	body, err := buildMessage(cfg, FilePayload{FileName: "a<b>.txt", FileURL: presigned, ExpirationTime: "1 day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message GoogleChatMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "" || len(message.CardsV2) != 1 {
+		t.Fatalf("message = %s, want a single card and no text", body)
+	}
+	card := message.CardsV2[0]
+	if card.CardID != googleChatCardID || card.Card.Header.Title != messageTitle || card.Card.Header.Subtitle == "" {
+		t.Errorf("card = %+v", card)
+	}
+
+	widgets := card.Card.Sections[0].Widgets
+	if len(widgets) != 3 {
+		t.Fatalf("got %d widgets, want file name, expiry and button", len(widgets))
+	}
+	if text := widgets[0].DecoratedText; text == nil || text.TopLabel != "File Name" || text.Text != "a&lt;b&gt;.txt" {
+		t.Errorf("file name widget = %+v, want the escaped file name", text)
+	}
+	if text := widgets[1].DecoratedText; text == nil || text.Text != "After 1 day" {
+		t.Errorf("expiry widget = %+v", text)
+	}
+	if list := widgets[2].ButtonList; list == nil || list.Buttons[0].OnClick.OpenLink.URL != presigned {
+		t.Errorf("button widget = %+v, want a link to the presigned URL", list)
+	}
+}
+
+func TestBuildGoogleChatSimple(t *testing.T) {
+	cfg := googleChatTestConfig(t, true)
+
+	body, err := buildMessage(cfg, FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf", ExpirationTime: "1 day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message map[string]interface{}
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := message["cardsV2"]; ok {
+		t.Errorf("simple message %s has cards", body)
+	}
+	text, _ := message["text"].(string)
+	if !strings.HasPrefix(text, "*"+messageTitle+"*\n") || !strings.Contains(text, "report.pdf") {
+		t.Errorf("text = %q, want the bold title and the message", text)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// jsonContentType is the Content-Type of every message body except file uploads
+const jsonContentType = "application/json"
+
+// version is the release named in the default User-Agent, set at build time
+// with -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
+// defaultUserAgent identifies the dispatcher to webhook receivers when USER_AGENT is unset
+func defaultUserAgent() string {
+	return "s3-event-webhook-dispatcher/" + version
+}
+
+// sensitiveHeaderWords mark a header whose value must never be logged
+var sensitiveHeaderWords = []string{"authorization", "cookie", "token", "secret", "key", "signature", "password"}
+
+// parseCustomHeaders decodes the CUSTOM_HEADERS JSON object of header name to value
+func parseCustomHeaders(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+		return nil, fmt.Errorf("invalid CUSTOM_HEADERS: must be a JSON object of string values: %w", err)
+	}
+	for name := range headers {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid CUSTOM_HEADERS: header names must not be empty")
+		}
+	}
+	return headers, nil
+}
+
+// applyHeaders sets the body's Content-Type and the User-Agent followed by
+// any custom headers, so those are only replaced when CUSTOM_HEADERS names
+// them explicitly, and then the dispatch's X-Request-Id and X-Correlation-Id.
+// Multipart bodies always keep theirs, since it carries the boundary, and are
+// never compressed. Configured credentials are set last so CUSTOM_HEADERS
+// can't clobber them.
+func applyHeaders(req *http.Request, cfg Config, contentType string) {
+	req.Header.Set("Content-Type", contentType)
+	if cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", cfg.UserAgent)
+	}
+	for name, value := range cfg.CustomHeaders {
+		req.Header.Set(name, value)
+	}
+	setDispatchIDHeaders(req)
+	if strings.HasPrefix(contentType, "multipart/") {
+		req.Header.Set("Content-Type", contentType)
+	} else if cfg.CompressBody == compressGzip {
+		req.Header.Set("Content-Encoding", compressGzip)
+	}
+
+	switch {
+	case cfg.OpsgenieAPIKey != "" && cfg.Platform == platformOpsgenie:
+		req.Header.Set("Authorization", "GenieKey "+cfg.OpsgenieAPIKey)
+	case cfg.AuthBearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+cfg.AuthBearerToken)
+	case cfg.AuthBasicUser != "":
+		req.SetBasicAuth(cfg.AuthBasicUser, cfg.AuthBasicPass)
+	}
+}
+
+// isSensitiveHeader reports whether a header's value could carry a credential
+func isSensitiveHeader(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaders renders headers for debug logging with credential values masked
+func redactHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ",")
+		if isSensitiveHeader(name) {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
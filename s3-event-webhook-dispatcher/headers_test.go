@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseCustomHeaders(t *testing.T) {
+	headers, err := parseCustomHeaders(`{"X-Api-Key": "abc", "X-Tenant-Id": "acme"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers["X-Api-Key"] != "abc" || headers["X-Tenant-Id"] != "acme" {
+		t.Errorf("headers = %v", headers)
+	}
+
+	if headers, err := parseCustomHeaders("  "); err != nil || headers != nil {
+		t.Errorf("empty CUSTOM_HEADERS = %v, %v", headers, err)
+	}
+	for _, raw := range []string{`["X-Api-Key"]`, `{"X-Retries": 3}`, `{" ": "x"}`, `{not json`} {
+		if _, err := parseCustomHeaders(raw); err == nil {
+			t.Errorf("parseCustomHeaders(%s): want an error", raw)
+		}
+	}
+}
+
+func TestSendAppliesCustomHeaders(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.CustomHeaders = map[string]string{"X-Api-Key": "abc", "X-Tenant-Id": "acme", "Authorization": "Token xyz"}
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Send(context.Background(), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	header := srv.received()[0].Header
+	for name, want := range map[string]string{
+		"X-Api-Key":     "abc",
+		"X-Tenant-Id":   "acme",
+		"Authorization": "Token xyz",
+		"Content-Type":  jsonContentType,
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestApplyHeadersContentTypeOverride(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	applyHeaders(req, Config{CustomHeaders: map[string]string{"Content-Type": "application/vnd.acme+json"}}, jsonContentType)
+	if got := req.Header.Get("Content-Type"); got != "application/vnd.acme+json" {
+		t.Errorf("Content-Type = %q, want the explicit override", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	applyHeaders(req, Config{CustomHeaders: map[string]string{"Content-Type": "text/plain"}}, "multipart/form-data; boundary=x")
+	if got := req.Header.Get("Content-Type"); got != "multipart/form-data; boundary=x" {
+		t.Errorf("multipart Content-Type = %q, want the boundary kept", got)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer t0ken")
+	header.Set("X-Api-Key", "abc")
+	header.Set("X-Tenant-Id", "acme")
+
+	got := redactHeaders(header)
+	if strings.Contains(got, "t0ken") || strings.Contains(got, "abc") {
+		t.Errorf("redactHeaders leaked a credential: %s", got)
+	}
+	if !strings.Contains(got, "X-Tenant-Id: acme") || !strings.Contains(got, "Authorization: [REDACTED]") {
+		t.Errorf("redactHeaders = %s", got)
+	}
+}
+
+func TestApplyHeadersBearerToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	applyHeaders(req, Config{AuthBearerToken: "t0ken", CustomHeaders: map[string]string{"Authorization": "clobbered"}}, jsonContentType)
+	if got := req.Header.Get("Authorization"); got != "Bearer t0ken" {
+		t.Errorf("Authorization = %q, want the bearer token over CUSTOM_HEADERS", got)
+	}
+}
+
+func TestApplyHeadersBasicAuth(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	applyHeaders(req, Config{AuthBasicUser: "Aladdin", AuthBasicPass: "open sesame", CustomHeaders: map[string]string{"Authorization": "clobbered"}}, jsonContentType)
+	// The example from RFC 7617
+	if got := req.Header.Get("Authorization"); got != "Basic QWxhZGRpbjpvcGVuIHNlc2FtZQ==" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestDispatchAuthNotLogged(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t, http.StatusUnauthorized)
+	cfg := testConfig(t, srv.URL)
+	cfg.AuthBasicUser = "dispatcher"
+	cfg.AuthBasicPass = "s3cr3t-pass"
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelDebug)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}); err == nil {
+		t.Fatal("want an error for the 401 response")
+	}
+	if got := srv.received()[0].Header.Get("Authorization"); !strings.HasPrefix(got, "Basic ") {
+		t.Errorf("Authorization = %q, want Basic auth sent", got)
+	}
+	encoded := strings.TrimPrefix(srv.received()[0].Header.Get("Authorization"), "Basic ")
+	for _, secret := range []string{"s3cr3t-pass", encoded} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("logs contain the credential %q", secret)
+		}
+	}
+}
+
+func TestSendUserAgent(t *testing.T) {
+	userAgent := func(env map[string]string) string {
+		t.Helper()
+		srv := newWebhookServer(t)
+		env["WEBHOOK_URL"] = srv.URL
+		env["ALLOW_PRIVATE_TARGETS"] = "true"
+		setenvConfig(t, env)
+		cfg, err := loadConfig(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := newTestDispatcher(cfg, srv).Send(context.Background(), []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+		return srv.received()[0].Header.Get("User-Agent")
+	}
+
+	old := version
+	version = "1.2.3"
+	t.Cleanup(func() { version = old })
+
+	if got := userAgent(map[string]string{}); got != "s3-event-webhook-dispatcher/1.2.3" {
+		t.Errorf("default User-Agent = %q, want s3-event-webhook-dispatcher/1.2.3", got)
+	}
+	if got := userAgent(map[string]string{"USER_AGENT": " acme-uploads/7 "}); got != "acme-uploads/7" {
+		t.Errorf("User-Agent with USER_AGENT set = %q, want acme-uploads/7", got)
+	}
+}
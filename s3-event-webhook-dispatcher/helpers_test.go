@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slogQuiet is a log level above every record the dispatcher emits
+const slogQuiet = slog.LevelError + 4
+
+// Records logged through slog.Default by code without a Dispatcher stay out of the test output
+func init() {
+	slog.SetDefault(newLogger(slogQuiet))
+}
+
+// resetTemplates forgets the templates compiled by loadTemplates, so a test
+// can set template variables, and forgets them again once it finishes
+func resetTemplates(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		coldStartTemplates.once = sync.Once{}
+		coldStartTemplates.templates = envTemplates{}
+		coldStartTemplates.err = nil
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// setenvConfig sets the environment for a loadConfig call with a valid
+// Discord webhook URL, and resets the compiled templates
+func setenvConfig(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("WEBHOOK_URL", "https://discord.com/api/webhooks/1/token")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	resetTemplates(t)
+}
+
+// testConfig returns a Discord configuration that delivers to webhookURL with
+// the defaults loadConfig would apply
+func testConfig(t *testing.T, webhookURL string) Config {
+	t.Helper()
+	tmpl, err := parseMessageTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := parseTitleTemplate("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Config{
+		WebhookURLs:      []string{webhookURL},
+		Platform:         platformDiscord,
+		MessageTemplate:  tmpl,
+		TitleTemplate:    title,
+		EmbedColor:       defaultEmbedColor,
+		FooterText:       footerText,
+		HTTPMethod:       http.MethodPost,
+		ContentType:      jsonContentType,
+		RequestTimeout:   5 * time.Second,
+		RetryBaseDelay:   time.Millisecond,
+		AllowNoExtension: true,
+		MaxConcurrency:   1,
+		UseEmbed:         true,
+		EmbedTimestamp:   true,
+		RequiredFields:   defaultRequiredFields,
+	}
+}
+
+// recordedRequest is one request received by a webhookServer
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// webhookServer is a TLS test webhook that records every request and answers
+// with the statuses in responses, repeating the last one
+type webhookServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requests  []recordedRequest
+	responses []int
+}
+
+// newWebhookServer starts a webhookServer that is closed when the test ends
+func newWebhookServer(t *testing.T, responses ...int) *webhookServer {
+	t.Helper()
+	if len(responses) == 0 {
+		responses = []int{http.StatusNoContent}
+	}
+	s := &webhookServer{responses: responses}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, recordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	status := s.responses[min(len(s.requests), len(s.responses))-1]
+	s.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+// received returns a copy of the requests recorded so far
+func (s *webhookServer) received() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+// newTestDispatcher returns a dispatcher for cfg whose client trusts srv
+func newTestDispatcher(cfg Config, srv *webhookServer) *Dispatcher {
+	d := NewDispatcher(cfg)
+	d.Client = srv.Client()
+	d.Logger = newLogger(slogQuiet)
+	return d
+}
+
+// jsonStrings returns every string value in a JSON document, at any depth
+func jsonStrings(t *testing.T, body []byte) []string {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid JSON %s: %v", body, err)
+	}
+
+	var values []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(doc)
+	return values
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// httpRequest is the part of an HTTP invocation the dispatcher reads, common
+// to Function URL and API Gateway proxy requests
+type httpRequest struct {
+	Method          string
+	Path            string
+	Body            string
+	IsBase64Encoded bool
+}
+
+// httpInvokeResult is the JSON body returned to an HTTP invocation
+type httpInvokeResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// parseHTTPRequest reports whether the raw invocation payload is a Function URL
+// (or API Gateway HTTP API) request or an API Gateway REST proxy request, and
+// returns its method, path and body
+func parseHTTPRequest(raw json.RawMessage) (httpRequest, bool) {
+	var urlRequest events.LambdaFunctionURLRequest
+	if err := json.Unmarshal(raw, &urlRequest); err == nil && urlRequest.RequestContext.HTTP.Method != "" {
+		return httpRequest{
+			Method:          urlRequest.RequestContext.HTTP.Method,
+			Path:            urlRequest.RawPath,
+			Body:            urlRequest.Body,
+			IsBase64Encoded: urlRequest.IsBase64Encoded,
+		}, true
+	}
+
+	var proxyRequest events.APIGatewayProxyRequest
+	if err := json.Unmarshal(raw, &proxyRequest); err == nil && proxyRequest.HTTPMethod != "" {
+		return httpRequest{
+			Method:          proxyRequest.HTTPMethod,
+			Path:            proxyRequest.Path,
+			Body:            proxyRequest.Body,
+			IsBase64Encoded: proxyRequest.IsBase64Encoded,
+		}, true
+	}
+	return httpRequest{}, false
+}
+
+// handleHTTP dispatches the body of an HTTP invocation, either an EventBridge
+// event or a bare file payload, so the dispatcher can be exercised with curl.
+// A GET answers as a health check without sending anything; a POST is only
+// dispatched with HTTP_INVOKE_ENABLED, so an exposed URL can't post messages by
+// default. Failures are reported in the response rather than as an invocation
+// error, so the caller gets a JSON status instead of a generic 502.
+func (d *Dispatcher) handleHTTP(ctx context.Context, req httpRequest) events.LambdaFunctionURLResponse {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return httpInvokeResponse(http.StatusOK, nil)
+	case http.MethodPost:
+		if !d.Config.HTTPInvokeEnabled {
+			return httpInvokeResponse(http.StatusForbidden, errors.New("dispatch over HTTP is disabled; set HTTP_INVOKE_ENABLED=true to POST events"))
+		}
+	default:
+		return httpInvokeResponse(http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed; POST an event or GET for a health check", req.Method))
+	}
+
+	payloads, err := httpPayloads(d.Config, req)
+	if err == nil {
+		err = d.dispatchEach(ctx, payloads)
+	}
+	return httpInvokeResponse(httpStatusFor(err), err)
+}
+
+// httpPayloads decodes the file payloads from an HTTP invocation body, which
+// holds an EventBridge event or the detail of one
+func httpPayloads(cfg Config, req httpRequest) ([]FilePayload, error) {
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: request body: %w", ErrPayloadParse, err)
+		}
+		body = decoded
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: request body is empty", ErrPayloadParse)
+	}
+
+	var event events.CloudWatchEvent
+	if err := json.Unmarshal(body, &event); err == nil && len(event.Detail) > 0 {
+		return payloadsFromEvent(cfg, event)
+	}
+	return parseDetail(cfg, body)
+}
+
+// httpStatusFor maps a dispatch result to the status code of the HTTP response
+func httpStatusFor(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrPayloadParse), errors.Is(err, ErrMissingFields):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// httpInvokeResponse builds the JSON response for an HTTP invocation
+func httpInvokeResponse(status int, err error) events.LambdaFunctionURLResponse {
+	result := httpInvokeResult{Status: "ok"}
+	if err != nil {
+		result = httpInvokeResult{Status: "error", Error: err.Error()}
+	}
+	body, _ := json.Marshal(result)
+	return events.LambdaFunctionURLResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": jsonContentType},
+		Body:       string(body),
+	}
+}
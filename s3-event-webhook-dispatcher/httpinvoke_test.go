@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const httpInvokeDetail = `{"fileName":"report.pdf","fileUrl":"https://example.com/report.pdf","bucket":"example-bucket","expirationTime":"24 hours"}`
+
+// functionURLRequest builds a raw Function URL invocation
+func functionURLRequest(t *testing.T, method, path, body string) json.RawMessage {
+	t.Helper()
+	var req events.LambdaFunctionURLRequest
+	req.RequestContext.HTTP.Method = method
+	req.RawPath = path
+	req.Body = body
+	raw, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// decodeHTTPResult decodes the JSON body of an HTTP invocation response
+func decodeHTTPResult(t *testing.T, resp events.LambdaFunctionURLResponse) httpInvokeResult {
+	t.Helper()
+	var result httpInvokeResult
+	if err := json.Unmarshal([]byte(resp.Body), &result); err != nil {
+		t.Fatalf("response body %q: %v", resp.Body, err)
+	}
+	return result
+}
+
+func TestParseHTTPRequest(t *testing.T) {
+	req, ok := parseHTTPRequest(functionURLRequest(t, http.MethodPost, "/", httpInvokeDetail))
+	if !ok || req.Method != http.MethodPost || req.Body != httpInvokeDetail {
+		t.Errorf("Function URL request = %+v, %v", req, ok)
+	}
+
+	proxy, _ := json.Marshal(events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/health"})
+	req, ok = parseHTTPRequest(proxy)
+	if !ok || req.Method != http.MethodGet || req.Path != "/health" {
+		t.Errorf("API Gateway request = %+v, %v", req, ok)
+	}
+
+	if _, ok := parseHTTPRequest(json.RawMessage(`{"detail-type":"File Uploaded","detail":{}}`)); ok {
+		t.Error("EventBridge event detected as an HTTP request")
+	}
+}
+
+func TestHandleHTTPDispatchesWhenEnabled(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.HTTPInvokeEnabled = true
+	d := newTestDispatcher(cfg, srv)
+
+	event := `{"detail-type":"File Uploaded","source":"s3-link-generator","detail":` + httpInvokeDetail + `}`
+	for _, body := range []string{event, httpInvokeDetail} {
+		resp := d.handleHTTP(context.Background(), httpRequest{Method: http.MethodPost, Body: body})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, body %s", resp.StatusCode, resp.Body)
+		}
+		if result := decodeHTTPResult(t, resp); result.Status != "ok" {
+			t.Errorf("result = %+v", result)
+		}
+	}
+	if got := len(srv.received()); got != 2 {
+		t.Errorf("webhook received %d requests, want 2", got)
+	}
+}
+
+func TestHandleHTTPRefusesPostByDefault(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	resp := d.handleHTTP(context.Background(), httpRequest{Method: http.MethodPost, Body: httpInvokeDetail})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if result := decodeHTTPResult(t, resp); result.Status != "error" || result.Error == "" {
+		t.Errorf("result = %+v", result)
+	}
+	if got := len(srv.received()); got != 0 {
+		t.Errorf("webhook received %d requests, want none", got)
+	}
+}
+
+func TestHandleHTTPHealthCheck(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		resp := d.handleHTTP(context.Background(), httpRequest{Method: method})
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s status = %d", method, resp.StatusCode)
+		}
+	}
+	resp := d.handleHTTP(context.Background(), httpRequest{Method: http.MethodDelete})
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+	if got := len(srv.received()); got != 0 {
+		t.Errorf("webhook received %d requests, want none", got)
+	}
+}
+
+func TestHandleHTTPBadRequest(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.HTTPInvokeEnabled = true
+	d := newTestDispatcher(cfg, srv)
+
+	resp := d.handleHTTP(context.Background(), httpRequest{Method: http.MethodPost})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("empty body status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestHandlerHTTPInvokeFlag(t *testing.T) {
+	setenvConfig(t, nil)
+
+	resp, err := Handler(context.Background(), functionURLRequest(t, http.MethodGet, "/", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := resp.(events.LambdaFunctionURLResponse).StatusCode; status != http.StatusOK {
+		t.Errorf("health check status = %d", status)
+	}
+
+	resp, err = Handler(context.Background(), functionURLRequest(t, http.MethodPost, "/", httpInvokeDetail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := resp.(events.LambdaFunctionURLResponse).StatusCode; status != http.StatusForbidden {
+		t.Errorf("POST status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	resp, err = Handler(context.Background(), functionURLRequest(t, http.MethodPost, selfTestPath, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := resp.(events.LambdaFunctionURLResponse); result.StatusCode != http.StatusInternalServerError {
+		t.Errorf("POST %s status = %d, body %s", selfTestPath, result.StatusCode, result.Body)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoDBAPI is the subset of the DynamoDB client used by the idempotency store
+type dynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// newDynamoDBClient creates the DynamoDB client for the idempotency table; tests replace it with a stub
+var newDynamoDBClient = func(ctx context.Context) (dynamoDBAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dynamodb.NewFromConfig(awsCfg), nil
+}
+
+// claimIdempotencyKey records key in the idempotency table with a conditional
+// put and reports whether it was already claimed. Items past their expiresAt
+// are treated as absent, since DynamoDB's TTL deletion can lag by hours.
+func claimIdempotencyKey(ctx context.Context, cfg Config, key string, now time.Time) (bool, error) {
+	client, err := newDynamoDBClient(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to create DynamoDB client: %w", err)
+	}
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.IdempotencyTable),
+		Item: map[string]types.AttributeValue{
+			"id":        &types.AttributeValueMemberS{Value: key},
+			"expiresAt": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(cfg.IdempotencyTTL).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(id) OR expiresAt < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to claim event in idempotency table %s: %w", cfg.IdempotencyTable, err)
+	}
+	return false, nil
+}
+
+// releaseIdempotencyKey deletes key from the idempotency table so a failed
+// dispatch can be retried
+func releaseIdempotencyKey(ctx context.Context, cfg Config, key string) error {
+	client, err := newDynamoDBClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create DynamoDB client: %w", err)
+	}
+
+	_, err = client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(cfg.IdempotencyTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release event in idempotency table %s: %w", cfg.IdempotencyTable, err)
+	}
+	return nil
+}
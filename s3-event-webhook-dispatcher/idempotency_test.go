@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeDynamoDB is an in-memory idempotency table that enforces the
+// attribute_not_exists condition of claimIdempotencyKey
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]bool
+	puts  int
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.puts++
+	id := params.Item["id"].(*types.AttributeValueMemberS).Value
+	if f.items[id] {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	}
+	f.items[id] = true
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.items, params.Key["id"].(*types.AttributeValueMemberS).Value)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// stubDynamoDB makes newDynamoDBClient return table until the test ends
+func stubDynamoDB(t *testing.T, table *fakeDynamoDB) {
+	t.Helper()
+	original := newDynamoDBClient
+	newDynamoDBClient = func(ctx context.Context) (dynamoDBAPI, error) { return table, nil }
+	t.Cleanup(func() { newDynamoDBClient = original })
+}
+
+func TestClaimIdempotencyKeyMissThenHit(t *testing.T) {
+	table := &fakeDynamoDB{items: map[string]bool{}}
+	stubDynamoDB(t, table)
+	cfg := Config{IdempotencyTable: "dispatches", IdempotencyTTL: time.Hour}
+
+	claimed, err := claimIdempotencyKey(context.Background(), cfg, "event-1", time.Now())
+	if err != nil || claimed {
+		t.Fatalf("first claim = %v, %v; want a miss", claimed, err)
+	}
+	claimed, err = claimIdempotencyKey(context.Background(), cfg, "event-1", time.Now())
+	if err != nil || !claimed {
+		t.Fatalf("second claim = %v, %v; want a hit", claimed, err)
+	}
+}
+
+func TestDispatchSkipsClaimedEvent(t *testing.T) {
+	table := &fakeDynamoDB{items: map[string]bool{}}
+	stubDynamoDB(t, table)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.IdempotencyTable = "dispatches"
+	cfg.IdempotencyTTL = time.Hour
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf", EventID: "evt-42"}
+	for i := 0; i < 2; i++ {
+		if err := d.Dispatch(context.Background(), payload); err != nil {
+			t.Fatalf("dispatch %d: %v", i+1, err)
+		}
+	}
+	if got := len(srv.received()); got != 1 {
+		t.Errorf("webhook received %d requests, want 1", got)
+	}
+	if table.puts != 2 {
+		t.Errorf("idempotency table got %d puts, want 2", table.puts)
+	}
+}
+
+func TestDispatchReleasesClaimOnFailure(t *testing.T) {
+	table := &fakeDynamoDB{items: map[string]bool{}}
+	stubDynamoDB(t, table)
+	srv := newWebhookServer(t, 400)
+	cfg := testConfig(t, srv.URL)
+	cfg.IdempotencyTable = "dispatches"
+	cfg.IdempotencyTTL = time.Hour
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/report.pdf", EventID: "evt-43"}
+	if err := d.Dispatch(context.Background(), payload); err == nil {
+		t.Fatal("dispatch to a failing webhook: want an error")
+	}
+	if len(table.items) != 0 {
+		t.Errorf("failed dispatch left its claim: %v", table.items)
+	}
+}
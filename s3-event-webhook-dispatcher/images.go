@@ -0,0 +1,21 @@
+package main
+
+// defaultImageExtensions are the image types Discord can render in an embed
+const defaultImageExtensions = "png,jpg,jpeg,gif,webp"
+
+// EmbedMedia represents an image shown in a Discord embed
+type EmbedMedia struct {
+	URL string `json:"url"`
+}
+
+// embedImageURL returns the link an embed can show as an image of the file, or
+// "" when the file isn't one of IMAGE_EXTENSIONS or has no http(s) link
+func embedImageURL(cfg Config, payload FilePayload) string {
+	if payload.isDeleted() || !isHTTPURL(payload.FileURL) {
+		return ""
+	}
+	if !cfg.ImageExtensions[fileExtension(payload.FileName)] {
+		return ""
+	}
+	return payload.FileURL
+}
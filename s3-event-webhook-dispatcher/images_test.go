@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// imageTestConfig returns a Discord configuration with the default IMAGE_EXTENSIONS
+func imageTestConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.UseEmbed = true
+	cfg.ImageExtensions = parseExtensionSet(defaultImageExtensions)
+	return cfg
+}
+
+// builtEmbed builds the Discord message for a file and returns its embed
+func builtEmbed(t *testing.T, cfg Config, payload FilePayload) DiscordEmbed {
+	t.Helper()
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeDiscordMessage(t, body).Embeds[0]
+}
+
+func TestBuildDiscordMessageImageThumbnail(t *testing.T) {
+	cfg := imageTestConfig(t)
+	cfg.ShowImageThumbnail = true
+
+	png := builtEmbed(t, cfg, FilePayload{FileName: "photos/cat.PNG", FileURL: "https://example.com/cat.png?X-Amz-Signature=abc"})
+	if png.Thumbnail == nil || png.Thumbnail.URL != "https://example.com/cat.png?X-Amz-Signature=abc" {
+		t.Errorf("thumbnail = %+v, want the presigned link of the .png", png.Thumbnail)
+	}
+	if png.Image != nil {
+		t.Errorf("image = %+v, want no large preview without SHOW_IMAGE_PREVIEW", png.Image)
+	}
+
+	if pdf := builtEmbed(t, cfg, FilePayload{FileName: "reports/q1.pdf", FileURL: "https://example.com/q1.pdf"}); pdf.Thumbnail != nil {
+		t.Errorf("thumbnail = %+v, want none for a .pdf", pdf.Thumbnail)
+	}
+
+	cfg.ShowImageThumbnail = false
+	if off := builtEmbed(t, cfg, FilePayload{FileName: "cat.png", FileURL: "https://example.com/cat.png"}); off.Thumbnail != nil {
+		t.Errorf("thumbnail = %+v, want none with SHOW_IMAGE_THUMBNAIL off", off.Thumbnail)
+	}
+}
+
+func TestEmbedImageURL(t *testing.T) {
+	cfg := imageTestConfig(t)
+	for payload, want := range map[FilePayload]string{
+		{FileName: "a.webp", FileURL: "https://example.com/a.webp"}: "https://example.com/a.webp",
+		{FileName: "a.tiff", FileURL: "https://example.com/a.tiff"}: "",
+		{FileName: "a.png"}: "",
+		{FileName: "a.png", FileURL: "s3://bucket/a.png"}:                                      "",
+		{FileName: "a.png", FileURL: "https://example.com/a.png", EventType: eventTypeDeleted}: "",
+	} {
+		if got := embedImageURL(cfg, payload); got != want {
+			t.Errorf("embedImageURL(%s, %q) = %q, want %q", payload.FileName, payload.FileURL, got, want)
+		}
+	}
+}
+
+func TestLoadConfigImageExtensions(t *testing.T) {
+	setenvConfig(t, map[string]string{"SHOW_IMAGE_THUMBNAIL": "true", "IMAGE_EXTENSIONS": "svg, .tiff"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.ShowImageThumbnail || !cfg.ImageExtensions["svg"] || !cfg.ImageExtensions["tiff"] || cfg.ImageExtensions["png"] {
+		t.Errorf("ShowImageThumbnail = %v, ImageExtensions = %v, want only svg and tiff", cfg.ShowImageThumbnail, cfg.ImageExtensions)
+	}
+}
+
+func TestBuildDiscordMessageImagePreview(t *testing.T) {
+	cfg := imageTestConfig(t)
+	cfg.ShowImagePreview = true
+
+	embed := builtEmbed(t, cfg, FilePayload{FileName: "photos/cat.jpg", FileURL: "https://example.com/cat.jpg?X-Amz-Signature=abc"})
+	if embed.Image == nil || embed.Image.URL != "https://example.com/cat.jpg?X-Amz-Signature=abc" {
+		t.Errorf("image = %+v, want the presigned link of the .jpg", embed.Image)
+	}
+	if embed.Thumbnail != nil {
+		t.Errorf("thumbnail = %+v, want none without SHOW_IMAGE_THUMBNAIL", embed.Thumbnail)
+	}
+	if pdf := builtEmbed(t, cfg, FilePayload{FileName: "q1.pdf", FileURL: "https://example.com/q1.pdf"}); pdf.Image != nil {
+		t.Errorf("image = %+v, want none for a .pdf", pdf.Image)
+	}
+
+	// The two options are independent, so both can be on at once
+	cfg.ShowImageThumbnail = true
+	both := builtEmbed(t, cfg, FilePayload{FileName: "cat.gif", FileURL: "https://example.com/cat.gif"})
+	if both.Image == nil || both.Thumbnail == nil {
+		t.Errorf("image = %+v, thumbnail = %+v, want both", both.Image, both.Thumbnail)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseLocale normalizes LOCALE to the suffix of its override variables, e.g.
+// "de" to "DE" and "de-AT" or "de_AT.UTF-8" to "DE_AT". An empty value
+// disables the overrides.
+func parseLocale(raw string) (string, error) {
+	locale := strings.TrimSpace(raw)
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale = strings.ToUpper(strings.ReplaceAll(locale, "-", "_"))
+
+	for _, r := range locale {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return "", fmt.Errorf("invalid LOCALE %q: expected a language code such as de or de-AT", raw)
+		}
+	}
+	return locale, nil
+}
+
+// localeEnv reads name for the locale: name_<LOCALE> when set, then
+// name_<language> for a regional locale such as DE_AT, then name itself. An
+// empty override counts as unset, so it falls back instead of blanking the
+// value. set reports whether the returned variable exists at all.
+func localeEnv(locale, name string) (value string, set bool) {
+	if locale != "" {
+		candidates := []string{locale}
+		if language, _, regional := strings.Cut(locale, "_"); regional {
+			candidates = append(candidates, language)
+		}
+		for _, candidate := range candidates {
+			if value := os.Getenv(name + "_" + candidate); value != "" {
+				return value, true
+			}
+		}
+	}
+	return os.LookupEnv(name)
+}
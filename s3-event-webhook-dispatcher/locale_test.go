@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// renderLocalized loads the configuration for env and renders the message,
+// title and footer for a file
+func renderLocalized(t *testing.T, env map[string]string) (message, title, footer string) {
+	t.Helper()
+	setenvConfig(t, env)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := FilePayload{FileName: "rechnung.pdf", FileURL: "https://example.com/r.pdf"}
+	if message, err = renderMessage(cfg, payload); err != nil {
+		t.Fatal(err)
+	}
+	if title, err = renderTitle(cfg, payload); err != nil {
+		t.Fatal(err)
+	}
+	if footer, err = renderFooter(cfg, payload); err != nil {
+		t.Fatal(err)
+	}
+	return message, title, footer
+}
+
+func TestLocaleSelectsGermanTemplates(t *testing.T) {
+	message, title, footer := renderLocalized(t, map[string]string{
+		"LOCALE":              "de",
+		"MESSAGE_TEMPLATE":    "New file: {{.FileName}}",
+		"MESSAGE_TEMPLATE_DE": "Neue Datei: {{.FileName}}",
+		"TITLE_TEMPLATE_DE":   "Neue Datei hochgeladen",
+		"FOOTER_TEXT_DE":      "S3-Benachrichtigung",
+	})
+	if message != "Neue Datei: rechnung.pdf" || title != "Neue Datei hochgeladen" || footer != "S3-Benachrichtigung" {
+		t.Errorf("got %q, %q, %q, want the German overrides", message, title, footer)
+	}
+}
+
+func TestLocaleFallsBackToDefault(t *testing.T) {
+	// Only the message has an Austrian override; the rest falls back through DE to the base
+	message, title, footer := renderLocalized(t, map[string]string{
+		"LOCALE":                 "de-AT",
+		"MESSAGE_TEMPLATE":       "New file: {{.FileName}}",
+		"MESSAGE_TEMPLATE_DE_AT": "Neue Datei (AT): {{.FileName}}",
+		"TITLE_TEMPLATE_DE":      "Neue Datei hochgeladen",
+		"FOOTER_TEXT_DE":         "",
+	})
+	if message != "Neue Datei (AT): rechnung.pdf" {
+		t.Errorf("message = %q, want the de-AT override", message)
+	}
+	if title != "Neue Datei hochgeladen" {
+		t.Errorf("title = %q, want the language override for a regional locale", title)
+	}
+	if footer != footerText {
+		t.Errorf("footer = %q, want the default since FOOTER_TEXT_DE is empty", footer)
+	}
+
+	// A locale without any overrides renders the base templates
+	message, title, _ = renderLocalized(t, map[string]string{"LOCALE": "fr", "MESSAGE_TEMPLATE": "New file: {{.FileName}}"})
+	if message != "New file: rechnung.pdf" || title != messageTitle {
+		t.Errorf("got %q, %q, want the base templates", message, title)
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	for raw, want := range map[string]string{"": "", "de": "DE", "de-AT": "DE_AT", "de_AT.UTF-8": "DE_AT", "sr@latin": "SR"} {
+		if got, err := parseLocale(raw); err != nil || got != want {
+			t.Errorf("parseLocale(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseLocale("de AT"); err == nil {
+		t.Error("parseLocale accepted a locale with a space")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// logOutput is where structured log records are written; Lambda forwards stdout to CloudWatch Logs
+var logOutput io.Writer = os.Stdout
+
+// parseLogLevel reads LOG_LEVEL, defaulting to info when it is empty
+func parseLogLevel(raw string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid LOG_LEVEL %q: must be debug, info, warn or error", raw)
+	}
+}
+
+// newLogger creates a JSON logger that drops records below level and tags
+// records with the IDs of the dispatch they were logged under
+func newLogger(level slog.Level) *slog.Logger {
+	return slog.New(dispatchIDHandler{slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: level})})
+}
+
+// webhookHost returns only the host of a webhook URL, which is safe to log
+// since the path and query usually carry the token
+func webhookHost(webhookURL string) string {
+	if u, err := url.Parse(webhookURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "invalid URL"
+}
+
+// fileAttrs describes the files in a dispatch for log records. Batched
+// messages list every file name and each distinct bucket.
+func fileAttrs(files []FilePayload) []slog.Attr {
+	if len(files) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(files))
+	var buckets []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		names = append(names, file.FileName)
+		if !seen[file.Bucket] {
+			seen[file.Bucket] = true
+			buckets = append(buckets, file.Bucket)
+		}
+	}
+	return []slog.Attr{
+		slog.String("fileName", strings.Join(names, ", ")),
+		slog.String("bucket", strings.Join(buckets, ", ")),
+	}
+}
+
+// stripURL removes the request URL that net/http and net/url embed in their
+// errors, so error messages never reveal a webhook token
+func stripURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// captureLogs points loggers created during the test at a buffer
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := logOutput
+	logOutput = &buf
+	t.Cleanup(func() { logOutput = old })
+	return &buf
+}
+
+func TestDispatchLogsOmitWebhookURL(t *testing.T) {
+	logs := captureLogs(t)
+	srv := newWebhookServer(t)
+	good := srv.URL + "/api/webhooks/123/SECRETTOKEN"
+	bad := "https://127.0.0.1:1/api/webhooks/9/OTHERSECRET"
+
+	cfg := testConfig(t, good)
+	cfg.WebhookURLs = []string{good, bad}
+	cfg.SigningSecret = "SIGNSECRET"
+	cfg.SignatureHeader = "X-Signature"
+	d := newTestDispatcher(cfg, srv)
+	d.Logger = newLogger(slog.LevelDebug)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", Bucket: "invoices"}); err != nil {
+		t.Fatalf("partial delivery returned %v, want success", err)
+	}
+
+	out := logs.String()
+	for _, secret := range []string{"SECRETTOKEN", "OTHERSECRET", "SIGNSECRET", "/api/webhooks/"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("logs contain %q:\n%s", secret, out)
+		}
+	}
+
+	var dispatched map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q isn't JSON: %v", line, err)
+		}
+		if record["msg"] == "webhook dispatched" {
+			dispatched = record
+		}
+	}
+	if dispatched == nil {
+		t.Fatalf("no dispatch record in:\n%s", out)
+	}
+	want := map[string]interface{}{
+		"fileName":    "a.txt",
+		"bucket":      "invoices",
+		"webhookHost": webhookHost(srv.URL),
+		"statusCode":  float64(204),
+		"attempts":    float64(1),
+	}
+	for field, value := range want {
+		if dispatched[field] != value {
+			t.Errorf("%s = %v, want %v", field, dispatched[field], value)
+		}
+	}
+	if _, ok := dispatched["durationMs"]; !ok {
+		t.Error("dispatch record has no durationMs")
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for raw, want := range map[string]slog.Level{"": slog.LevelInfo, "DEBUG": slog.LevelDebug, "warning": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := parseLogLevel(raw); err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v, want %v", raw, got, err, want)
+		}
+	}
+	if _, err := parseLogLevel("verbose"); err == nil {
+		t.Error("parseLogLevel accepted an unknown level")
+	}
+}
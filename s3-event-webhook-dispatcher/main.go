@@ -1,13 +1,15 @@
 ﻿package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
-	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -21,25 +23,87 @@ type FilePayload struct {
 	Bucket         string `json:"bucket"`
 	ExpirationTime string `json:"expirationTime"`
 	Timestamp      string `json:"timestamp"`
+	FileSize       int64  `json:"fileSize"`
+	EventType      string `json:"eventType,omitempty"`
+	EventID        string `json:"eventId,omitempty"`
+	CorrelationID  string `json:"correlationId,omitempty"`
+	ContentType    string `json:"contentType,omitempty"`
+
+	// textEscaper is set on a copy prepared by escapePayloadText, so derived
+	// template fields such as CleanURL and Summary are escaped the same way
+	textEscaper *strings.Replacer
+
+	// displayLocation and displayLayout format LocalTime; renderTemplate
+	// sets them from DISPLAY_TIMEZONE and TIMESTAMP_LAYOUT
+	displayLocation *time.Location
+	displayLayout   string
+
+	// fileSizeSet tells a zero-byte file from one whose size wasn't sent
+	fileSizeSet bool
+
+	// keyRegex supplies the captures of Match; renderTemplate sets it from KEY_REGEX
+	keyRegex *regexp.Regexp
+
+	// rawFileName and rawExpirationTime are the values before
+	// escapePayloadText: Match runs KEY_REGEX against the key as the filters
+	// do, and the link expiry check parses the expiration as received
+	rawFileName       string
+	rawExpirationTime string
+
+	// unlinked marks an S3 notification handled without GENERATE_PRESIGNED_URL,
+	// which has no link to require
+	unlinked bool
 }
 
 // DiscordEmbed represents a Discord message embed structure
 type DiscordEmbed struct {
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Color       int       `json:"color"`
-	Timestamp   string    `json:"timestamp"`
-	Footer      EmbedItem `json:"footer"`
+	Title       string       `json:"title,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Description string       `json:"description"`
+	Color       int          `json:"color"`
+	Timestamp   string       `json:"timestamp,omitempty"`
+	Footer      EmbedItem    `json:"footer"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+	Author      *EmbedAuthor `json:"author,omitempty"`
+	Thumbnail   *EmbedMedia  `json:"thumbnail,omitempty"`
+	Image       *EmbedMedia  `json:"image,omitempty"`
+}
+
+// EmbedAuthor represents the author line shown above a Discord embed's title
+type EmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// EmbedField represents a name/value pair shown in a Discord embed's field grid
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
 }
 
 // EmbedItem represents elements in a Discord embed that have text attributes
 type EmbedItem struct {
-	Text string `json:"text"`
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
 }
 
 // DiscordMessage represents the full webhook payload sent to Discord
 type DiscordMessage struct {
-	Embeds []DiscordEmbed `json:"embeds"`
+	Content         string                  `json:"content,omitempty"`
+	Username        string                  `json:"username,omitempty"`
+	AvatarURL       string                  `json:"avatar_url,omitempty"`
+	ThreadName      string                  `json:"thread_name,omitempty"`
+	AllowedMentions *DiscordAllowedMentions `json:"allowed_mentions,omitempty"`
+	Embeds          []DiscordEmbed          `json:"embeds,omitempty"`
+}
+
+// DiscordAllowedMentions restricts which mentions in the message content actually notify anyone
+type DiscordAllowedMentions struct {
+	Parse []string `json:"parse"`
+	Roles []string `json:"roles,omitempty"`
+	Users []string `json:"users,omitempty"`
 }
 
 // getRandomRainbowColor returns a random color from a rainbow-like palette
@@ -69,81 +133,167 @@ func getRandomRainbowColor() int {
 	return rainbowColors[rand.Intn(len(rainbowColors))]
 }
 
-// Handler is the Lambda function handler
-func Handler(ctx context.Context, event events.CloudWatchEvent) error {
-	// Get webhook URL from environment variables
-	webhookURL := os.Getenv("WEBHOOK_URL")
-	if webhookURL == "" {
-		return fmt.Errorf("WEBHOOK_URL environment variable is not set")
-	}
-
-	// Parse the event detail
-	var payload FilePayload
-	if err := json.Unmarshal([]byte(event.Detail), &payload); err != nil {
-		return fmt.Errorf("failed to parse event detail: %v", err)
-	}
-
-	// Create description with formatted message
-	description := fmt.Sprintf(
-		"A new file has been uploaded to S3.\n\n**File Name:** %s\n**Temporary Link:** [Download File](%s)\n**Link Expires:** After %s",
-		payload.FileName,
-		payload.FileURL,
-		payload.ExpirationTime,
-	)
-
-	// Create Discord message with embed using a random rainbow color
-	message := DiscordMessage{
-		Embeds: []DiscordEmbed{
-			{
-				Title:       "New File Uploaded",
-				Description: description,
-				Color:       getRandomRainbowColor(),
-				Timestamp:   time.Now().Format(time.RFC3339),
-				Footer: EmbedItem{
-					Text: "S3 File Notification System",
-				},
-			},
-		},
-	}
-
-	// Serialize message to JSON for HTTP request
-	messageJSON, err := json.Marshal(message)
+// Handler is the Lambda function handler. It accepts a single EventBridge
+// event, an SQS batch of EventBridge events, an S3 event notification, or an
+// HTTP request through a Function URL or API Gateway for local testing. A
+// self-test request reports on the configuration instead. A panic is returned
+// as an ErrPanic error so the event is retried like any other failure.
+func Handler(ctx context.Context, raw json.RawMessage) (response interface{}, err error) {
+	defer recoverPanic(ctx, &err)
+
+	// A self-test reports a broken configuration in its result, so it runs before loading it
+	if send, overHTTP, ok := selfTestRequest(raw); ok {
+		result := runSelfTest(ctx, send, overHTTP)
+		if overHTTP {
+			return selfTestResponse(result), nil
+		}
+		return result, nil
+	}
+
+	// Load configuration from environment variables
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message to JSON: %v", err)
+		return nil, err
+	}
+	d := NewDispatcher(cfg)
+
+	// Route the standard logger through the JSON logger so every line is structured
+	slog.SetDefault(d.Logger)
+
+	// SQS batches report per-message failures instead of failing the whole invocation
+	if isSQSEvent(raw) {
+		var batch events.SQSEvent
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return nil, fmt.Errorf("%w: SQS event: %w", ErrPayloadParse, err)
+		}
+		return d.handleSQS(ctx, batch), nil
+	}
+
+	// S3 notifications carry no presigned URL; the payload is synthesized from each record
+	if isS3Event(raw) {
+		var notification events.S3Event
+		if err := json.Unmarshal(raw, &notification); err != nil {
+			return nil, fmt.Errorf("%w: S3 event: %w", ErrPayloadParse, err)
+		}
+		return nil, d.handleS3(ctx, notification)
+	}
+
+	// HTTP invocations get a JSON status response instead of an invocation error
+	if req, ok := parseHTTPRequest(raw); ok {
+		return d.handleHTTP(ctx, req), nil
 	}
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	var event events.CloudWatchEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, fmt.Errorf("%w: EventBridge event: %w", ErrPayloadParse, err)
 	}
+	return nil, d.handleEvent(ctx, event)
+}
 
-	// Send request to webhook endpoint
-	req, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		webhookURL,
-		bytes.NewBuffer(messageJSON),
-	)
+// handleEvent dispatches the files described by a single EventBridge event
+func (d *Dispatcher) handleEvent(ctx context.Context, event events.CloudWatchEvent) error {
+	payloads, err := payloadsFromEvent(d.Config, event)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %v", err)
+		return err
+	}
+	return d.dispatchEach(ctx, payloads)
+}
+
+// dispatchEach concurrently dispatches the files of one event, failing when any
+// of them fails so the whole event is retried
+func (d *Dispatcher) dispatchEach(ctx context.Context, payloads []FilePayload) error {
+	if len(payloads) == 1 {
+		return d.Dispatch(ctx, payloads[0])
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	// Execute HTTP request
-	resp, err := client.Do(req)
+	errs := make([]error, len(payloads))
+	forEachConcurrently(len(payloads), func(i int) {
+		errs[i] = d.Dispatch(ctx, payloads[i])
+	})
+
+	var failures multiError
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("file #%d (%s): %w", i+1, payloads[i].FileName, err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d file(s) failed: %w", len(failures), len(payloads), failures)
+}
+
+// payloadsFromEvent decodes the file payloads of an EventBridge event,
+// identifying each by the event ID when the detail doesn't carry its own
+// eventId. Files batched into one event get the ID suffixed with their
+// position, so deduplication doesn't mistake them for each other.
+func payloadsFromEvent(cfg Config, event events.CloudWatchEvent) ([]FilePayload, error) {
+	payloads, err := parseDetail(cfg, event.Detail)
 	if err != nil {
-		return fmt.Errorf("failed to send message to webhook: %v", err)
+		return nil, err
+	}
+	for i := range payloads {
+		if payloads[i].EventID != "" || event.ID == "" {
+			continue
+		}
+		payloads[i].EventID = event.ID
+		if len(payloads) > 1 {
+			payloads[i].EventID = fmt.Sprintf("%s#%d", event.ID, i)
+		}
+	}
+	return payloads, nil
+}
+
+// parseDetail decodes the file payloads from an EventBridge event detail,
+// which holds one payload or an array of them and may be base64-encoded as
+// DETAIL_ENCODING allows
+func parseDetail(cfg Config, detail json.RawMessage) ([]FilePayload, error) {
+	var payloads filePayloads
+	if err := decodeDetail(cfg, detail, &payloads); err != nil {
+		return nil, fmt.Errorf("%w: event detail: %w", ErrPayloadParse, err)
+	}
+
+	// Upstream producers that forward raw S3 keys pass the names still encoded
+	if cfg.DecodeFileNames {
+		for i := range payloads {
+			payloads[i].FileName = decodeS3Key(payloads[i].FileName)
+		}
 	}
-	defer resp.Body.Close()
+	return payloads, nil
+}
 
-	// Check for success status code
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook returned non-success status code: %d", resp.StatusCode)
+// checkConfigAtStartup loads the configuration during cold start so a broken
+// deployment fails its first init instead of every invocation. Only a failed
+// webhook URL lookup is left for the handler to retry, since it may be transient.
+func checkConfigAtStartup(ctx context.Context) error {
+	// Compile the templates first, so a malformed one fails init even when
+	// the webhook lookup below is left for the handler
+	if _, err := loadTemplates(); err != nil {
+		return err
 	}
 
+	cfg, err := loadConfig(ctx)
+	if errors.Is(err, ErrWebhookLookup) {
+		slog.WarnContext(ctx, "webhook URL lookup failed at startup; retrying on first invocation",
+			slog.String("error", err.Error()))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger := newLogger(cfg.LogLevel)
+	for _, warning := range configWarnings(cfg) {
+		logger.WarnContext(ctx, "configuration warning", slog.String("warning", warning))
+	}
 	return nil
 }
 
 func main() {
+	slog.SetDefault(newLogger(slog.LevelInfo))
+	if err := checkConfigAtStartup(context.Background()); err != nil {
+		slog.Error("invalid configuration; fix the environment and redeploy", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	lambda.Start(Handler)
 }
\ No newline at end of file
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MattermostAttachment represents a Mattermost message attachment, a subset of
+// Slack's that the incoming webhook renders as a colored card
+type MattermostAttachment struct {
+	Fallback  string `json:"fallback"`
+	Color     string `json:"color"`
+	Title     string `json:"title,omitempty"`
+	TitleLink string `json:"title_link,omitempty"`
+	Footer    string `json:"footer,omitempty"`
+}
+
+// MattermostMessage represents the body of a Mattermost incoming webhook request
+type MattermostMessage struct {
+	Text        string                 `json:"text"`
+	Username    string                 `json:"username,omitempty"`
+	Channel     string                 `json:"channel,omitempty"`
+	Attachments []MattermostAttachment `json:"attachments"`
+}
+
+// buildMattermostMessage formats the payload as a Mattermost incoming webhook
+// message: the rendered MESSAGE_TEMPLATE as Markdown text, posted to
+// MATTERMOST_CHANNEL when set, and an attachment linking the title to the file
+func buildMattermostMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	text, err := renderMessage(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	title, err := renderTitle(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	footer, err := renderFooter(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := MattermostAttachment{
+		Fallback: eventSummary(payload),
+		Color:    "#" + colorHex(embedColor(cfg, payload)),
+		Title:    title,
+		Footer:   footer,
+	}
+	if title != "" && isHTTPURL(payload.FileURL) {
+		attachment.TitleLink = payload.FileURL
+	}
+
+	message := MattermostMessage{
+		Text:        text,
+		Username:    cfg.MattermostUsername,
+		Channel:     cfg.MattermostChannel,
+		Attachments: []MattermostAttachment{attachment},
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Mattermost message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// mattermostTestConfig returns a Mattermost configuration with the platform defaults
+func mattermostTestConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := testConfig(t, "https://chat.example.com/hooks/xxxx")
+	cfg.Platform = platformMattermost
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+	cfg.EmbedColor = 0x3498DB
+	return cfg
+}
+
+func TestBuildMattermostMessageShape(t *testing.T) {
+	body, err := buildMessage(mattermostTestConfig(t), FilePayload{
+		FileName:       "reports/q1.pdf",
+		FileURL:        "https://example.com/q1.pdf",
+		Bucket:         "example-bucket",
+		ExpirationTime: "24 hours",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var message map[string]interface{}
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := message["text"].(string); !strings.Contains(text, "reports/q1.pdf") {
+		t.Errorf("text %q doesn't name the file", text)
+	}
+	for _, key := range []string{"embeds", "channel", "username"} {
+		if _, ok := message[key]; ok {
+			t.Errorf("Mattermost body carries %s: %s", key, body)
+		}
+	}
+	attachments, _ := message["attachments"].([]interface{})
+	if len(attachments) != 1 {
+		t.Fatalf("want one attachment: %s", body)
+	}
+	attachment := attachments[0].(map[string]interface{})
+	for key, want := range map[string]interface{}{
+		"color":      "#3498DB",
+		"title":      "New File Uploaded",
+		"title_link": "https://example.com/q1.pdf",
+		"footer":     footerText,
+	} {
+		if attachment[key] != want {
+			t.Errorf("attachment %s = %v, want %v", key, attachment[key], want)
+		}
+	}
+	if fallback, _ := attachment["fallback"].(string); fallback == "" {
+		t.Errorf("attachment has no fallback: %s", body)
+	}
+}
+
+func TestBuildMattermostMessageChannelOverride(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"PLATFORM":            platformMattermost,
+		"WEBHOOK_URL":         "https://chat.example.com/hooks/xxxx",
+		"MATTERMOST_CHANNEL":  " town-square ",
+		"MATTERMOST_USERNAME": "uploads-bot",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := buildMessage(cfg, FilePayload{FileName: "q1.pdf", FileURL: "https://example.com/q1.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message MattermostMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Channel != "town-square" || message.Username != "uploads-bot" {
+		t.Errorf("channel = %q, username = %q, want the overrides", message.Channel, message.Username)
+	}
+}
+
+func TestBuildMattermostMessageDeleteHasNoLink(t *testing.T) {
+	body, err := buildMessage(mattermostTestConfig(t), FilePayload{FileName: "q1.pdf", EventType: eventTypeDeleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message MattermostMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if link := message.Attachments[0].TitleLink; link != "" {
+		t.Errorf("title_link = %q, want none for a delete", link)
+	}
+}
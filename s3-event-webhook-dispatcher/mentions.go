@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Mention types Discord accepts in allowed_mentions.parse
+const (
+	mentionRoles    = "roles"
+	mentionUsers    = "users"
+	mentionEveryone = "everyone"
+)
+
+// mentionPattern matches the role (<@&id>) and user (<@id>, <@!id>) mentions in MENTION_CONTENT
+var mentionPattern = regexp.MustCompile(`<@([&!]?)(\d+)>`)
+
+// parseMentionParse reads MENTION_PARSE, a comma-separated list of roles,
+// users and everyone, which let every mention of that type ping, and of
+// role:<id> and user:<id> entries, which allow one role or user. An empty
+// value returns nil, leaving the allowed mentions to MENTION_CONTENT.
+func parseMentionParse(raw string) (*DiscordAllowedMentions, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	mentions := &DiscordAllowedMentions{Parse: []string{}}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		kind, id, hasID := strings.Cut(entry, ":")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		id = strings.TrimSpace(id)
+
+		switch {
+		case entry == "":
+			continue
+		case hasID && (id == "" || strings.Trim(id, "0123456789") != ""):
+			return nil, fmt.Errorf("invalid MENTION_PARSE entry %q: the ID must be numeric", entry)
+		case hasID && kind == "role":
+			mentions.Roles = appendUnique(mentions.Roles, id)
+		case hasID && kind == "user":
+			mentions.Users = appendUnique(mentions.Users, id)
+		case !hasID && (kind == mentionRoles || kind == mentionUsers || kind == mentionEveryone):
+			mentions.Parse = appendUnique(mentions.Parse, kind)
+		default:
+			return nil, fmt.Errorf("invalid MENTION_PARSE entry %q (supported: roles, users, everyone, role:<id>, user:<id>)", entry)
+		}
+	}
+
+	// Discord rejects a type listed both in parse and by ID
+	if len(mentions.Roles) > 0 && slices.Contains(mentions.Parse, mentionRoles) {
+		return nil, fmt.Errorf("MENTION_PARSE can't combine roles with role:<id> entries")
+	}
+	if len(mentions.Users) > 0 && slices.Contains(mentions.Parse, mentionUsers) {
+		return nil, fmt.Errorf("MENTION_PARSE can't combine users with user:<id> entries")
+	}
+	return mentions, nil
+}
+
+// discordAllowedMentions builds the allowed_mentions sent with every Discord
+// message, so mentions spelled out by file names or templates never ping.
+// MENTION_PARSE decides when set; otherwise only the roles and users
+// mentioned in MENTION_CONTENT ping, and @everyone and @here in it stay
+// silent unless ALLOW_EVERYONE is enabled.
+func discordAllowedMentions(cfg Config) *DiscordAllowedMentions {
+	if cfg.MentionParse != nil {
+		return cfg.MentionParse
+	}
+
+	mentions := &DiscordAllowedMentions{Parse: []string{}}
+	if cfg.MentionContent == "" {
+		return mentions
+	}
+	for _, match := range mentionPattern.FindAllStringSubmatch(cfg.MentionContent, -1) {
+		if match[1] == "&" {
+			mentions.Roles = appendUnique(mentions.Roles, match[2])
+		} else {
+			mentions.Users = appendUnique(mentions.Users, match[2])
+		}
+	}
+	if cfg.AllowEveryone {
+		mentions.Parse = append(mentions.Parse, mentionEveryone)
+	}
+	return mentions
+}
+
+// appendUnique appends value to list unless it is already there
+func appendUnique(list []string, value string) []string {
+	if slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
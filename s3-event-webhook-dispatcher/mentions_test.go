@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBuildDiscordMessageRoleMention(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.MentionContent = "<@&123456789> new invoice"
+
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := decodeDiscordMessage(t, body)
+	if message.Content != cfg.MentionContent {
+		t.Errorf("content = %q, want %q", message.Content, cfg.MentionContent)
+	}
+	if message.Embeds[0].Description == cfg.MentionContent {
+		t.Error("mention content leaked into the embed description")
+	}
+	want := &DiscordAllowedMentions{Parse: []string{}, Roles: []string{"123456789"}}
+	if !reflect.DeepEqual(message.AllowedMentions, want) {
+		t.Errorf("allowed_mentions = %+v, want %+v", message.AllowedMentions, want)
+	}
+}
+
+func TestDiscordAllowedMentionsSuppressesEveryone(t *testing.T) {
+	cfg := Config{MentionContent: "@everyone @here <@!42>"}
+	mentions := discordAllowedMentions(cfg)
+	if slices.Contains(mentions.Parse, mentionEveryone) {
+		t.Errorf("parse = %v, want @everyone suppressed by default", mentions.Parse)
+	}
+	if !reflect.DeepEqual(mentions.Users, []string{"42"}) {
+		t.Errorf("users = %v, want [42]", mentions.Users)
+	}
+
+	cfg.AllowEveryone = true
+	if mentions := discordAllowedMentions(cfg); !slices.Contains(mentions.Parse, mentionEveryone) {
+		t.Errorf("parse = %v, want everyone with ALLOW_EVERYONE", mentions.Parse)
+	}
+
+	// Without mention content nothing pings, including mentions in file names
+	if mentions := discordAllowedMentions(Config{}); mentions.Parse == nil || len(mentions.Parse)+len(mentions.Roles)+len(mentions.Users) != 0 {
+		t.Errorf("allowed_mentions = %+v, want an empty parse list", mentions)
+	}
+}
+
+func TestParseMentionParseRolesOnly(t *testing.T) {
+	mentions, err := parseMentionParse(" Roles ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&DiscordAllowedMentions{Parse: []string{mentionRoles}}); !reflect.DeepEqual(mentions, want) {
+		t.Errorf("allowed_mentions = %+v, want %+v", mentions, want)
+	}
+}
+
+func TestParseMentionParseIDs(t *testing.T) {
+	mentions, err := parseMentionParse("role:123, user:456, role:123, everyone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DiscordAllowedMentions{Parse: []string{mentionEveryone}, Roles: []string{"123"}, Users: []string{"456"}}
+	if !reflect.DeepEqual(mentions, want) {
+		t.Errorf("allowed_mentions = %+v, want %+v", mentions, want)
+	}
+
+	for _, raw := range []string{"role:abc", "user:", "channels", "roles,role:1", "users,user:2"} {
+		if _, err := parseMentionParse(raw); err == nil {
+			t.Errorf("parseMentionParse(%q): want an error", raw)
+		}
+	}
+}
+
+func TestMentionParseDefaultPingsNothing(t *testing.T) {
+	if mentions, err := parseMentionParse(""); err != nil || mentions != nil {
+		t.Errorf("parseMentionParse(\"\") = %+v, %v, want nil", mentions, err)
+	}
+
+	// A file name spelling out mentions can't ping anyone by default
+	setenvConfig(t, nil)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "@everyone <@&1>.txt", FileURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DiscordAllowedMentions{Parse: []string{}}
+	if got := decodeDiscordMessage(t, body).AllowedMentions; !reflect.DeepEqual(got, want) {
+		t.Errorf("allowed_mentions = %+v, want an empty parse list", got)
+	}
+}
+
+func TestMentionParseOverridesMentionContent(t *testing.T) {
+	setenvConfig(t, map[string]string{"MENTION_CONTENT": "<@&123> <@456>", "MENTION_PARSE": "role:123"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DiscordAllowedMentions{Parse: []string{}, Roles: []string{"123"}}
+	if got := discordAllowedMentions(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("allowed_mentions = %+v, want only role 123", got)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// metricsNamespace is the CloudWatch namespace the dispatch metrics are published under
+const metricsNamespace = "S3WebhookDispatcher"
+
+// metricsMu serializes EMF records from concurrent deliveries so lines never interleave
+var metricsMu sync.Mutex
+
+// emfMetric declares one metric in an Embedded Metric Format directive
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfDirective tells CloudWatch which fields of the record are metrics and dimensions
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+// emfMetadata is the "_aws" member that marks a log line as Embedded Metric Format
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// dispatchMetrics is the EMF record for one delivery to one webhook
+type dispatchMetrics struct {
+	AWS               emfMetadata `json:"_aws"`
+	WebhookHost       string      `json:"WebhookHost"`
+	Platform          string      `json:"Platform"`
+	DispatchSuccess   int         `json:"DispatchSuccess"`
+	DispatchFailure   int         `json:"DispatchFailure"`
+	DispatchLatencyMs int64       `json:"DispatchLatencyMs"`
+	FailureClass      string      `json:"FailureClass,omitempty"`
+}
+
+// newDispatchMetrics builds the EMF record for a delivery that finished at
+// now. A failed delivery is also counted per FailureClass, the class
+// classifyFailure gave its error.
+func newDispatchMetrics(cfg Config, webhookURL string, elapsed time.Duration, failureClass string, now time.Time) dispatchMetrics {
+	record := dispatchMetrics{
+		AWS: emfMetadata{
+			Timestamp: now.UnixMilli(),
+			CloudWatchMetrics: []emfDirective{{
+				Namespace:  metricsNamespace,
+				Dimensions: [][]string{{"WebhookHost", "Platform"}},
+				Metrics: []emfMetric{
+					{Name: "DispatchSuccess", Unit: "Count"},
+					{Name: "DispatchFailure", Unit: "Count"},
+					{Name: "DispatchLatencyMs", Unit: "Milliseconds"},
+				},
+			}},
+		},
+		WebhookHost:       webhookHost(webhookURL),
+		Platform:          cfg.Platform,
+		DispatchLatencyMs: elapsed.Milliseconds(),
+	}
+	if failureClass == "" {
+		record.DispatchSuccess = 1
+		return record
+	}
+
+	record.DispatchFailure = 1
+	record.FailureClass = failureClass
+	directive := &record.AWS.CloudWatchMetrics[0]
+	directive.Dimensions = append(directive.Dimensions, []string{"WebhookHost", "Platform", "FailureClass"})
+	return record
+}
+
+// emitDispatchMetrics writes the EMF record for a delivery when EMIT_METRICS is enabled
+func (d *Dispatcher) emitDispatchMetrics(webhookURL string, elapsed time.Duration, err error) {
+	if !d.Config.EmitMetrics {
+		return
+	}
+
+	line, marshalErr := json.Marshal(newDispatchMetrics(d.Config, webhookURL, elapsed, classifyFailure(err), time.Now()))
+	if marshalErr != nil {
+		d.Logger.Error("failed to encode metrics", slog.String("error", marshalErr.Error()))
+		return
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	logOutput.Write(append(line, '\n'))
+}
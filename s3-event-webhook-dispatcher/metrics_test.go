@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeEMF checks a record against the Embedded Metric Format spec: every
+// declared metric and dimension must be a top-level member of the record
+func decodeEMF(t *testing.T, line []byte) map[string]interface{} {
+	t.Helper()
+	var record map[string]interface{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		t.Fatalf("invalid EMF JSON %s: %v", line, err)
+	}
+	aws, ok := record["_aws"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("record %s has no _aws metadata", line)
+	}
+	if _, ok := aws["Timestamp"].(float64); !ok {
+		t.Errorf("_aws.Timestamp = %v, want epoch milliseconds", aws["Timestamp"])
+	}
+	directives, _ := aws["CloudWatchMetrics"].([]interface{})
+	if len(directives) != 1 {
+		t.Fatalf("got %d CloudWatchMetrics directives, want 1", len(directives))
+	}
+	directive := directives[0].(map[string]interface{})
+	if directive["Namespace"] != metricsNamespace {
+		t.Errorf("Namespace = %v, want %s", directive["Namespace"], metricsNamespace)
+	}
+	for _, set := range directive["Dimensions"].([]interface{}) {
+		for _, name := range set.([]interface{}) {
+			if _, ok := record[name.(string)].(string); !ok {
+				t.Errorf("dimension %s isn't a string member of the record", name)
+			}
+		}
+	}
+	for _, metric := range directive["Metrics"].([]interface{}) {
+		name := metric.(map[string]interface{})["Name"].(string)
+		if _, ok := record[name].(float64); !ok {
+			t.Errorf("metric %s isn't a number member of the record", name)
+		}
+	}
+	return record
+}
+
+func TestNewDispatchMetrics(t *testing.T) {
+	cfg := Config{Platform: platformSlack}
+	webhookURL := "https://hooks.slack.com/services/T0/B0/secret"
+
+	line, err := json.Marshal(newDispatchMetrics(cfg, webhookURL, 1500*time.Millisecond, "", time.UnixMilli(10000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := decodeEMF(t, line)
+	want := map[string]interface{}{
+		"WebhookHost":       "hooks.slack.com",
+		"Platform":          platformSlack,
+		"DispatchSuccess":   float64(1),
+		"DispatchFailure":   float64(0),
+		"DispatchLatencyMs": float64(1500),
+	}
+	for field, value := range want {
+		if record[field] != value {
+			t.Errorf("%s = %v, want %v", field, record[field], value)
+		}
+	}
+	if ts := record["_aws"].(map[string]interface{})["Timestamp"]; ts != float64(10000) {
+		t.Errorf("Timestamp = %v, want 10000", ts)
+	}
+	if strings.Contains(string(line), "secret") {
+		t.Errorf("metrics %s contain the webhook token", line)
+	}
+
+	line, err = json.Marshal(newDispatchMetrics(cfg, webhookURL, time.Second, failureHTTP5xx, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record = decodeEMF(t, line)
+	if record["DispatchSuccess"] != float64(0) || record["DispatchFailure"] != float64(1) || record["FailureClass"] != failureHTTP5xx {
+		t.Errorf("failure record = %s", line)
+	}
+}
+
+func TestEmitDispatchMetricsToggle(t *testing.T) {
+	logs := captureLogs(t)
+	d := &Dispatcher{Config: Config{Platform: platformDiscord}, Logger: newLogger(slogQuiet)}
+
+	d.emitDispatchMetrics("https://discord.com/api/webhooks/1/token", time.Second, nil)
+	if logs.Len() != 0 {
+		t.Errorf("metrics written without EMIT_METRICS: %s", logs)
+	}
+
+	d.Config.EmitMetrics = true
+	d.emitDispatchMetrics("https://discord.com/api/webhooks/1/token", time.Second, errors.New("boom"))
+	record := decodeEMF(t, logs.Bytes())
+	if record["WebhookHost"] != "discord.com" || record["Platform"] != platformDiscord || record["DispatchFailure"] != float64(1) {
+		t.Errorf("record = %s", logs)
+	}
+}
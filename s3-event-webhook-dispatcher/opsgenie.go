@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// opsgenieAlertsURL is the Alert API endpoint used when no webhook URL is
+// configured; EU accounts set WEBHOOK_URL to https://api.eu.opsgenie.com/v2/alerts
+const opsgenieAlertsURL = "https://api.opsgenie.com/v2/alerts"
+
+// maxOpsgenieMessageChars is the Alert API limit on an alert's message
+const maxOpsgenieMessageChars = 130
+
+// opsgeniePriorities are the OPSGENIE_PRIORITY values the Alert API accepts
+var opsgeniePriorities = []string{"P1", "P2", "P3", "P4", "P5"}
+
+// OpsgenieAlert represents the body of an Opsgenie Alert API create request
+type OpsgenieAlert struct {
+	Message  string            `json:"message"`
+	Alias    string            `json:"alias"`
+	Source   string            `json:"source"`
+	Priority string            `json:"priority"`
+	Details  map[string]string `json:"details"`
+}
+
+// parseOpsgeniePriority reads OPSGENIE_PRIORITY, defaulting to Opsgenie's own default of P3
+func parseOpsgeniePriority(raw string) (string, error) {
+	priority := strings.ToUpper(strings.TrimSpace(raw))
+	if priority == "" {
+		return "P3", nil
+	}
+	for _, allowed := range opsgeniePriorities {
+		if priority == allowed {
+			return priority, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported OPSGENIE_PRIORITY %q (supported: %s)", raw, strings.Join(opsgeniePriorities, ", "))
+}
+
+// buildOpsgenieAlert formats the payload as an Opsgenie alert. The alias comes
+// from the event identity, so Opsgenie folds a redelivered event into the
+// open alert instead of raising another.
+func buildOpsgenieAlert(cfg Config, payload FilePayload) ([]byte, error) {
+	message, err := alertSummary(cfg, payload, maxOpsgenieMessageChars)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{
+		"bucket": payload.Bucket,
+		"key":    payload.FileName,
+	}
+	if payload.FileURL != "" {
+		details["url"] = payload.FileURL
+	}
+	if payload.FileSize > 0 {
+		details["fileSize"] = strconv.FormatInt(payload.FileSize, 10)
+	}
+	if payload.EventType != "" {
+		details["eventType"] = payload.EventType
+	}
+
+	alert := OpsgenieAlert{
+		Message:  message,
+		Alias:    eventKey(payload),
+		Source:   footerText,
+		Priority: cfg.OpsgeniePriority,
+		Details:  details,
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(alert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Opsgenie alert to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDispatchOpsgenieAlert(t *testing.T) {
+	srv := newWebhookServer(t)
+	setenvConfig(t, map[string]string{
+		"PLATFORM":              platformOpsgenie,
+		"WEBHOOK_URL":           srv.URL,
+		"OPSGENIE_API_KEY":      "0p5-k3y",
+		"OPSGENIE_PRIORITY":     "p1",
+		"MESSAGE_TEMPLATE":      "{{.FileName}} landed in {{.Bucket}}",
+		"ALLOW_PRIVATE_TARGETS": "true",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.MaxRetries = 0
+	d := newTestDispatcher(cfg, srv)
+
+	payload := FilePayload{
+		EventID:  "ev-42",
+		Bucket:   "security-logs",
+		FileName: "alerts/breach.json",
+		FileURL:  "https://example.com/breach.json",
+		FileSize: 2048,
+	}
+	if err := d.Dispatch(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+
+	request := srv.received()[0]
+	if got := request.Header.Get("Authorization"); got != "GenieKey 0p5-k3y" {
+		t.Errorf("Authorization = %q, want GenieKey 0p5-k3y", got)
+	}
+	var alert OpsgenieAlert
+	if err := json.Unmarshal(request.Body, &alert); err != nil {
+		t.Fatal(err)
+	}
+	if alert.Alias != "ev-42" {
+		t.Errorf("alias = %q, want the event ID", alert.Alias)
+	}
+	if alert.Message != "alerts/breach.json landed in security-logs" || alert.Priority != "P1" {
+		t.Errorf("message = %q, priority = %q", alert.Message, alert.Priority)
+	}
+	if alert.Details["bucket"] != "security-logs" || alert.Details["key"] != "alerts/breach.json" ||
+		alert.Details["url"] != "https://example.com/breach.json" || alert.Details["fileSize"] != "2048" {
+		t.Errorf("details = %v", alert.Details)
+	}
+}
+
+func TestBuildOpsgenieAlertAlias(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.FileName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Platform: platformOpsgenie, OpsgeniePriority: "P3", MessageTemplate: tmpl}
+	alias := func(payload FilePayload) string {
+		body, err := buildOpsgenieAlert(cfg, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var alert OpsgenieAlert
+		if err := json.Unmarshal(body, &alert); err != nil {
+			t.Fatal(err)
+		}
+		return alert.Alias
+	}
+
+	// Redeliveries of an upload without an event ID fold into the same alert
+	upload := FilePayload{Bucket: "b", FileName: "a.txt", Timestamp: "2024-05-01T12:00:00Z"}
+	if first, again := alias(upload), alias(upload); first == "" || first != again {
+		t.Errorf("aliases %q and %q for the same upload, want one stable alias", first, again)
+	}
+}
+
+func TestBuildOpsgenieAlertTruncatesMessage(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.FileName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Platform: platformOpsgenie, OpsgeniePriority: "P3", MessageTemplate: tmpl}
+	body, err := buildOpsgenieAlert(cfg, FilePayload{FileName: strings.Repeat("x", 500)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var alert OpsgenieAlert
+	if err := json.Unmarshal(body, &alert); err != nil {
+		t.Fatal(err)
+	}
+	if n := len([]rune(alert.Message)); n > maxOpsgenieMessageChars {
+		t.Errorf("message has %d characters, want at most %d", n, maxOpsgenieMessageChars)
+	}
+}
+
+func TestParseOpsgeniePriority(t *testing.T) {
+	for raw, want := range map[string]string{"": "P3", "p1": "P1", " P5 ": "P5"} {
+		if got, err := parseOpsgeniePriority(raw); err != nil || got != want {
+			t.Errorf("parseOpsgeniePriority(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseOpsgeniePriority("P0"); err == nil {
+		t.Error("parseOpsgeniePriority accepted an unknown priority")
+	}
+}
+
+func TestOpsgenieRequiresAPIKey(t *testing.T) {
+	setenvConfig(t, map[string]string{"PLATFORM": platformOpsgenie, "WEBHOOK_URL": ""})
+	if _, err := loadConfig(context.Background()); err == nil || !strings.Contains(err.Error(), "OPSGENIE_API_KEY") {
+		t.Errorf("loadConfig without OPSGENIE_API_KEY: err = %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pagerDutyEventsURL is the Events API v2 endpoint used when no webhook URL is configured
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// maxPagerDutySummaryChars is the Events API v2 limit on payload.summary
+const maxPagerDutySummaryChars = 1024
+
+// pagerDutySeverities are the PAGERDUTY_SEVERITY values the Events API v2 accepts
+var pagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+// PagerDutyEvent represents an Events API v2 trigger event
+type PagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     PagerDutyPayload `json:"payload"`
+}
+
+// PagerDutyPayload describes the incident created by a PagerDutyEvent
+type PagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	CustomDetails PagerDutyCustomDetails `json:"custom_details"`
+}
+
+// PagerDutyCustomDetails carries the file metadata shown on the incident
+type PagerDutyCustomDetails struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	URL       string `json:"url,omitempty"`
+	FileSize  int64  `json:"fileSize,omitempty"`
+	EventType string `json:"eventType,omitempty"`
+}
+
+// parsePagerDutySeverity reads PAGERDUTY_SEVERITY, defaulting to warning
+func parsePagerDutySeverity(raw string) (string, error) {
+	severity := strings.ToLower(strings.TrimSpace(raw))
+	if severity == "" {
+		return "warning", nil
+	}
+	for _, allowed := range pagerDutySeverities {
+		if severity == allowed {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported PAGERDUTY_SEVERITY %q (supported: %s)", raw, strings.Join(pagerDutySeverities, ", "))
+}
+
+// buildPagerDutyEvent formats the payload as a PagerDuty trigger event for
+// PAGERDUTY_ROUTING_KEY. The dedup key comes from the event identity, so a
+// redelivered event updates the same incident instead of opening another.
+func buildPagerDutyEvent(cfg Config, payload FilePayload) ([]byte, error) {
+	if cfg.PagerDutyRoutingKey == "" {
+		return nil, fmt.Errorf("PAGERDUTY_ROUTING_KEY must be set when PLATFORM is %q", platformPagerDuty)
+	}
+
+	summary, err := alertSummary(cfg, payload, maxPagerDutySummaryChars)
+	if err != nil {
+		return nil, err
+	}
+
+	source := payload.Bucket
+	if source == "" {
+		source = footerText
+	}
+
+	event := PagerDutyEvent{
+		RoutingKey:  cfg.PagerDutyRoutingKey,
+		EventAction: "trigger",
+		DedupKey:    eventKey(payload),
+		Payload: PagerDutyPayload{
+			Summary:   summary,
+			Source:    source,
+			Severity:  cfg.PagerDutySeverity,
+			Timestamp: messageTime(cfg, payload).Format(time.RFC3339),
+			CustomDetails: PagerDutyCustomDetails{
+				Bucket:    payload.Bucket,
+				Key:       payload.FileName,
+				URL:       payload.FileURL,
+				FileSize:  payload.FileSize,
+				EventType: payload.EventType,
+			},
+		},
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PagerDuty event to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
+
+// alertSummary renders MESSAGE_TEMPLATE as the one-line summary of an alert,
+// collapsing line breaks and cutting it to max characters
+func alertSummary(cfg Config, payload FilePayload, max int) (string, error) {
+	summary, err := renderMessage(cfg, payload)
+	if err != nil {
+		return "", err
+	}
+	return truncateText(strings.Join(strings.Fields(summary), " "), max), nil
+}
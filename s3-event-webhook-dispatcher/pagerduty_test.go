@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildPagerDutyEvent(t *testing.T) {
+	tmpl, err := parseMessageTemplate("New upload:\n{{.FileName}} in {{.Bucket}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		Platform:            platformPagerDuty,
+		PagerDutyRoutingKey: "R0UT1NGKEY",
+		PagerDutySeverity:   "critical",
+		MessageTemplate:     tmpl,
+	}
+	payload := FilePayload{
+		EventID:   "ev-123",
+		EventType: "ObjectCreated:Put",
+		Bucket:    "security-logs",
+		FileName:  "alerts/breach.json",
+		FileURL:   "https://example.com/breach.json",
+		FileSize:  2048,
+		Timestamp: "2024-05-01T12:00:00Z",
+	}
+
+	body, err := buildPagerDutyEvent(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode generically so the test pins the Events API v2 field names
+	var event map[string]any
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event["routing_key"] != "R0UT1NGKEY" || event["event_action"] != "trigger" || event["dedup_key"] != "ev-123" {
+		t.Errorf("event = %v, want a trigger for the routing key deduplicated on the event ID", event)
+	}
+	details := event["payload"].(map[string]any)
+	if details["summary"] != "New upload: alerts/breach.json in security-logs" {
+		t.Errorf("summary = %q", details["summary"])
+	}
+	if details["source"] != "security-logs" || details["severity"] != "critical" || details["timestamp"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("payload = %v", details)
+	}
+	custom := details["custom_details"].(map[string]any)
+	if custom["bucket"] != "security-logs" || custom["key"] != "alerts/breach.json" || custom["url"] != "https://example.com/breach.json" {
+		t.Errorf("custom_details = %v", custom)
+	}
+}
+
+func TestBuildPagerDutyEventDedupKey(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.FileName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Platform: platformPagerDuty, PagerDutyRoutingKey: "key", PagerDutySeverity: "warning", MessageTemplate: tmpl}
+	dedupKey := func(payload FilePayload) string {
+		body, err := buildPagerDutyEvent(cfg, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var event PagerDutyEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatal(err)
+		}
+		return event.DedupKey
+	}
+
+	// Without an event ID a redelivery of the same upload still maps to one incident
+	upload := FilePayload{Bucket: "b", FileName: "a.txt", Timestamp: "2024-05-01T12:00:00Z"}
+	first, again := dedupKey(upload), dedupKey(upload)
+	if first == "" || first != again {
+		t.Errorf("dedup keys %q and %q for the same upload, want one stable key", first, again)
+	}
+	upload.FileName = "b.txt"
+	if other := dedupKey(upload); other == first {
+		t.Error("different uploads share a dedup key")
+	}
+}
+
+func TestBuildPagerDutyEventRequiresRoutingKey(t *testing.T) {
+	if _, err := buildPagerDutyEvent(Config{Platform: platformPagerDuty}, FilePayload{FileName: "a.txt"}); err == nil {
+		t.Error("buildPagerDutyEvent accepted an empty PAGERDUTY_ROUTING_KEY")
+	}
+}
+
+func TestParsePagerDutySeverity(t *testing.T) {
+	for raw, want := range map[string]string{"": "warning", "Critical": "critical", " info ": "info"} {
+		if got, err := parsePagerDutySeverity(raw); err != nil || got != want {
+			t.Errorf("parsePagerDutySeverity(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parsePagerDutySeverity("sev1"); err == nil {
+		t.Error("parsePagerDutySeverity accepted an unknown severity")
+	}
+}
+
+func TestLoadConfigPagerDutyDefaultsURL(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"PLATFORM":              platformPagerDuty,
+		"WEBHOOK_URL":           "",
+		"PAGERDUTY_ROUTING_KEY": "key",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.WebhookURLs) != 1 || cfg.WebhookURLs[0] != pagerDutyEventsURL {
+		t.Errorf("WebhookURLs = %q, want the Events API v2 endpoint", cfg.WebhookURLs)
+	}
+}
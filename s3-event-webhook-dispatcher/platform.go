@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Supported values for the PLATFORM environment variable
+const (
+	platformDiscord    = "discord"
+	platformSlack      = "slack"
+	platformTeams      = "teams"
+	platformGeneric    = "generic"
+	platformTelegram   = "telegram"
+	platformGoogleChat = "googlechat"
+	platformPagerDuty  = "pagerduty"
+	platformOpsgenie   = "opsgenie"
+	platformMattermost = "mattermost"
+)
+
+// Text shared by every platform's message
+const (
+	messageTitle = "New File Uploaded"
+	footerText   = "S3 File Notification System"
+)
+
+// messageBuilder serializes a file payload into a platform-specific webhook request body
+type messageBuilder func(cfg Config, payload FilePayload) ([]byte, error)
+
+// messageBuilders maps each supported platform to its body builder
+var messageBuilders = map[string]messageBuilder{
+	platformDiscord:    buildDiscordMessage,
+	platformSlack:      buildSlackMessage,
+	platformTeams:      buildTeamsMessage,
+	platformGeneric:    buildGenericMessage,
+	platformTelegram:   buildTelegramMessage,
+	platformGoogleChat: buildGoogleChatMessage,
+	platformPagerDuty:  buildPagerDutyEvent,
+	platformOpsgenie:   buildOpsgenieAlert,
+	platformMattermost: buildMattermostMessage,
+}
+
+// buildMessage serializes the payload using the builder for the configured platform
+func buildMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	build, ok := messageBuilders[cfg.Platform]
+	if !ok {
+		return nil, fmt.Errorf("unsupported platform %q", cfg.Platform)
+	}
+	return build(cfg, payload)
+}
+
+// parsePlatform normalizes the PLATFORM value and checks that a builder exists for it
+func parsePlatform(raw string) (string, error) {
+	platform := strings.ToLower(strings.TrimSpace(raw))
+	if platform == "" {
+		return platformDiscord, nil
+	}
+	if _, ok := messageBuilders[platform]; !ok {
+		return "", fmt.Errorf("unsupported PLATFORM %q (supported: %s)", raw, strings.Join(supportedPlatforms(), ", "))
+	}
+	return platform, nil
+}
+
+// platformHosts maps webhook hosts, and their subdomains, to the platform they expect
+var platformHosts = []struct {
+	host     string
+	platform string
+}{
+	{"discord.com", platformDiscord},
+	{"discordapp.com", platformDiscord},
+	{"hooks.slack.com", platformSlack},
+	{"webhook.office.com", platformTeams},
+	{"outlook.office.com", platformTeams},
+	{"logic.azure.com", platformTeams},
+	{"api.telegram.org", platformTelegram},
+	{"chat.googleapis.com", platformGoogleChat},
+	{"events.pagerduty.com", platformPagerDuty},
+	{"api.opsgenie.com", platformOpsgenie},
+	{"api.eu.opsgenie.com", platformOpsgenie},
+}
+
+// detectPlatform infers the platform from the webhook URL's host when PLATFORM
+// isn't set, falling back to Discord for hosts it doesn't recognize
+func detectPlatform(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return platformDiscord
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, known := range platformHosts {
+		if host == known.host || strings.HasSuffix(host, "."+known.host) {
+			return known.platform
+		}
+	}
+	return platformDiscord
+}
+
+// defaultWebhookURL returns the fixed endpoint of an alerting platform, or ""
+// for platforms that need a webhook URL
+func defaultWebhookURL(platform string) string {
+	switch platform {
+	case platformPagerDuty:
+		return pagerDutyEventsURL
+	case platformOpsgenie:
+		return opsgenieAlertsURL
+	}
+	return ""
+}
+
+// supportedPlatforms lists the registered platform names in sorted order
+func supportedPlatforms() []string {
+	names := make([]string, 0, len(messageBuilders))
+	for name := range messageBuilders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Values of FilePayload.EventType; an empty type is treated as created
+const (
+	eventTypeCreated = "created"
+	eventTypeDeleted = "deleted"
+)
+
+// isDeleted reports whether the payload describes a removed object
+func (p FilePayload) isDeleted() bool {
+	return strings.EqualFold(p.EventType, eventTypeDeleted)
+}
+
+// eventSummary returns the one-line description of what happened to the file
+func eventSummary(payload FilePayload) string {
+	if payload.isDeleted() {
+		return "A file has been deleted from S3."
+	}
+	return "A new file has been uploaded to S3."
+}
+
+// embedColor returns the color for the payload's event type, or a random
+// rainbow color when none is configured
+func embedColor(cfg Config, payload FilePayload) int {
+	color := cfg.EmbedColor
+	if payload.isDeleted() && cfg.DeleteEmbedColorSet {
+		color = cfg.DeleteEmbedColor
+	}
+	return resolveColor(color, payload)
+}
+
+// resolveColor turns the random and hash color modes into an RGB value for the payload
+func resolveColor(color int, payload FilePayload) int {
+	switch color {
+	case randomEmbedColor:
+		return getRandomRainbowColor()
+	case hashEmbedColor:
+		return bucketColor(payload.Bucket)
+	}
+	return color
+}
+
+// bucketColor derives a stable 24-bit color from a bucket name
+func bucketColor(bucket string) int {
+	h := fnv.New32a()
+	h.Write([]byte(bucket))
+	return int(h.Sum32() & 0xFFFFFF)
+}
+
+// colorHex converts an integer RGB color into a 6-digit uppercase hex string without a prefix
+func colorHex(color int) string {
+	return fmt.Sprintf("%06X", color&0xFFFFFF)
+}
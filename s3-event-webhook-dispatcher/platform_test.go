@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeleteEventOverrides(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"EMBED_COLOR":             "#00FF00",
+		"DELETE_MESSAGE_TEMPLATE": "Removed {{.FileName}}",
+		"DELETE_TITLE":            "File Deleted from {{.Bucket}}",
+		"DELETE_EMBED_COLOR":      "#FF0000",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		eventType   string
+		title       string
+		description string
+		color       int
+	}{
+		{eventTypeCreated, messageTitle, "report.pdf", 0x00FF00},
+		{eventTypeDeleted, "File Deleted from invoices", "Removed report.pdf", 0xFF0000},
+	}
+	for _, tt := range tests {
+		payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/a", Bucket: "invoices", EventType: tt.eventType}
+		body, err := buildDiscordMessage(cfg, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		embed := decodeDiscordMessage(t, body).Embeds[0]
+		if embed.Title != tt.title {
+			t.Errorf("%s: title = %q, want %q", tt.eventType, embed.Title, tt.title)
+		}
+		if !strings.Contains(embed.Description, tt.description) {
+			t.Errorf("%s: description = %q, want it to contain %q", tt.eventType, embed.Description, tt.description)
+		}
+		if embed.Color != tt.color {
+			t.Errorf("%s: color = %#x, want %#x", tt.eventType, embed.Color, tt.color)
+		}
+	}
+}
+
+func TestDeleteEventFallsBackToCreateConfig(t *testing.T) {
+	setenvConfig(t, map[string]string{"EMBED_COLOR": "#00FF00"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/a", EventType: eventTypeDeleted}
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embed := decodeDiscordMessage(t, body).Embeds[0]
+	if embed.Title != messageTitle {
+		t.Errorf("title = %q, want the create title %q", embed.Title, messageTitle)
+	}
+	if embed.Color != 0x00FF00 {
+		t.Errorf("color = %#x, want the create color", embed.Color)
+	}
+	if !strings.Contains(embed.Description, "report.pdf") {
+		t.Errorf("description = %q, want the create template", embed.Description)
+	}
+}
+
+func TestPayloadFromS3RecordEventType(t *testing.T) {
+	record := s3PutRecord("uploads", "report.pdf", 1)
+	payload, err := payloadFromS3Record(context.Background(), Config{}, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.EventType != eventTypeCreated {
+		t.Errorf("EventType = %q for %s", payload.EventType, record.EventName)
+	}
+
+	record.EventName = "ObjectRemoved:Delete"
+	payload, err = payloadFromS3Record(context.Background(), Config{}, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !payload.isDeleted() {
+		t.Errorf("EventType = %q for %s", payload.EventType, record.EventName)
+	}
+}
+
+func TestDetectPlatform(t *testing.T) {
+	for webhookURL, want := range map[string]string{
+		"https://discord.com/api/webhooks/1/token":                 platformDiscord,
+		"https://canary.discordapp.com/api/webhooks/1/token":       platformDiscord,
+		"https://hooks.slack.com/services/T0/B0/x":                 platformSlack,
+		"https://acme.webhook.office.com/webhookb2/x":              platformTeams,
+		"https://prod-01.westus.logic.azure.com/workflows/x":       platformTeams,
+		"https://api.telegram.org/bot123:abc/sendMessage":          platformTelegram,
+		"https://chat.googleapis.com/v1/spaces/AAA/messages?key=k": platformGoogleChat,
+		"https://events.pagerduty.com/v2/enqueue":                  platformPagerDuty,
+		"https://API.EU.OPSGENIE.COM/v2/alerts":                    platformOpsgenie,
+		"https://hooks.example.com/incoming":                       platformDiscord,
+		"https://notslack.com/hooks.slack.com":                     platformDiscord,
+		"https://hooks.slack.com.evil.example/services/T0/B0/x":    platformDiscord,
+		"://not a url": platformDiscord,
+	} {
+		if got := detectPlatform(webhookURL); got != want {
+			t.Errorf("detectPlatform(%q) = %q, want %q", webhookURL, got, want)
+		}
+	}
+}
+
+func TestLoadConfigDetectsPlatform(t *testing.T) {
+	setenvConfig(t, map[string]string{"WEBHOOK_URL": "https://hooks.slack.com/services/T0/B0/x"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Platform != platformSlack {
+		t.Errorf("Platform = %q, want %q detected from the host", cfg.Platform, platformSlack)
+	}
+
+	// An explicit PLATFORM always wins over the host
+	setenvConfig(t, map[string]string{
+		"WEBHOOK_URL":   "https://hooks.slack.com/services/T0/B0/x",
+		"PLATFORM":      platformGeneric,
+		"BODY_TEMPLATE": `{"name": {{json .FileName}}}`,
+	})
+	cfg, err = loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Platform != platformGeneric {
+		t.Errorf("Platform = %q, want the explicit %q", cfg.Platform, platformGeneric)
+	}
+}
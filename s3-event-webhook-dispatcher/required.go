@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultRequiredFields are the payload fields a message is useless without
+var defaultRequiredFields = []string{"fileName", "fileUrl"}
+
+// payloadFieldPresent reports, for each REQUIRED_FIELDS name, whether the
+// payload has a value for that field. Names match the payload's JSON fields.
+var payloadFieldPresent = map[string]func(FilePayload) bool{
+	"fileName":       func(p FilePayload) bool { return strings.TrimSpace(p.FileName) != "" },
+	"fileUrl":        func(p FilePayload) bool { return strings.TrimSpace(p.FileURL) != "" },
+	"bucket":         func(p FilePayload) bool { return strings.TrimSpace(p.Bucket) != "" },
+	"expirationTime": func(p FilePayload) bool { return strings.TrimSpace(p.ExpirationTime) != "" },
+	"timestamp":      func(p FilePayload) bool { return strings.TrimSpace(p.Timestamp) != "" },
+	"fileSize":       func(p FilePayload) bool { return p.FileSize > 0 },
+	"eventType":      func(p FilePayload) bool { return p.EventType != "" },
+	"eventId":        func(p FilePayload) bool { return p.EventID != "" },
+	"correlationId":  func(p FilePayload) bool { return p.CorrelationID != "" },
+	"contentType":    func(p FilePayload) bool { return strings.TrimSpace(p.ContentType) != "" },
+}
+
+// parseRequiredFields reads REQUIRED_FIELDS, a comma-separated list of payload
+// field names matched case-insensitively, defaulting to fileName and fileUrl
+func parseRequiredFields(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return defaultRequiredFields, nil
+	}
+
+	var fields []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field, ok := canonicalPayloadField(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown REQUIRED_FIELDS field %q (supported: %s)", name, strings.Join(payloadFieldNames(), ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// canonicalPayloadField returns the payload field name matching name regardless of case
+func canonicalPayloadField(name string) (string, bool) {
+	for field := range payloadFieldPresent {
+		if strings.EqualFold(field, name) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// payloadFieldNames lists the payload field names REQUIRED_FIELDS accepts, sorted
+func payloadFieldNames() []string {
+	names := make([]string, 0, len(payloadFieldPresent))
+	for name := range payloadFieldPresent {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkRequiredFields returns an ErrMissingFields error naming every
+// REQUIRED_FIELDS field the payload lacks. Delete events and S3 notifications
+// without GENERATE_PRESIGNED_URL have no link to share, so fileUrl is never
+// required of them.
+func checkRequiredFields(cfg Config, payload FilePayload) error {
+	var missing []string
+	for _, field := range cfg.RequiredFields {
+		if field == "fileUrl" && (payload.isDeleted() || payload.unlinked) {
+			continue
+		}
+		if !payloadFieldPresent[field](payload) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrMissingFields, strings.Join(missing, ", "))
+}
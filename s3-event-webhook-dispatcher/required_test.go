@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestCheckRequiredFieldsMissingFileName(t *testing.T) {
+	cfg := Config{RequiredFields: defaultRequiredFields}
+	err := checkRequiredFields(cfg, FilePayload{FileURL: "https://example.com/report.pdf"})
+	if !errors.Is(err, ErrMissingFields) {
+		t.Fatalf("err = %v, want ErrMissingFields", err)
+	}
+	if got, want := err.Error(), ErrMissingFields.Error()+": fileName"; got != want {
+		t.Errorf("err = %q, want %q", got, want)
+	}
+}
+
+func TestCheckRequiredFieldsDeleteWithoutURL(t *testing.T) {
+	cfg := Config{RequiredFields: defaultRequiredFields}
+	payload := FilePayload{FileName: "report.pdf", EventType: eventTypeDeleted}
+	if err := checkRequiredFields(cfg, payload); err != nil {
+		t.Errorf("delete event: %v", err)
+	}
+}
+
+func TestCheckRequiredFieldsS3PutWithoutPresigning(t *testing.T) {
+	cfg := Config{RequiredFields: defaultRequiredFields}
+	record := events.S3EventRecord{EventName: "ObjectCreated:Put"}
+	record.S3.Bucket.Name = "uploads"
+	record.S3.Object.Key = "reports/summary.pdf"
+
+	payload, err := payloadFromS3Record(context.Background(), cfg, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.FileURL != "" {
+		t.Fatalf("FileURL = %q, want none without GENERATE_PRESIGNED_URL", payload.FileURL)
+	}
+	if err := checkRequiredFields(cfg, payload); err != nil {
+		t.Errorf("S3 put without presigning: %v", err)
+	}
+
+	// An event without a link from any other source still needs one
+	payload.unlinked = false
+	if err := checkRequiredFields(cfg, payload); !errors.Is(err, ErrMissingFields) {
+		t.Errorf("err = %v, want ErrMissingFields", err)
+	}
+}
+
+func TestParseRequiredFields(t *testing.T) {
+	fields, err := parseRequiredFields(" FILENAME , bucket ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || fields[0] != "fileName" || fields[1] != "bucket" {
+		t.Errorf("fields = %v, want [fileName bucket]", fields)
+	}
+	if _, err := parseRequiredFields("fileName,owner"); err == nil {
+		t.Error("unknown field: want an error")
+	}
+}
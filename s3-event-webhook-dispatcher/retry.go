@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponseBodyBytes bounds how much of a failed response body is read, both
+// for a 429's retry_after and for the error message
+const maxResponseBodyBytes = 4096
+
+// deadlineSafetyMargin is kept free before the Lambda deadline so a failed
+// dispatch is still logged and reported instead of being cut off by the timeout
+const deadlineSafetyMargin = 500 * time.Millisecond
+
+// deliveryResult summarizes a delivery to one webhook for logging
+type deliveryResult struct {
+	StatusCode int
+	Attempts   int
+	MessageID  string
+}
+
+// attemptResult describes the outcome of a single webhook request
+type attemptResult struct {
+	StatusCode int
+	Retryable  bool
+	RetryAfter time.Duration
+	MessageID  string
+}
+
+// sendWithRetry posts the body to the webhook, retrying network errors and
+// 5xx/429 responses with exponential backoff until MaxRetries is exhausted.
+// A Retry-After hint from a 429 response replaces the backoff delay. All
+// attempts and sleeps must finish before the invocation deadline minus
+// deadlineSafetyMargin, and within REQUEST_TIMEOUT_SECONDS when
+// PER_ATTEMPT_TIMEOUT_SECONDS gives each attempt its own timeout.
+func (d *Dispatcher) sendWithRetry(ctx context.Context, webhookURL string, body []byte, contentType string) (deliveryResult, error) {
+	cfg := d.Config
+	ctx, cancel := withDeadlineBudget(ctx)
+	defer cancel()
+	if cfg.AttemptTimeout > 0 {
+		var cancelBudget context.CancelFunc
+		ctx, cancelBudget = context.WithTimeout(ctx, cfg.RequestTimeout)
+		defer cancelBudget()
+	}
+
+	var result deliveryResult
+	var lastErr error
+	var retryAfter time.Duration
+	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+		// Wait before every attempt except the first
+		if attempt > 0 {
+			delay := backoffDelay(cfg.RetryBaseDelay, attempt)
+			if retryAfter > 0 {
+				delay = retryAfter
+			}
+
+			// Don't sleep past the deadline only to fail once we wake up
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return result, fmt.Errorf("deadline exceeded after %d attempt(s): retry delay %s exceeds remaining time (last error: %w)", attempt, delay, lastErr)
+			}
+
+			d.Logger.DebugContext(ctx, "retrying webhook request",
+				slog.String("webhookHost", webhookHost(webhookURL)),
+				slog.Int("attempt", attempt+1),
+				slog.Int64("delayMs", delay.Milliseconds()),
+				slog.String("lastError", lastErr.Error()))
+
+			if err := sleepContext(ctx, delay); err != nil {
+				if err == context.DeadlineExceeded {
+					return result, fmt.Errorf("deadline exceeded after %d attempt(s) (last error: %w)", attempt, lastErr)
+				}
+				return result, fmt.Errorf("gave up after %d attempt(s): %w (last error: %w)", attempt, err, lastErr)
+			}
+		}
+
+		attempted, err := d.sendOnce(ctx, webhookURL, body, contentType)
+		result.Attempts = attempt + 1
+		result.StatusCode = attempted.StatusCode
+		result.MessageID = attempted.MessageID
+		retryAfter = attempted.RetryAfter
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if ctx.Err() == context.DeadlineExceeded {
+			return result, fmt.Errorf("deadline exceeded after %d attempt(s) (last error: %w)", attempt+1, err)
+		}
+		if !attempted.Retryable {
+			return result, err
+		}
+	}
+
+	return result, fmt.Errorf("gave up after %d attempt(s): %w", cfg.MaxRetries+1, lastErr)
+}
+
+// withDeadlineBudget shortens the context deadline by deadlineSafetyMargin.
+// Contexts without a deadline are returned unchanged.
+func withDeadlineBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-deadlineSafetyMargin))
+}
+
+// attemptTimeout returns the timeout for one webhook request:
+// PER_ATTEMPT_TIMEOUT_SECONDS when set, otherwise REQUEST_TIMEOUT_SECONDS
+func attemptTimeout(cfg Config) time.Duration {
+	if cfg.AttemptTimeout > 0 {
+		return cfg.AttemptTimeout
+	}
+	return cfg.RequestTimeout
+}
+
+// sendOnce performs a single webhook request and reports whether a failure is
+// worth retrying, along with any server-requested delay before the next attempt
+func (d *Dispatcher) sendOnce(ctx context.Context, webhookURL string, body []byte, contentType string) (attemptResult, error) {
+	// Bound the request by its attempt timeout; the shared client has no timeout of its own
+	reqCtx, cancel := context.WithTimeout(ctx, attemptTimeout(d.Config))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		reqCtx,
+		d.Config.HTTPMethod,
+		discordWebhookURL(d.Config, webhookURL),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return attemptResult{}, fmt.Errorf("failed to create HTTP request: %w", stripURL(err))
+	}
+	applyHeaders(req, d.Config, contentType)
+	now := time.Now()
+	signRequest(req, d.Config, body, now)
+
+	// SigV4 goes last since it signs the headers set above
+	if err := signSigV4(ctx, req, d.Config, body, now); err != nil {
+		return attemptResult{}, err
+	}
+
+	d.Logger.DebugContext(ctx, "sending webhook request",
+		slog.String("webhookHost", req.URL.Host),
+		slog.String("headers", redactHeaders(req.Header)))
+
+	// Execute HTTP request; network errors and request timeouts are retryable
+	// unless the invocation's own context is done
+	if err := d.acquireSlot(ctx); err != nil {
+		return attemptResult{}, fmt.Errorf("failed to send message to webhook: %w", err)
+	}
+	defer d.releaseSlot()
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return attemptResult{Retryable: ctx.Err() == nil}, fmt.Errorf("failed to send message to webhook: %w", stripURL(err))
+	}
+	defer resp.Body.Close()
+
+	// Failed responses usually explain themselves, e.g. Discord's validation errors
+	accepted := d.Config.AcceptedStatusCodes.accepts(resp.StatusCode)
+	var respBody []byte
+	if !accepted {
+		respBody, _ = io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	}
+
+	// With DISCORD_WAIT the response is the created message, whose ID is logged
+	result := attemptResult{StatusCode: resp.StatusCode}
+	if accepted && d.Config.Platform == platformDiscord && d.Config.DiscordWait {
+		result.MessageID = parseDiscordMessageID(resp.Body)
+	}
+
+	// Drain the body so the connection can be reused by the next attempt
+	io.Copy(io.Discard, resp.Body)
+
+	// Check for a status code accepted by ACCEPTED_STATUS_CODES, 2xx by default
+	if !accepted {
+		result.Retryable = isRetryableStatus(resp.StatusCode)
+
+		// Rate limited responses tell us how long to wait before trying again
+		if resp.StatusCode == http.StatusTooManyRequests {
+			result.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), respBody)
+		}
+		return result, &WebhookStatusError{StatusCode: resp.StatusCode, Body: sanitizeResponseBody(respBody)}
+	}
+
+	return result, nil
+}
+
+// parseRetryAfter extracts the rate limit delay from a Retry-After header
+// (seconds or an HTTP date) or, failing that, a JSON retry_after field in milliseconds
+func parseRetryAfter(header string, body []byte) time.Duration {
+	header = strings.TrimSpace(header)
+	if header != "" {
+		if seconds, err := strconv.ParseFloat(header, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if when, err := http.ParseTime(header); err == nil {
+			if d := time.Until(when); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+
+	var rateLimit struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.Unmarshal(body, &rateLimit); err == nil && rateLimit.RetryAfter > 0 {
+		return time.Duration(rateLimit.RetryAfter * float64(time.Millisecond))
+	}
+
+	return 0
+}
+
+// isRetryableStatus reports whether a response status indicates a transient failure
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// backoffDelay returns the jittered exponential delay before the given retry attempt
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base << (attempt - 1)
+
+	// Pick a random delay in [delay/2, delay) so concurrent retries spread out
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)))
+}
+
+// sleepContext waits for d or returns early with the context error if ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendWithRetryTransientFailures(t *testing.T) {
+	for _, status := range []int{500, 503, 429} {
+		srv := newWebhookServer(t, status, status, 204)
+		cfg := testConfig(t, srv.URL)
+		cfg.MaxRetries = 3
+		d := newTestDispatcher(cfg, srv)
+
+		result, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType)
+		if err != nil {
+			t.Fatalf("status %d: %v", status, err)
+		}
+		if result.Attempts != 3 || len(srv.received()) != 3 {
+			t.Errorf("status %d: %d attempts, %d requests; want 3", status, result.Attempts, len(srv.received()))
+		}
+	}
+}
+
+func TestSendWithRetryClientErrorNotRetried(t *testing.T) {
+	for _, status := range []int{400, 404} {
+		srv := newWebhookServer(t, status)
+		cfg := testConfig(t, srv.URL)
+		cfg.MaxRetries = 3
+		d := newTestDispatcher(cfg, srv)
+
+		result, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType)
+		var statusErr *WebhookStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Fatalf("status %d: error = %v", status, err)
+		}
+		if result.Attempts != 1 || len(srv.received()) != 1 {
+			t.Errorf("status %d: %d attempts, %d requests; want 1", status, result.Attempts, len(srv.received()))
+		}
+	}
+}
+
+func TestSendWithRetryExhausted(t *testing.T) {
+	srv := newWebhookServer(t, 502)
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 2
+	d := newTestDispatcher(cfg, srv)
+
+	_, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType)
+	if err == nil {
+		t.Fatal("want an error once retries are exhausted")
+	}
+	if got := len(srv.received()); got != 3 {
+		t.Errorf("webhook received %d requests, want 3", got)
+	}
+}
+
+func TestSendWithRetryStopsWhenContextCanceled(t *testing.T) {
+	srv := newWebhookServer(t, 503)
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 5
+	cfg.RetryBaseDelay = time.Hour
+	d := newTestDispatcher(cfg, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := d.sendWithRetry(ctx, srv.URL, []byte(`{}`), jsonContentType); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("kept sleeping for %s after cancellation", elapsed)
+	}
+	if got := len(srv.received()); got != 1 {
+		t.Errorf("webhook received %d requests, want 1", got)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt := 1; attempt <= 4; attempt++ {
+		full := base << (attempt - 1)
+		for i := 0; i < 50; i++ {
+			if delay := backoffDelay(base, attempt); delay < full/2 || delay >= full {
+				t.Fatalf("attempt %d delay %s outside [%s, %s)", attempt, delay, full/2, full)
+			}
+		}
+	}
+	if delay := backoffDelay(0, 3); delay != 0 {
+		t.Errorf("zero base delay = %s", delay)
+	}
+}
+
+func TestLoadConfigRetrySettings(t *testing.T) {
+	setenvConfig(t, nil)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxRetries != defaultMaxRetries || cfg.RetryBaseDelay != defaultRetryBaseDelayMS*time.Millisecond {
+		t.Errorf("defaults = %d, %s", cfg.MaxRetries, cfg.RetryBaseDelay)
+	}
+
+	setenvConfig(t, map[string]string{"MAX_RETRIES": "5", "RETRY_BASE_DELAY_MS": "250"})
+	cfg, err = loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxRetries != 5 || cfg.RetryBaseDelay != 250*time.Millisecond {
+		t.Errorf("configured = %d, %s", cfg.MaxRetries, cfg.RetryBaseDelay)
+	}
+
+	for _, env := range []map[string]string{{"MAX_RETRIES": "-1"}, {"MAX_RETRIES": "three"}, {"MAX_RETRIES": "3", "RETRY_BASE_DELAY_MS": "-5"}} {
+		setenvConfig(t, env)
+		if _, err := loadConfig(context.Background()); err == nil {
+			t.Errorf("loadConfig with %v: want an error", env)
+		}
+	}
+}
+
+func TestSendWithRetryHonorsRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var sent []time.Time
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, time.Now())
+		if len(sent) == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 3
+	d := &Dispatcher{Config: cfg, Client: srv.Client(), Logger: newLogger(slogQuiet)}
+
+	result, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Attempts != 2 || len(sent) != 2 {
+		t.Fatalf("%d attempts, %d requests; want 2", result.Attempts, len(sent))
+	}
+	if gap := sent[1].Sub(sent[0]); gap < 2*time.Second {
+		t.Errorf("resent after %s, want at least the 2s Retry-After", gap)
+	}
+}
+
+func TestSendWithRetryRetryAfterPastDeadline(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 3
+	d := &Dispatcher{Config: cfg, Client: srv.Client(), Logger: newLogger(slogQuiet)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := d.sendWithRetry(ctx, srv.URL, []byte(`{}`), jsonContentType); err == nil {
+		t.Fatal("want a deadline error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s for a Retry-After beyond the deadline", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		body   string
+		want   time.Duration
+	}{
+		{"2", "", 2 * time.Second},
+		{"0.5", "", 500 * time.Millisecond},
+		{"", `{"message": "You are being rate limited.", "retry_after": 1500}`, 1500 * time.Millisecond},
+		{"3", `{"retry_after": 1500}`, 3 * time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), "", 0},
+		{"soon", "not json", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header, []byte(tt.body)); got != tt.want {
+			t.Errorf("parseRetryAfter(%q, %q) = %s, want %s", tt.header, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestSendWithRetryShortDeadline(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 3
+	cfg.RequestTimeout = 10 * time.Second
+	d := &Dispatcher{Config: cfg, Client: srv.Client(), Logger: newLogger(slogQuiet)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
+	defer cancel()
+	_, err := d.sendWithRetry(ctx, srv.URL, []byte(`{}`), jsonContentType)
+	if err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Fatalf("err = %v, want a deadline exceeded error", err)
+	}
+	// The safety margin leaves time to report the failure before the invocation ends
+	if ctx.Err() != nil {
+		t.Error("sendWithRetry ran into the invocation deadline")
+	}
+}
+
+func TestWithDeadlineBudget(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	parent, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	ctx, cancelBudget := withDeadlineBudget(parent)
+	defer cancelBudget()
+	if got, _ := ctx.Deadline(); !got.Equal(deadline.Add(-deadlineSafetyMargin)) {
+		t.Errorf("deadline = %v, want %v", got, deadline.Add(-deadlineSafetyMargin))
+	}
+
+	ctx, cancelBudget = withDeadlineBudget(context.Background())
+	defer cancelBudget()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("withDeadlineBudget added a deadline to a context without one")
+	}
+}
+
+func TestSendWithRetryPerAttemptTimeout(t *testing.T) {
+	done := make(chan struct{})
+	var mu sync.Mutex
+	attempts := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		first := attempts == 1
+		mu.Unlock()
+
+		// The first attempt hangs past its timeout; the retry answers at once
+		if first {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 2
+	cfg.RequestTimeout = 5 * time.Second
+	cfg.AttemptTimeout = 200 * time.Millisecond
+	d := &Dispatcher{Config: cfg, Client: srv.Client(), Logger: newLogger(slogQuiet)}
+
+	start := time.Now()
+	result, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType)
+	if err != nil {
+		t.Fatalf("sendWithRetry: %v", err)
+	}
+	if result.Attempts != 2 || result.StatusCode != http.StatusNoContent {
+		t.Errorf("result = %+v, want success on attempt 2", result)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %s; the slow attempt should only cost its own timeout", elapsed)
+	}
+}
+
+func TestSendWithRetryOverallBudget(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	// Every attempt times out, and REQUEST_TIMEOUT_SECONDS caps them all together
+	cfg := testConfig(t, srv.URL)
+	cfg.MaxRetries = 10
+	cfg.RequestTimeout = 500 * time.Millisecond
+	cfg.AttemptTimeout = 200 * time.Millisecond
+	d := &Dispatcher{Config: cfg, Client: srv.Client(), Logger: newLogger(slogQuiet)}
+
+	start := time.Now()
+	if _, err := d.sendWithRetry(context.Background(), srv.URL, []byte(`{}`), jsonContentType); err == nil {
+		t.Fatal("sendWithRetry succeeded against a webhook that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %s, want the retries stopped by the overall budget", elapsed)
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	if got := attemptTimeout(Config{RequestTimeout: 10 * time.Second}); got != 10*time.Second {
+		t.Errorf("attemptTimeout without PER_ATTEMPT_TIMEOUT_SECONDS = %s, want REQUEST_TIMEOUT_SECONDS", got)
+	}
+	if got := attemptTimeout(Config{RequestTimeout: 10 * time.Second, AttemptTimeout: 2 * time.Second}); got != 2*time.Second {
+		t.Errorf("attemptTimeout = %s, want 2s", got)
+	}
+
+	setenvConfig(t, map[string]string{"PER_ATTEMPT_TIMEOUT_SECONDS": "-1"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted a negative PER_ATTEMPT_TIMEOUT_SECONDS")
+	}
+}
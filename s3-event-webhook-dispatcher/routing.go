@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RoutingRule sends the files of one bucket or key prefix to its own webhook,
+// optionally with its own message template and embed color
+type RoutingRule struct {
+	Bucket     string
+	Prefix     string
+	WebhookURL string
+
+	// MessageTemplate is nil and ColorSet false when the rule keeps the defaults
+	MessageTemplate *template.Template
+	Color           int
+	ColorSet        bool
+}
+
+// parseRoutingRules decodes ROUTING_RULES, a JSON array of
+// {"bucket": ..., "prefix": ..., "webhookUrl": ..., "template": ..., "color": ...}
+// objects. Each rule needs a webhook URL and a bucket, a prefix, or both.
+func parseRoutingRules(raw string) ([]RoutingRule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var entries []struct {
+		Bucket     string          `json:"bucket"`
+		Prefix     string          `json:"prefix"`
+		WebhookURL string          `json:"webhookUrl"`
+		Template   string          `json:"template"`
+		Color      json.RawMessage `json:"color"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("invalid ROUTING_RULES: %w", err)
+	}
+
+	rules := make([]RoutingRule, 0, len(entries))
+	for i, entry := range entries {
+		rule := RoutingRule{
+			Bucket:     strings.TrimSpace(entry.Bucket),
+			Prefix:     entry.Prefix,
+			WebhookURL: strings.TrimSpace(entry.WebhookURL),
+		}
+		if rule.Bucket == "" && rule.Prefix == "" {
+			return nil, fmt.Errorf("invalid ROUTING_RULES rule %d: set bucket, prefix, or both", i)
+		}
+		if rule.WebhookURL == "" {
+			return nil, fmt.Errorf("invalid ROUTING_RULES rule %d: webhookUrl is required", i)
+		}
+
+		var err error
+		rule.Color, rule.ColorSet, err = parseJSONColor(entry.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ROUTING_RULES color for rule %d: %w", i, err)
+		}
+
+		if entry.Template != "" {
+			rule.MessageTemplate, err = parseTemplate(fmt.Sprintf("ROUTING_RULES rule %d template", i), entry.Template)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches reports whether the file falls under the rule's bucket and prefix
+func (r RoutingRule) matches(payload FilePayload) bool {
+	if r.Bucket != "" && r.Bucket != payload.Bucket {
+		return false
+	}
+	return strings.HasPrefix(payload.FileName, r.Prefix)
+}
+
+// matchRoute returns the index of the first rule the file matches, or -1 when
+// it goes to the default webhooks
+func matchRoute(cfg Config, payload FilePayload) int {
+	for i, rule := range cfg.RoutingRules {
+		if rule.matches(payload) {
+			return i
+		}
+	}
+	return -1
+}
+
+// routeConfig returns the configuration for delivering the file: the default
+// one, or one sending to the first matching rule's webhook with its overrides
+func routeConfig(cfg Config, payload FilePayload) Config {
+	i := matchRoute(cfg, payload)
+	if i < 0 {
+		return cfg
+	}
+
+	rule := cfg.RoutingRules[i]
+	cfg.WebhookURLs = []string{rule.WebhookURL}
+	cfg.Destinations = nil
+	if rule.MessageTemplate != nil {
+		cfg.MessageTemplate = rule.MessageTemplate
+	}
+	if rule.ColorSet {
+		cfg.EmbedColor = rule.Color
+	}
+	return cfg
+}
+
+// routed returns a Dispatcher sharing d's client and concurrency limit that
+// delivers the file according to ROUTING_RULES
+func (d *Dispatcher) routed(payload FilePayload) *Dispatcher {
+	if len(d.Config.RoutingRules) == 0 {
+		return d
+	}
+	r := *d
+	r.Config = routeConfig(d.Config, payload)
+	return &r
+}
+
+// groupByRoute splits payloads by the rule they match, keeping the order of
+// first appearance, and returns the indexes of each group's payloads
+func groupByRoute(cfg Config, payloads []FilePayload) [][]int {
+	var groups [][]int
+	position := make(map[int]int)
+	for i, payload := range payloads {
+		route := matchRoute(cfg, payload)
+		g, ok := position[route]
+		if !ok {
+			g = len(groups)
+			position[route] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRouteConfig(t *testing.T) {
+	rules, err := parseRoutingRules(`[
+		{"prefix": "logs/", "webhookUrl": "https://logs.example.com/hook", "color": "#95A5A6"},
+		{"bucket": "invoices", "webhookUrl": "https://invoices.example.com/hook", "template": "Invoice {{.FileName}}"},
+		{"bucket": "photos", "webhookUrl": "https://photos.example.com/hook", "color": "random"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, "https://default.example.com/hook")
+	cfg.EmbedColor = 0x3498DB
+	cfg.RoutingRules = rules
+
+	tests := []struct {
+		name    string
+		payload FilePayload
+		url     string
+		color   int
+	}{
+		{"prefix", FilePayload{Bucket: "invoices", FileName: "logs/app.log"}, "https://logs.example.com/hook", 0x95A5A6},
+		{"bucket", FilePayload{Bucket: "invoices", FileName: "2025/may.pdf"}, "https://invoices.example.com/hook", 0x3498DB},
+		{"explicit random", FilePayload{Bucket: "photos", FileName: "cat.png"}, "https://photos.example.com/hook", randomEmbedColor},
+		{"default", FilePayload{Bucket: "other", FileName: "notes.txt"}, "https://default.example.com/hook", 0x3498DB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routed := routeConfig(cfg, tt.payload)
+			if len(routed.WebhookURLs) != 1 || routed.WebhookURLs[0] != tt.url {
+				t.Errorf("webhooks = %v, want %s", routed.WebhookURLs, tt.url)
+			}
+			if routed.EmbedColor != tt.color {
+				t.Errorf("color = %#x, want %#x", routed.EmbedColor, tt.color)
+			}
+		})
+	}
+
+	routed := routeConfig(cfg, FilePayload{Bucket: "invoices", FileName: "may.pdf"})
+	if text, err := renderMessage(routed, FilePayload{FileName: "may.pdf"}); err != nil || text != "Invoice may.pdf" {
+		t.Errorf("rule template rendered %q, %v", text, err)
+	}
+}
+
+func TestParseRoutingRulesInvalid(t *testing.T) {
+	for _, raw := range []string{
+		`[{"webhookUrl": "https://example.com/hook"}]`,
+		`[{"prefix": "logs/"}]`,
+		`[{"prefix": "logs/", "webhookUrl": "https://example.com/hook", "color": "teal"}]`,
+		`{"prefix": "logs/"}`,
+	} {
+		if _, err := parseRoutingRules(raw); err == nil {
+			t.Errorf("parseRoutingRules(%s): want an error", raw)
+		}
+	}
+}
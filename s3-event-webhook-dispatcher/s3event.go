@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3PresignAPI is the subset of the S3 presign client used by the dispatcher
+type s3PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// newS3Presigner creates the S3 presign client; tests replace it with a stub
+var newS3Presigner = func(ctx context.Context) (s3PresignAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewPresignClient(s3.NewFromConfig(awsCfg)), nil
+}
+
+// isS3Event reports whether the raw invocation payload is an S3 event notification
+func isS3Event(raw json.RawMessage) bool {
+	return recordsEventSource(raw) == "aws:s3"
+}
+
+// handleS3 concurrently dispatches a message for every record of an S3 event notification
+func (d *Dispatcher) handleS3(ctx context.Context, notification events.S3Event) error {
+	errs := make([]error, len(notification.Records))
+	forEachConcurrently(len(notification.Records), func(i int) {
+		payload, err := payloadFromS3Record(ctx, d.Config, notification.Records[i])
+		if err == nil {
+			err = d.Dispatch(ctx, payload)
+		}
+		errs[i] = err
+	})
+
+	var failures multiError
+	for i, err := range errs {
+		if err != nil {
+			record := notification.Records[i]
+			failures = append(failures, fmt.Errorf("%s/%s: %w", record.S3.Bucket.Name, record.S3.Object.Key, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d S3 record(s) failed: %w", len(failures), len(notification.Records), failures)
+	}
+	return nil
+}
+
+// payloadFromS3Record synthesizes the payload the Link Generator would have
+// published, presigning a download URL when GENERATE_PRESIGNED_URL is enabled
+func payloadFromS3Record(ctx context.Context, cfg Config, record events.S3EventRecord) (FilePayload, error) {
+	key := decodeS3Key(record.S3.Object.Key)
+	payload := FilePayload{
+		FileName:  key,
+		Bucket:    record.S3.Bucket.Name,
+		Timestamp: record.EventTime.UTC().Format(time.RFC3339),
+		FileSize:  record.S3.Object.Size,
+		EventType: eventTypeCreated,
+
+		fileSizeSet: true,
+	}
+
+	// Removed objects can't be downloaded, so skip presigning
+	if strings.HasPrefix(record.EventName, "ObjectRemoved:") {
+		payload.EventType = eventTypeDeleted
+		return payload, nil
+	}
+
+	if !cfg.GeneratePresignedURL {
+		payload.unlinked = true
+		return payload, nil
+	}
+
+	presigner, err := newS3Presigner(ctx)
+	if err != nil {
+		return FilePayload{}, fmt.Errorf("failed to create S3 presign client: %w", err)
+	}
+
+	// Presign the real object key; the event key is URL-encoded
+	req, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(record.S3.Bucket.Name),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(cfg.URLExpiration))
+	if err != nil {
+		return FilePayload{}, fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	payload.FileURL = req.URL
+	payload.ExpirationTime = formatExpiration(cfg.URLExpiration)
+	return payload, nil
+}
+
+// decodeS3Key reverses the form encoding S3 applies to keys in event
+// notifications, where spaces become "+" and other special characters "%XX".
+// Keys that aren't validly encoded are returned unchanged.
+func decodeS3Key(key string) string {
+	decoded, err := url.QueryUnescape(key)
+	if err != nil {
+		return key
+	}
+	return decoded
+}
+
+// formatExpiration renders a duration the way the Link Generator does (e.g. "30 minutes", "1 day")
+func formatExpiration(d time.Duration) string {
+	seconds := int(d / time.Second)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case seconds < 60:
+		return plural(seconds, "second")
+	case seconds < 3600:
+		return plural(seconds/60, "minute")
+	case seconds < 86400:
+		return plural(seconds/3600, "hour")
+	default:
+		return plural(seconds/86400, "day")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakePresigner presigns by building an example URL carrying the expiry
+type fakePresigner struct {
+	keys []string
+}
+
+func (f *fakePresigner) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	var opts s3.PresignOptions
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+	key := aws.ToString(params.Key)
+	f.keys = append(f.keys, key)
+	u := url.URL{
+		Scheme:   "https",
+		Host:     aws.ToString(params.Bucket) + ".s3.amazonaws.com",
+		Path:     "/" + key,
+		RawQuery: url.Values{"X-Amz-Expires": {opts.Expires.String()}, "X-Amz-Signature": {"abc123"}}.Encode(),
+	}
+	return &v4.PresignedHTTPRequest{URL: u.String(), Method: "GET"}, nil
+}
+
+// stubPresigner makes newS3Presigner return presigner until the test ends
+func stubPresigner(t *testing.T, presigner *fakePresigner) {
+	t.Helper()
+	original := newS3Presigner
+	newS3Presigner = func(ctx context.Context) (s3PresignAPI, error) { return presigner, nil }
+	t.Cleanup(func() { newS3Presigner = original })
+}
+
+// s3PutRecord returns an ObjectCreated:Put record for key in bucket
+func s3PutRecord(bucket, key string, size int64) events.S3EventRecord {
+	record := events.S3EventRecord{
+		EventSource: "aws:s3",
+		EventName:   "ObjectCreated:Put",
+		EventTime:   time.Date(2025, 5, 17, 10, 0, 0, 0, time.UTC),
+	}
+	record.S3.Bucket.Name = bucket
+	record.S3.Object.Key = key
+	record.S3.Object.Size = size
+	return record
+}
+
+func TestHandleS3PutRecord(t *testing.T) {
+	presigner := &fakePresigner{}
+	stubPresigner(t, presigner)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.GeneratePresignedURL = true
+	cfg.URLExpiration = time.Hour
+	d := newTestDispatcher(cfg, srv)
+
+	notification := events.S3Event{Records: []events.S3EventRecord{s3PutRecord("uploads", "reports/q1.pdf", 4096)}}
+	if err := d.handleS3(context.Background(), notification); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := srv.received()
+	if len(requests) != 1 {
+		t.Fatalf("webhook received %d requests, want 1", len(requests))
+	}
+	var message DiscordMessage
+	if err := json.Unmarshal(requests[0].Body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if len(message.Embeds) != 1 {
+		t.Fatalf("want one embed: %s", requests[0].Body)
+	}
+	description := message.Embeds[0].Description
+	if !strings.Contains(description, "reports/q1.pdf") || !strings.Contains(description, "https://uploads.s3.amazonaws.com/reports/q1.pdf?") {
+		t.Errorf("description %q doesn't name the object and link its presigned URL", description)
+	}
+	if message.Embeds[0].Timestamp != "2025-05-17T10:00:00Z" {
+		t.Errorf("timestamp = %q, want the event time", message.Embeds[0].Timestamp)
+	}
+	if len(presigner.keys) != 1 || presigner.keys[0] != "reports/q1.pdf" {
+		t.Errorf("presigned keys %v", presigner.keys)
+	}
+}
+
+func TestPayloadFromS3RecordPresignedURL(t *testing.T) {
+	stubPresigner(t, &fakePresigner{})
+	cfg := Config{GeneratePresignedURL: true, URLExpiration: 30 * time.Minute}
+
+	payload, err := payloadFromS3Record(context.Background(), cfg, s3PutRecord("uploads", "a.pdf", 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(payload.FileURL)
+	if err != nil || u.Query().Get("X-Amz-Expires") != "30m0s" {
+		t.Errorf("FileURL = %q, want presigned for 30 minutes", payload.FileURL)
+	}
+	if payload.ExpirationTime != "30 minutes" || payload.Bucket != "uploads" || payload.FileSize != 10 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestFormatExpiration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		time.Second:        "1 second",
+		45 * time.Second:   "45 seconds",
+		time.Minute:        "1 minute",
+		30 * time.Minute:   "30 minutes",
+		2 * time.Hour:      "2 hours",
+		24 * time.Hour:     "1 day",
+		7 * 24 * time.Hour: "7 days",
+	} {
+		if got := formatExpiration(d); got != want {
+			t.Errorf("formatExpiration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestDecodeS3Key(t *testing.T) {
+	for key, want := range map[string]string{
+		"my+report.pdf":                  "my report.pdf",
+		"folder%2Ffile.txt":              "folder/file.txt",
+		"r%C3%A9sum%C3%A9+%E6%97%A5.pdf": "résumé 日.pdf",
+		"100%25+done%2B.txt":             "100% done+.txt",
+		"bad%zzkey":                      "bad%zzkey",
+	} {
+		if got := decodeS3Key(key); got != want {
+			t.Errorf("decodeS3Key(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestPayloadFromS3RecordDecodesKey(t *testing.T) {
+	payload, err := payloadFromS3Record(context.Background(), Config{}, s3PutRecord("uploads", "my+report.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.FileName != "my report.pdf" {
+		t.Errorf("FileName = %q, want %q", payload.FileName, "my report.pdf")
+	}
+}
+
+func TestParseDetailDecodesFileNames(t *testing.T) {
+	detail := json.RawMessage(`{"fileName": "folder%2Fmy+report.pdf", "fileUrl": "https://example.com/a"}`)
+
+	payloads, err := parseDetail(Config{DecodeFileNames: true}, detail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payloads[0].FileName != "folder/my report.pdf" {
+		t.Errorf("FileName = %q with DECODE_FILE_NAMES", payloads[0].FileName)
+	}
+
+	payloads, err = parseDetail(Config{}, detail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payloads[0].FileName != "folder%2Fmy+report.pdf" {
+		t.Errorf("FileName = %q, want it unchanged by default", payloads[0].FileName)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// secretsManagerAPI is the subset of the Secrets Manager client used by the dispatcher
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// newSecretsManagerClient creates the Secrets Manager client; tests replace it with a stub
+var newSecretsManagerClient = func(ctx context.Context) (secretsManagerAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return secretsmanager.NewFromConfig(awsCfg), nil
+}
+
+// valueCache remembers a remotely resolved value for the lifetime of the container.
+// Failed lookups are not cached so the next invocation tries again.
+type valueCache struct {
+	sync.Mutex
+	key   string
+	value string
+}
+
+// get returns the cached value for key, calling fetch only when nothing is cached for it
+func (c *valueCache) get(key string, fetch func() (string, error)) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.key == key && c.value != "" {
+		return c.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	c.key = key
+	c.value = value
+	return value, nil
+}
+
+// webhookSecretCache holds the webhook URL read from Secrets Manager
+var webhookSecretCache valueCache
+
+// resolveWebhookSecret returns the webhook URL stored in the given secret,
+// fetching it only on the first call for that ARN
+func resolveWebhookSecret(ctx context.Context, arn string) (string, error) {
+	return webhookSecretCache.get(arn, func() (string, error) {
+		client, err := newSecretsManagerClient(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create Secrets Manager client: %w", err)
+		}
+
+		out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(arn),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch webhook URL from secret %s: %w", arn, err)
+		}
+
+		value := strings.TrimSpace(aws.ToString(out.SecretString))
+		if value == "" {
+			return "", fmt.Errorf("secret %s has no string value", arn)
+		}
+		return value, nil
+	})
+}
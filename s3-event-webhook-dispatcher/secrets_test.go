@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// fakeSecretsManager returns the secret string for one ARN, or fails with err
+type fakeSecretsManager struct {
+	arn   string
+	value string
+	err   error
+	calls int
+}
+
+func (f *fakeSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if aws.ToString(params.SecretId) != f.arn {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(f.value)}, nil
+}
+
+// stubSecretsManager makes newSecretsManagerClient return client and empties
+// the secret cache until the test ends
+func stubSecretsManager(t *testing.T, client *fakeSecretsManager) {
+	t.Helper()
+	original := newSecretsManagerClient
+	newSecretsManagerClient = func(ctx context.Context) (secretsManagerAPI, error) { return client, nil }
+	webhookSecretCache = valueCache{}
+	t.Cleanup(func() {
+		newSecretsManagerClient = original
+		webhookSecretCache = valueCache{}
+	})
+}
+
+const testSecretARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:webhook-AbCdEf"
+
+func TestLoadConfigWebhookSecret(t *testing.T) {
+	client := &fakeSecretsManager{arn: testSecretARN, value: "https://discord.com/api/webhooks/3/from-secret\n"}
+	stubSecretsManager(t, client)
+	stubSSM(t, &fakeSSM{err: errors.New("SSM should not be called")})
+	setenvConfig(t, map[string]string{
+		"WEBHOOK_URL":           "https://discord.com/api/webhooks/1/plain",
+		"WEBHOOK_SECRET_ARN":    testSecretARN,
+		"WEBHOOK_URL_SSM_PARAM": "/dispatcher/webhook",
+	})
+
+	for i := 0; i < 2; i++ {
+		cfg, err := loadConfig(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.WebhookURLs) != 1 || cfg.WebhookURLs[0] != "https://discord.com/api/webhooks/3/from-secret" {
+			t.Fatalf("webhooks = %v, want the secret's URL", cfg.WebhookURLs)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("GetSecretValue called %d times, want 1 across warm invocations", client.calls)
+	}
+}
+
+func TestLoadConfigWebhookSecretFailureNotCached(t *testing.T) {
+	client := &fakeSecretsManager{arn: testSecretARN, err: errors.New("AccessDeniedException")}
+	stubSecretsManager(t, client)
+	setenvConfig(t, map[string]string{"WEBHOOK_SECRET_ARN": testSecretARN})
+
+	if _, err := loadConfig(context.Background()); !errors.Is(err, ErrWebhookLookup) {
+		t.Fatalf("loadConfig error = %v, want ErrWebhookLookup", err)
+	}
+
+	client.err, client.value = nil, "https://discord.com/api/webhooks/3/from-secret"
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WebhookURLs[0] != "https://discord.com/api/webhooks/3/from-secret" || client.calls != 2 {
+		t.Errorf("webhooks = %v after %d calls", cfg.WebhookURLs, client.calls)
+	}
+}
+
+func TestLoadConfigEmptySecret(t *testing.T) {
+	stubSecretsManager(t, &fakeSecretsManager{arn: testSecretARN, value: "  "})
+	setenvConfig(t, map[string]string{"WEBHOOK_SECRET_ARN": testSecretARN})
+
+	if _, err := loadConfig(context.Background()); !errors.Is(err, ErrWebhookLookup) {
+		t.Errorf("loadConfig error = %v, want ErrWebhookLookup", err)
+	}
+}
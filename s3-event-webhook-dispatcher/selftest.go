@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// selfTestPath is the HTTP path that runs the self-test instead of a dispatch
+const selfTestPath = "/selftest"
+
+// selfTestFileName labels the synthetic file so nobody mistakes the message for a real upload
+const selfTestFileName = "[TEST] dispatcher online"
+
+// selfTestResult is the JSON result of a self-test invocation
+type selfTestResult struct {
+	Status   string   `json:"status"`
+	Platform string   `json:"platform,omitempty"`
+	Webhooks int      `json:"webhooks"`
+	Sent     bool     `json:"sent"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// selfTestRequest reports whether the raw invocation payload asks for a
+// self-test, either {"selfTest": true} or an HTTP request to /selftest, and
+// whether it should send the synthetic message; over HTTP only a POST sends
+func selfTestRequest(raw json.RawMessage) (send, overHTTP, ok bool) {
+	if req, isHTTP := parseHTTPRequest(raw); isHTTP {
+		return req.Method == http.MethodPost, true, req.Path == selfTestPath
+	}
+
+	var probe struct {
+		SelfTest bool `json:"selfTest"`
+		Send     bool `json:"send"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil || !probe.SelfTest {
+		return false, false, false
+	}
+	return probe.Send, false, true
+}
+
+// runSelfTest loads and validates the configuration and, when send is set,
+// delivers a synthetic file to every webhook, so a deployment can be smoke
+// tested without a real S3 event. Sending over HTTP needs HTTP_INVOKE_ENABLED
+// like any other HTTP dispatch. Failures are reported in the result rather
+// than as an invocation error.
+func runSelfTest(ctx context.Context, send, overHTTP bool) selfTestResult {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return selfTestResult{Status: "error", Error: err.Error()}
+	}
+
+	result := selfTestResult{
+		Status:   "ok",
+		Platform: cfg.Platform,
+		Webhooks: len(cfg.WebhookURLs) + len(cfg.Destinations),
+		Warnings: configWarnings(cfg),
+	}
+	if !send {
+		return result
+	}
+	if overHTTP && !cfg.HTTPInvokeEnabled {
+		result.Status, result.Error = "error", "sending over HTTP is disabled; set HTTP_INVOKE_ENABLED=true to POST /selftest"
+		return result
+	}
+
+	d := NewDispatcher(cfg)
+	payload := selfTestPayload(time.Now())
+	ctx = withDispatchIDs(ctx, dispatchIDs{RequestID: newRequestID()})
+	if err := d.deliverAll(ctx, payload); err != nil {
+		result.Status, result.Error = "error", err.Error()
+		return result
+	}
+	result.Sent = !cfg.DryRun
+	return result
+}
+
+// selfTestPayload builds the synthetic file sent by a self-test
+func selfTestPayload(now time.Time) FilePayload {
+	return FilePayload{
+		FileName:       selfTestFileName,
+		FileURL:        "https://example.com/dispatcher-self-test",
+		Bucket:         "self-test",
+		ExpirationTime: "n/a",
+		Timestamp:      now.UTC().Format(time.RFC3339),
+		EventType:      "self-test",
+		EventID:        "self-test-" + newRequestID(),
+	}
+}
+
+// selfTestResponse wraps a self-test result as the JSON response to an HTTP invocation
+func selfTestResponse(result selfTestResult) events.LambdaFunctionURLResponse {
+	status := http.StatusOK
+	if result.Status != "ok" {
+		status = http.StatusInternalServerError
+	}
+	body, _ := json.Marshal(result)
+	return events.LambdaFunctionURLResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": jsonContentType},
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// setenvSelfTest points the configuration at srv, trusting its certificate,
+// and keeps the self-test's dispatch logs out of the test output
+func setenvSelfTest(t *testing.T, srv *webhookServer, env map[string]string) {
+	t.Helper()
+	resetHTTPClient(t)
+	captureLogs(t)
+	if env == nil {
+		env = map[string]string{}
+	}
+	env["WEBHOOK_URL"] = srv.URL
+	env["ALLOW_PRIVATE_TARGETS"] = "true"
+	env["CA_CERT_PEM"] = serverCAPEM(srv.Server)
+	setenvConfig(t, env)
+}
+
+func TestSelfTestPayload(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	payload := selfTestPayload(now)
+	if !strings.Contains(payload.FileName, "[TEST]") || payload.EventType != "self-test" || payload.Bucket != "self-test" {
+		t.Errorf("payload = %+v, want it clearly labeled as a test", payload)
+	}
+	if payload.Timestamp != "2024-05-01T12:00:00Z" || payload.FileURL == "" {
+		t.Errorf("timestamp = %q, fileUrl = %q", payload.Timestamp, payload.FileURL)
+	}
+	if other := selfTestPayload(now); other.EventID == payload.EventID || !strings.HasPrefix(payload.EventID, "self-test-") {
+		t.Errorf("event IDs %q and %q, want a fresh self-test- ID each run", payload.EventID, other.EventID)
+	}
+}
+
+func TestHandlerSelfTestSends(t *testing.T) {
+	srv := newWebhookServer(t)
+	setenvSelfTest(t, srv, nil)
+
+	response, err := Handler(context.Background(), json.RawMessage(`{"selfTest": true, "send": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := response.(selfTestResult)
+	if result.Status != "ok" || !result.Sent || result.Webhooks != 1 || result.Platform != platformDiscord {
+		t.Errorf("result = %+v, want ok and sent to the one webhook", result)
+	}
+
+	requests := srv.received()
+	if len(requests) != 1 {
+		t.Fatalf("webhook received %d requests, want 1", len(requests))
+	}
+	if description := decodeDiscordMessage(t, requests[0].Body).Embeds[0].Description; !strings.Contains(description, selfTestFileName) {
+		t.Errorf("description = %q, want the synthetic test file", description)
+	}
+}
+
+func TestHandlerSelfTestValidatesOnly(t *testing.T) {
+	srv := newWebhookServer(t)
+	setenvSelfTest(t, srv, nil)
+
+	response, err := Handler(context.Background(), json.RawMessage(`{"selfTest": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := response.(selfTestResult); result.Status != "ok" || result.Sent {
+		t.Errorf("result = %+v, want ok without sending", result)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("webhook received %d requests, want none", n)
+	}
+}
+
+func TestHandlerSelfTestReportsBrokenConfig(t *testing.T) {
+	setenvConfig(t, map[string]string{"MAX_RETRIES": "many"})
+	response, err := Handler(context.Background(), json.RawMessage(`{"selfTest": true, "send": true}`))
+	if err != nil {
+		t.Fatalf("a failed self-test returned an invocation error: %v", err)
+	}
+	if result := response.(selfTestResult); result.Status != "error" || result.Error == "" {
+		t.Errorf("result = %+v, want the configuration error", result)
+	}
+}
+
+func TestHandlerSelfTestOverHTTP(t *testing.T) {
+	srv := newWebhookServer(t)
+	setenvSelfTest(t, srv, nil)
+
+	// GET validates only, and POST can't send unless HTTP_INVOKE_ENABLED is on
+	for method, wantStatus := range map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusInternalServerError} {
+		response, err := Handler(context.Background(), functionURLRequest(t, method, selfTestPath, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := response.(events.LambdaFunctionURLResponse).StatusCode; got != wantStatus {
+			t.Errorf("%s %s: status %d, want %d", method, selfTestPath, got, wantStatus)
+		}
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("webhook received %d requests, want none", n)
+	}
+
+	setenvSelfTest(t, srv, map[string]string{"HTTP_INVOKE_ENABLED": "true"})
+	response, err := Handler(context.Background(), functionURLRequest(t, http.MethodPost, selfTestPath, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.(events.LambdaFunctionURLResponse); got.StatusCode != http.StatusOK || !strings.Contains(got.Body, `"sent":true`) {
+		t.Errorf("POST %s = %d %s, want the synthetic message sent", selfTestPath, got.StatusCode, got.Body)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("webhook received %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// shortenerTimeout bounds each call to SHORTENER_URL so a slow shortener only
+// delays the message instead of holding up the dispatch
+const shortenerTimeout = 3 * time.Second
+
+// urlShortener replaces a long link with a short one
+type urlShortener interface {
+	Shorten(ctx context.Context, longURL string) (string, error)
+}
+
+// newURLShortener creates the shortener for SHORTENER_URL; tests replace it with a stub
+var newURLShortener = func(cfg Config, client *http.Client) urlShortener {
+	return httpShortener{endpoint: cfg.ShortenerURL, token: cfg.ShortenerToken, userAgent: cfg.UserAgent, client: client}
+}
+
+// httpShortener calls a shortener API that takes {"url": ...} and answers
+// with the short link as JSON (shortUrl, short_url or link) or plain text
+type httpShortener struct {
+	endpoint  string
+	token     string
+	userAgent string
+	client    *http.Client
+}
+
+// Shorten posts the long URL to the shortener and returns the short link
+func (s httpShortener) Shorten(ctx context.Context, longURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, shortenerTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]string{"url": longURL})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal shortener request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create shortener request: %w", stripURL(err))
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+	if s.userAgent != "" {
+		req.Header.Set("User-Agent", s.userAgent)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call shortener: %w", stripURL(err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read shortener response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("shortener returned status code %d", resp.StatusCode)
+	}
+
+	short := parseShortURL(respBody)
+	if !isHTTPURL(short) {
+		return "", fmt.Errorf("shortener response has no http(s) link")
+	}
+	return short, nil
+}
+
+// parseShortURL extracts the short link from a shortener response body
+func parseShortURL(body []byte) string {
+	var reply struct {
+		ShortURL      string `json:"shortUrl"`
+		ShortURLSnake string `json:"short_url"`
+		Link          string `json:"link"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+	for _, candidate := range []string{reply.ShortURL, reply.ShortURLSnake, reply.Link} {
+		if candidate != "" {
+			return strings.TrimSpace(candidate)
+		}
+	}
+	return ""
+}
+
+// shortenLink replaces the payload's link with a short one when SHORTEN_URL is
+// on. A failed shortener call is logged and the full link kept, so shortening
+// never fails a dispatch.
+func (d *Dispatcher) shortenLink(ctx context.Context, payload FilePayload) FilePayload {
+	if !d.Config.ShortenURL || payload.FileURL == "" {
+		return payload
+	}
+
+	short, err := newURLShortener(d.Config, d.Client).Shorten(ctx, payload.FileURL)
+	if err != nil {
+		d.Logger.WarnContext(ctx, "sending full link; URL shortening failed",
+			slog.String("fileName", payload.FileName),
+			slog.String("error", err.Error()))
+		return payload
+	}
+	payload.FileURL = short
+	return payload
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubShortener answers every Shorten call with short and err
+type stubShortener struct {
+	short string
+	err   error
+	calls []string
+}
+
+func (s *stubShortener) Shorten(ctx context.Context, longURL string) (string, error) {
+	s.calls = append(s.calls, longURL)
+	return s.short, s.err
+}
+
+// useShortener makes newURLShortener return shortener until the test ends
+func useShortener(t *testing.T, shortener urlShortener) {
+	t.Helper()
+	original := newURLShortener
+	newURLShortener = func(Config, *http.Client) urlShortener { return shortener }
+	t.Cleanup(func() { newURLShortener = original })
+}
+
+const longPresignedURL = "https://invoices.s3.amazonaws.com/report.pdf?X-Amz-Signature=abcdef0123456789"
+
+func TestDispatchShortensLink(t *testing.T) {
+	shortener := &stubShortener{short: "https://sho.rt/x1"}
+	useShortener(t, shortener)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.ShortenURL = true
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "report.pdf", FileURL: longPresignedURL}); err != nil {
+		t.Fatal(err)
+	}
+	if len(shortener.calls) != 1 || shortener.calls[0] != longPresignedURL {
+		t.Errorf("shortener calls = %q, want the presigned URL once", shortener.calls)
+	}
+	body := string(srv.received()[0].Body)
+	if !strings.Contains(body, "https://sho.rt/x1") || strings.Contains(body, "X-Amz-Signature") {
+		t.Errorf("body = %s, want only the short link", body)
+	}
+}
+
+func TestDispatchShortenerFailureKeepsFullLink(t *testing.T) {
+	useShortener(t, &stubShortener{err: errors.New("shortener unavailable")})
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.ShortenURL = true
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "report.pdf", FileURL: longPresignedURL}); err != nil {
+		t.Fatalf("a failed shortener call failed the dispatch: %v", err)
+	}
+	if body := string(srv.received()[0].Body); !strings.Contains(body, "X-Amz-Signature=abcdef0123456789") {
+		t.Errorf("body = %s, want the full link as a fallback", body)
+	}
+}
+
+func TestDispatchShortenURLOff(t *testing.T) {
+	shortener := &stubShortener{short: "https://sho.rt/x1"}
+	useShortener(t, shortener)
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "report.pdf", FileURL: longPresignedURL}); err != nil {
+		t.Fatal(err)
+	}
+	if len(shortener.calls) != 0 {
+		t.Errorf("shortener called %d times with SHORTEN_URL off", len(shortener.calls))
+	}
+}
+
+func TestHTTPShortener(t *testing.T) {
+	var request struct {
+		Auth string
+		Body map[string]string
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request.Auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &request.Body)
+		w.Write([]byte(`{"short_url": "https://sho.rt/abc"}`))
+	}))
+	defer srv.Close()
+
+	shortener := httpShortener{endpoint: srv.URL, token: "s3cr3t", client: srv.Client()}
+	short, err := shortener.Shorten(context.Background(), longPresignedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short != "https://sho.rt/abc" {
+		t.Errorf("short link = %q, want https://sho.rt/abc", short)
+	}
+	if request.Auth != "Bearer s3cr3t" || request.Body["url"] != longPresignedURL {
+		t.Errorf("shortener received Authorization %q and body %v", request.Auth, request.Body)
+	}
+}
+
+func TestHTTPShortenerErrors(t *testing.T) {
+	for name, handler := range map[string]http.HandlerFunc{
+		"status":  func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTooManyRequests) },
+		"no link": func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"error": "quota"}`)) },
+		"text":    func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("not a link")) },
+	} {
+		srv := httptest.NewServer(handler)
+		shortener := httpShortener{endpoint: srv.URL, client: srv.Client()}
+		if short, err := shortener.Shorten(context.Background(), longPresignedURL); err == nil {
+			t.Errorf("%s: Shorten = %q, want an error", name, short)
+		}
+		srv.Close()
+	}
+}
+
+func TestParseShortURL(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"shortUrl": "https://sho.rt/a"}`:  "https://sho.rt/a",
+		`{"short_url": "https://sho.rt/b"}`: "https://sho.rt/b",
+		`{"link": " https://sho.rt/c "}`:    "https://sho.rt/c",
+		"https://sho.rt/d\n":                "https://sho.rt/d",
+		`{"id": "e"}`:                       "",
+	} {
+		if got := parseShortURL([]byte(body)); got != want {
+			t.Errorf("parseShortURL(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signature settings applied when WEBHOOK_SIGNING_SECRET is set
+const (
+	defaultSignatureHeader   = "X-Signature-256"
+	signatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+// signRequest adds an HMAC-SHA256 signature of the exact body bytes to the
+// request. With SIGNATURE_INCLUDE_TIMESTAMP the signed value is
+// "<unix seconds>.<body>" and the timestamp is sent alongside so receivers
+// can reject replays.
+func signRequest(req *http.Request, cfg Config, body []byte, now time.Time) {
+	if cfg.SigningSecret == "" {
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(cfg.SigningSecret))
+	if cfg.SignatureIncludeTimestamp {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		mac.Write([]byte(ts + "."))
+		req.Header.Set(signatureTimestampHeader, ts)
+	}
+	mac.Write(body)
+
+	req.Header.Set(cfg.SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+}
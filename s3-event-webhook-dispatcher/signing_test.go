@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// hmacHex returns the hex HMAC-SHA256 of message under secret
+func hmacHex(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSendSignsBody(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.SigningSecret = "s3cr3t"
+	cfg.SignatureHeader = defaultSignatureHeader
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Dispatch(context.Background(), FilePayload{FileName: "a.pdf", FileURL: "https://example.com/a.pdf"}); err != nil {
+		t.Fatal(err)
+	}
+	req := srv.received()[0]
+	if got, want := req.Header.Get(defaultSignatureHeader), hmacHex("s3cr3t", string(req.Body)); got != want {
+		t.Errorf("%s = %q, want %q", defaultSignatureHeader, got, want)
+	}
+	if ts := req.Header.Get(signatureTimestampHeader); ts != "" {
+		t.Errorf("%s = %q without SIGNATURE_INCLUDE_TIMESTAMP", signatureTimestampHeader, ts)
+	}
+}
+
+func TestSignRequestWithTimestamp(t *testing.T) {
+	cfg := Config{SigningSecret: "s3cr3t", SignatureHeader: "X-Hub-Signature", SignatureIncludeTimestamp: true}
+	body := []byte(`{"content":"hello"}`)
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+
+	signRequest(req, cfg, body, time.Unix(1747476000, 0))
+	if ts := req.Header.Get(signatureTimestampHeader); ts != "1747476000" {
+		t.Errorf("%s = %q", signatureTimestampHeader, ts)
+	}
+	if got, want := req.Header.Get("X-Hub-Signature"), hmacHex("s3cr3t", "1747476000."+string(body)); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestSignRequestWithoutSecret(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	signRequest(req, Config{SignatureHeader: defaultSignatureHeader}, []byte("{}"), time.Now())
+	if len(req.Header) != 0 {
+		t.Errorf("headers set without a secret: %v", req.Header)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// stubSigV4Credentials signs with static test credentials until the test ends
+func stubSigV4Credentials(t *testing.T) {
+	t.Helper()
+	old := newSigV4Credentials
+	newSigV4Credentials = func(context.Context) (aws.CredentialsProvider, error) {
+		return credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "SECRETEXAMPLE", ""), nil
+	}
+	reset := func() {
+		sigV4CredentialsCache.Lock()
+		sigV4CredentialsCache.provider = nil
+		sigV4CredentialsCache.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		newSigV4Credentials = old
+		reset()
+	})
+}
+
+// signedAuthorization signs a request carrying body and returns its Authorization header
+func signedAuthorization(t *testing.T, target string, cfg Config, body string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if err := signSigV4(context.Background(), req, cfg, []byte(body), time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	return req.Header.Get("Authorization")
+}
+
+func TestSignSigV4(t *testing.T) {
+	stubSigV4Credentials(t)
+	cfg := Config{SigV4Service: "execute-api", SigV4Region: "us-east-1"}
+	target := "https://abc123.execute-api.eu-west-1.amazonaws.com/prod/notify"
+
+	auth := signedAuthorization(t, target, cfg, `{"a":1}`)
+	// The region in the host wins over AWS_REGION
+	if want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20231114/eu-west-1/execute-api/aws4_request"; !strings.HasPrefix(auth, want) {
+		t.Errorf("Authorization = %q, want prefix %q", auth, want)
+	}
+	if !strings.Contains(auth, "SignedHeaders=") || !strings.Contains(auth, "Signature=") {
+		t.Errorf("Authorization = %q, want signed headers and a signature", auth)
+	}
+
+	// The signature covers the body bytes
+	if other := signedAuthorization(t, target, cfg, `{"a":2}`); other == auth {
+		t.Error("signature unchanged for a different body")
+	}
+}
+
+func TestSignSigV4RegionFallback(t *testing.T) {
+	stubSigV4Credentials(t)
+
+	auth := signedAuthorization(t, "https://notify.example.com/hook", Config{SigV4Service: "execute-api", SigV4Region: "ap-south-1"}, `{}`)
+	if !strings.Contains(auth, "/ap-south-1/execute-api/") {
+		t.Errorf("Authorization = %q, want the AWS_REGION region", auth)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://notify.example.com/hook", nil)
+	if err := signSigV4(context.Background(), req, Config{SigV4Service: "execute-api"}, nil, time.Now()); err == nil {
+		t.Error("signSigV4 succeeded without any region")
+	}
+}
+
+func TestSendSignsSigV4(t *testing.T) {
+	stubSigV4Credentials(t)
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.SigV4Service = "execute-api"
+	cfg.SigV4Region = "eu-west-1"
+	d := newTestDispatcher(cfg, srv)
+
+	if err := d.Send(context.Background(), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	request := srv.received()[0]
+	if auth := request.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		t.Errorf("Authorization = %q, want a SigV4 signature", auth)
+	}
+	if request.Header.Get("X-Amz-Date") == "" {
+		t.Error("request has no X-Amz-Date header")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SlackAttachment represents a legacy Slack message attachment
+type SlackAttachment struct {
+	Color     string `json:"color"`
+	Title     string `json:"title,omitempty"`
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text"`
+	Footer    string `json:"footer"`
+	Timestamp int64  `json:"ts"`
+}
+
+// maxSlackHeaderChars is the longest plain text a Block Kit header block accepts
+const maxSlackHeaderChars = 150
+
+// maxSlackSectionChars is the longest text a Block Kit section block accepts
+const maxSlackSectionChars = 3000
+
+// SlackText represents a Block Kit text object, either plain_text or mrkdwn
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackButton represents a Block Kit button that opens a URL
+type SlackButton struct {
+	Type string    `json:"type"`
+	Text SlackText `json:"text"`
+	URL  string    `json:"url"`
+}
+
+// SlackBlock represents one Block Kit layout block; only the fields of its type are set
+type SlackBlock struct {
+	Type     string        `json:"type"`
+	Text     *SlackText    `json:"text,omitempty"`
+	Fields   []SlackText   `json:"fields,omitempty"`
+	Elements []SlackButton `json:"elements,omitempty"`
+}
+
+// SlackMessage represents the full payload sent to a Slack incoming webhook
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+	Blocks      []SlackBlock      `json:"blocks,omitempty"`
+}
+
+// slackEscaper escapes the characters Slack treats as control sequences in message text
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// buildSlackMessage formats the payload as a Slack incoming webhook message
+// with one attachment, or with Block Kit blocks when SLACK_BLOCKS is set
+func buildSlackMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	// Slack uses mrkdwn: single asterisks for bold and <url|text> for links
+	text, err := renderMessage(cfg, escapePayloadText(payload, slackEscaper))
+	if err != nil {
+		return nil, err
+	}
+
+	title, err := renderTitle(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	footer, err := renderFooter(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SlackBlocks {
+		return marshalSlackMessage(SlackMessage{
+			Text:   eventSummary(payload),
+			Blocks: slackBlocks(title, text, payload),
+		})
+	}
+
+	message := SlackMessage{
+		Text: eventSummary(payload),
+		Attachments: []SlackAttachment{
+			{
+				Color:     "#" + colorHex(embedColor(cfg, payload)),
+				Title:     title,
+				TitleLink: payload.FileURL,
+				Text:      text,
+				Footer:    footer,
+				Timestamp: messageTime(cfg, payload).Unix(),
+			},
+		},
+	}
+
+	return marshalSlackMessage(message)
+}
+
+// slackBlocks builds the Block Kit form: a header with the title, the
+// rendered message as a mrkdwn section and a download button. The text field
+// of the message stays as the notification fallback.
+func slackBlocks(title, text string, payload FilePayload) []SlackBlock {
+	// A header block can't be empty, so the summary moves up when the title is disabled
+	if title == "" {
+		title = eventSummary(payload)
+	}
+
+	blocks := []SlackBlock{
+		{Type: "header", Text: &SlackText{Type: "plain_text", Text: truncateText(title, maxSlackHeaderChars)}},
+	}
+	// Nor can a section, so a template that renders nothing leaves it out
+	if strings.TrimSpace(text) != "" {
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: truncateText(text, maxSlackSectionChars)},
+		})
+	}
+	if payload.FileURL != "" {
+		blocks = append(blocks, SlackBlock{
+			Type: "actions",
+			Elements: []SlackButton{
+				{Type: "button", Text: SlackText{Type: "plain_text", Text: "Download File"}, URL: payload.FileURL},
+			},
+		})
+	}
+	return blocks
+}
+
+// marshalSlackMessage serializes a Slack message for the HTTP request
+func marshalSlackMessage(message SlackMessage) ([]byte, error) {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Slack message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// slackTestConfig returns a Slack configuration with the platform defaults
+func slackTestConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := testConfig(t, "https://hooks.slack.com/services/T000/B000/XXXX")
+	cfg.Platform = platformSlack
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+	cfg.EmbedColor = 0x3498DB
+	return cfg
+}
+
+func TestBuildSlackMessageShape(t *testing.T) {
+	body, err := buildMessage(slackTestConfig(t), FilePayload{
+		FileName:       "reports/q1.pdf",
+		FileURL:        "https://example.com/q1.pdf",
+		Bucket:         "example-bucket",
+		ExpirationTime: "24 hours",
+		Timestamp:      "2025-05-17T10:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var message map[string]interface{}
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := message["text"].(string); text == "" {
+		t.Errorf("text is empty: %s", body)
+	}
+	if _, ok := message["embeds"]; ok {
+		t.Errorf("Slack body carries Discord embeds: %s", body)
+	}
+	attachments, _ := message["attachments"].([]interface{})
+	if len(attachments) != 1 {
+		t.Fatalf("want one attachment: %s", body)
+	}
+	attachment := attachments[0].(map[string]interface{})
+	for key, want := range map[string]interface{}{
+		"color":      "#3498DB",
+		"title":      "New File Uploaded",
+		"title_link": "https://example.com/q1.pdf",
+		"footer":     footerText,
+		"ts":         float64(1747476000),
+	} {
+		if attachment[key] != want {
+			t.Errorf("attachment %s = %v, want %v", key, attachment[key], want)
+		}
+	}
+	if text, _ := attachment["text"].(string); !strings.Contains(text, "reports/q1.pdf") {
+		t.Errorf("attachment text %q doesn't name the file", text)
+	}
+}
+
+func TestBuildSlackMessageEscapesText(t *testing.T) {
+	body, err := buildMessage(slackTestConfig(t), FilePayload{FileName: "<!channel> & co.pdf", FileURL: "https://example.com/a.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message SlackMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if text := message.Attachments[0].Text; !strings.Contains(text, "&lt;!channel&gt; &amp; co.pdf") {
+		t.Errorf("attachment text %q isn't escaped", text)
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	for raw, want := range map[string]string{"": platformDiscord, "discord": platformDiscord, " Slack ": platformSlack} {
+		if got, err := parsePlatform(raw); err != nil || got != want {
+			t.Errorf("parsePlatform(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parsePlatform("irc"); err == nil {
+		t.Error("parsePlatform(irc): want an error")
+	}
+}
+
+func TestBuildSlackMessageBlocks(t *testing.T) {
+	cfg := slackTestConfig(t)
+	cfg.SlackBlocks = true
+	body, err := buildMessage(cfg, FilePayload{
+		FileName:       "reports/q1.pdf",
+		FileURL:        "https://example.com/q1.pdf?X-Amz-Signature=abc",
+		ExpirationTime: "24 hours",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode generically so the test pins the Block Kit field names
+	var message struct {
+		Text        string        `json:"text"`
+		Attachments []interface{} `json:"attachments"`
+		Blocks      []struct {
+			Type     string `json:"type"`
+			Text     *struct{ Type, Text string }
+			Elements []struct {
+				Type string
+				Text struct{ Type, Text string }
+				URL  string `json:"url"`
+			}
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Text == "" || message.Attachments != nil {
+		t.Errorf("text = %q, attachments = %v, want a fallback text and no attachments", message.Text, message.Attachments)
+	}
+
+	var types []string
+	for _, block := range message.Blocks {
+		types = append(types, block.Type)
+	}
+	if strings.Join(types, ",") != "header,section,actions" {
+		t.Fatalf("blocks = %q, want header, section and actions", types)
+	}
+	header, section, actions := message.Blocks[0], message.Blocks[1], message.Blocks[2]
+	if header.Text.Type != "plain_text" || header.Text.Text != "New File Uploaded" {
+		t.Errorf("header = %+v", header.Text)
+	}
+	if section.Text.Type != "mrkdwn" || !strings.Contains(section.Text.Text, "reports/q1.pdf") || !strings.Contains(section.Text.Text, "24 hours") {
+		t.Errorf("section = %+v, want the file name and expiration in mrkdwn", section.Text)
+	}
+	if len(actions.Elements) != 1 {
+		t.Fatalf("actions has %d elements, want one button", len(actions.Elements))
+	}
+	button := actions.Elements[0]
+	if button.Type != "button" || button.URL != "https://example.com/q1.pdf?X-Amz-Signature=abc" || button.Text.Text != "Download File" {
+		t.Errorf("button = %+v, want a Download File button linking to the file", button)
+	}
+}
+
+func TestSlackBlocksWithoutLinkOrTitle(t *testing.T) {
+	blocks := slackBlocks("", "", FilePayload{FileName: "gone.txt", EventType: eventTypeDeleted})
+	if len(blocks) != 1 || blocks[0].Type != "header" || blocks[0].Text.Text == "" {
+		t.Errorf("blocks = %+v, want only a header carrying the summary", blocks)
+	}
+
+	long := strings.Repeat("x", 200)
+	if header := slackBlocks(long, "text", FilePayload{})[0]; len([]rune(header.Text.Text)) > maxSlackHeaderChars {
+		t.Errorf("header has %d characters, want at most %d", len([]rune(header.Text.Text)), maxSlackHeaderChars)
+	}
+}
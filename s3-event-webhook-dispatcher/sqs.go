@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// isSQSEvent reports whether the raw invocation payload is an SQS batch
+func isSQSEvent(raw json.RawMessage) bool {
+	return recordsEventSource(raw) == "aws:sqs"
+}
+
+// recordsEventSource returns the eventSource of the first record in a
+// Records-style event, or an empty string for any other payload
+func recordsEventSource(raw json.RawMessage) string {
+	var probe struct {
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil || len(probe.Records) == 0 {
+		return ""
+	}
+	return probe.Records[0].EventSource
+}
+
+// handleSQS concurrently dispatches each record of an SQS batch, whose bodies
+// are EventBridge events, and reports the message IDs that failed so only
+// those are redriven
+func (d *Dispatcher) handleSQS(ctx context.Context, batch events.SQSEvent) events.SQSEventResponse {
+	if d.Config.BatchMessages && d.Config.Platform == platformDiscord && len(d.Config.Destinations) == 0 {
+		return d.handleSQSBatched(ctx, batch)
+	}
+
+	errs := make([]error, len(batch.Records))
+	forEachConcurrently(len(batch.Records), func(i int) {
+		errs[i] = d.handleSQSRecord(ctx, batch.Records[i])
+	})
+
+	var response events.SQSEventResponse
+	for i, err := range errs {
+		if err != nil {
+			response.BatchItemFailures = append(response.BatchItemFailures, d.sqsFailure(ctx, batch.Records[i].MessageId, err))
+		}
+	}
+	return response
+}
+
+// handleSQSRecord parses one SQS message body as an EventBridge event and dispatches its files
+func (d *Dispatcher) handleSQSRecord(ctx context.Context, record events.SQSMessage) error {
+	payloads, err := parseSQSRecord(d.Config, record)
+	if err != nil {
+		return err
+	}
+	return d.dispatchEach(ctx, payloads)
+}
+
+// handleSQSBatched combines the files of an SQS batch into Discord messages of
+// up to maxDiscordEmbeds embeds each. When a message fails, every SQS record
+// it carried is reported as failed.
+func (d *Dispatcher) handleSQSBatched(ctx context.Context, batch events.SQSEvent) events.SQSEventResponse {
+	var response events.SQSEventResponse
+
+	var payloads []FilePayload
+	var messageIDs []string
+	for _, record := range batch.Records {
+		recordPayloads, err := parseSQSRecord(d.Config, record)
+		if err != nil {
+			response.BatchItemFailures = append(response.BatchItemFailures, d.sqsFailure(ctx, record.MessageId, err))
+			continue
+		}
+		for _, payload := range recordPayloads {
+			if d.skipFiltered(ctx, payload) {
+				continue
+			}
+			if err := d.checkPayload(ctx, payload); err != nil {
+				response.BatchItemFailures = append(response.BatchItemFailures, d.sqsFailure(ctx, record.MessageId, err))
+				continue
+			}
+			proceed, err := d.claimEvent(ctx, payload)
+			if err != nil {
+				response.BatchItemFailures = append(response.BatchItemFailures, d.sqsFailure(ctx, record.MessageId, err))
+				continue
+			}
+			if !proceed {
+				continue
+			}
+			payloads = append(payloads, d.shortenLink(ctx, payload))
+			messageIDs = append(messageIDs, record.MessageId)
+		}
+	}
+
+	// Files routed to different webhooks can't share a message
+	for _, group := range groupByRoute(d.Config, payloads) {
+		routedPayloads := make([]FilePayload, len(group))
+		routedIDs := make([]string, len(group))
+		for i, index := range group {
+			routedPayloads[i], routedIDs[i] = payloads[index], messageIDs[index]
+		}
+		failures := d.routed(routedPayloads[0]).sendBatched(ctx, routedPayloads, routedIDs)
+		response.BatchItemFailures = append(response.BatchItemFailures, failures...)
+	}
+	response.BatchItemFailures = uniqueFailures(response.BatchItemFailures)
+	return response
+}
+
+// uniqueFailures drops repeated entries for a message whose detail carried
+// several files, keeping the first
+func uniqueFailures(failures []events.SQSBatchItemFailure) []events.SQSBatchItemFailure {
+	seen := make(map[string]bool, len(failures))
+	unique := failures[:0]
+	for _, failure := range failures {
+		if !seen[failure.ItemIdentifier] {
+			seen[failure.ItemIdentifier] = true
+			unique = append(unique, failure)
+		}
+	}
+	return unique
+}
+
+// sendBatched sends payloads, carried by the SQS messages messageIDs, in
+// Discord messages of up to maxDiscordEmbeds embeds each and returns the
+// records of the messages that failed
+func (d *Dispatcher) sendBatched(ctx context.Context, payloads []FilePayload, messageIDs []string) []events.SQSBatchItemFailure {
+	var failures []events.SQSBatchItemFailure
+	offset := 0
+	for _, chunk := range chunkPayloads(payloads, maxDiscordEmbeds) {
+		ids := messageIDs[offset : offset+len(chunk)]
+		offset += len(chunk)
+		ctx := withDispatchIDs(ctx, dispatchIDs{RequestID: newRequestID(), CorrelationID: batchCorrelationID(chunk)})
+
+		messageJSON, err := buildDiscordBatch(d.Config, chunk)
+		if err == nil {
+			err = d.sendJSON(ctx, messageJSON, chunk)
+		}
+		if err != nil {
+			for i, id := range ids {
+				if d.reportFailure(ctx, chunk[i], err) {
+					continue
+				}
+				d.releaseEvent(ctx, chunk[i])
+				failures = append(failures, d.sqsFailure(ctx, id, err))
+			}
+		}
+	}
+	return failures
+}
+
+// batchCorrelationID returns the correlation ID shared by every file in a
+// batched message, or "" when they don't all carry the same one
+func batchCorrelationID(payloads []FilePayload) string {
+	for _, payload := range payloads[1:] {
+		if payload.CorrelationID != payloads[0].CorrelationID {
+			return ""
+		}
+	}
+	return payloads[0].CorrelationID
+}
+
+// parseSQSRecord extracts the file payloads from the EventBridge event in an SQS message body
+func parseSQSRecord(cfg Config, record events.SQSMessage) ([]FilePayload, error) {
+	var event events.CloudWatchEvent
+	if err := json.Unmarshal([]byte(record.Body), &event); err != nil {
+		return nil, fmt.Errorf("%w: message body: %w", ErrPayloadParse, err)
+	}
+
+	return payloadsFromEvent(cfg, event)
+}
+
+// sqsFailure logs a failed SQS message and returns its batch item failure entry
+func (d *Dispatcher) sqsFailure(ctx context.Context, messageID string, err error) events.SQSBatchItemFailure {
+	d.Logger.WarnContext(ctx, "SQS message failed",
+		slog.String("messageId", messageID),
+		slog.String("error", err.Error()))
+	return events.SQSBatchItemFailure{ItemIdentifier: messageID}
+}
+
+// chunkPayloads splits payloads into consecutive groups of at most size elements
+func chunkPayloads(payloads []FilePayload, size int) [][]FilePayload {
+	var chunks [][]FilePayload
+	for size < len(payloads) {
+		payloads, chunks = payloads[size:], append(chunks, payloads[:size:size])
+	}
+	if len(payloads) > 0 {
+		chunks = append(chunks, payloads)
+	}
+	return chunks
+}
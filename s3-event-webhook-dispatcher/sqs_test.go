@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// sqsBatch wraps each body in an SQS message with ID msg-<index>
+func sqsBatch(bodies ...string) events.SQSEvent {
+	var batch events.SQSEvent
+	for i, body := range bodies {
+		batch.Records = append(batch.Records, events.SQSMessage{
+			MessageId:   fmt.Sprintf("msg-%d", i+1),
+			EventSource: "aws:sqs",
+			Body:        body,
+		})
+	}
+	return batch
+}
+
+// uploadEvent returns an EventBridge event body for one uploaded file
+func uploadEvent(fileName string) string {
+	return `{"detail-type":"File Uploaded","source":"s3-link-generator","detail":{"fileName":"` + fileName +
+		`","fileUrl":"https://example.com/` + fileName + `","bucket":"example-bucket","expirationTime":"24 hours"}}`
+}
+
+func TestHandleSQSReportsFailedRecord(t *testing.T) {
+	srv := newWebhookServer(t)
+	d := newTestDispatcher(testConfig(t, srv.URL), srv)
+
+	response := d.handleSQS(context.Background(), sqsBatch(uploadEvent("a.pdf"), `{"detail": not json`))
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "msg-2" {
+		t.Errorf("failures = %+v, want msg-2 only", response.BatchItemFailures)
+	}
+	if got := len(srv.received()); got != 1 {
+		t.Errorf("webhook received %d requests, want 1", got)
+	}
+}
+
+func TestHandleSQSReportsFailedDelivery(t *testing.T) {
+	var requests int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var body bytes.Buffer
+		body.ReadFrom(r.Body)
+		if bytes.Contains(body.Bytes(), []byte("rejected.pdf")) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	d := NewDispatcher(testConfig(t, srv.URL))
+	d.Client, d.Logger = srv.Client(), newLogger(slogQuiet)
+
+	response := d.handleSQS(context.Background(), sqsBatch(uploadEvent("rejected.pdf"), uploadEvent("b.pdf")))
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "msg-1" {
+		t.Errorf("failures = %+v, want msg-1 only", response.BatchItemFailures)
+	}
+	if requests != 2 {
+		t.Errorf("webhook received %d requests, want 2", requests)
+	}
+}
+
+func TestHandlerDetectsSQSBatch(t *testing.T) {
+	setenvConfig(t, nil)
+	raw, err := json.Marshal(sqsBatch(`not an event`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSQSEvent(raw) {
+		t.Fatal("SQS batch not detected")
+	}
+	if isSQSEvent(json.RawMessage(uploadEvent("a.pdf"))) {
+		t.Error("EventBridge event detected as an SQS batch")
+	}
+
+	resp, err := Handler(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures := resp.(events.SQSEventResponse).BatchItemFailures; len(failures) != 1 {
+		t.Errorf("failures = %+v, want the malformed record", failures)
+	}
+}
+
+func TestChunkPayloads(t *testing.T) {
+	payloads := make([]FilePayload, 23)
+	for i := range payloads {
+		payloads[i].FileName = fmt.Sprintf("file-%02d.pdf", i)
+	}
+
+	chunks := chunkPayloads(payloads, maxDiscordEmbeds)
+	if len(chunks) != 3 || len(chunks[0]) != 10 || len(chunks[1]) != 10 || len(chunks[2]) != 3 {
+		t.Fatalf("chunk sizes = %d chunks", len(chunks))
+	}
+	if chunks[2][0].FileName != "file-20.pdf" {
+		t.Errorf("last chunk starts at %s, want file-20.pdf", chunks[2][0].FileName)
+	}
+	if chunks := chunkPayloads(nil, maxDiscordEmbeds); len(chunks) != 0 {
+		t.Errorf("chunkPayloads(nil) = %d chunks", len(chunks))
+	}
+}
+
+func TestHandleSQSBatchedSplitsEmbeds(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.BatchMessages = true
+	d := newTestDispatcher(cfg, srv)
+
+	bodies := make([]string, 23)
+	for i := range bodies {
+		bodies[i] = uploadEvent(fmt.Sprintf("file-%02d.pdf", i))
+	}
+	if response := d.handleSQS(context.Background(), sqsBatch(bodies...)); len(response.BatchItemFailures) != 0 {
+		t.Fatalf("failures = %+v", response.BatchItemFailures)
+	}
+
+	requests := srv.received()
+	if len(requests) != 3 {
+		t.Fatalf("webhook received %d requests, want 3", len(requests))
+	}
+	var embeds int
+	for _, req := range requests {
+		var message DiscordMessage
+		if err := json.Unmarshal(req.Body, &message); err != nil {
+			t.Fatal(err)
+		}
+		if len(message.Embeds) > maxDiscordEmbeds {
+			t.Errorf("message has %d embeds, over the limit of %d", len(message.Embeds), maxDiscordEmbeds)
+		}
+		for _, embed := range message.Embeds {
+			if !strings.Contains(embed.Description, ".pdf") {
+				t.Errorf("embed description %q isn't the rendered template", embed.Description)
+			}
+		}
+		embeds += len(message.Embeds)
+	}
+	if embeds != 23 {
+		t.Errorf("sent %d embeds, want 23", embeds)
+	}
+}
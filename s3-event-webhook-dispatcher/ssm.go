@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// ssmAPI is the subset of the SSM client used by the dispatcher
+type ssmAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// newSSMClient creates the SSM client; tests replace it with a stub
+var newSSMClient = func(ctx context.Context) (ssmAPI, error) {
+	awsCfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ssm.NewFromConfig(awsCfg), nil
+}
+
+// webhookParamCache holds the webhook URL read from Parameter Store
+var webhookParamCache valueCache
+
+// resolveWebhookParam returns the webhook URL stored in the given parameter,
+// decrypting SecureString values and fetching only on the first call for that name
+func resolveWebhookParam(ctx context.Context, name string) (string, error) {
+	return webhookParamCache.get(name, func() (string, error) {
+		client, err := newSSMClient(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create SSM client: %w", err)
+		}
+
+		out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch webhook URL from SSM parameter %s: %w", name, err)
+		}
+
+		var value string
+		if out.Parameter != nil {
+			value = strings.TrimSpace(aws.ToString(out.Parameter.Value))
+		}
+		if value == "" {
+			return "", fmt.Errorf("SSM parameter %s is empty", name)
+		}
+		return value, nil
+	})
+}
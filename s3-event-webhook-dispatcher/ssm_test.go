@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// fakeSSM serves one SecureString parameter, returning its value only when
+// decryption is requested
+type fakeSSM struct {
+	name  string
+	value string
+	err   error
+	calls int
+}
+
+func (f *fakeSSM) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if aws.ToString(params.Name) != f.name {
+		return nil, &types.ParameterNotFound{Message: aws.String("parameter not found")}
+	}
+	value := "AQICAHh-ciphertext"
+	if aws.ToBool(params.WithDecryption) {
+		value = f.value
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{
+		Name:  params.Name,
+		Type:  types.ParameterTypeSecureString,
+		Value: aws.String(value),
+	}}, nil
+}
+
+// stubSSM makes newSSMClient return client and empties the parameter cache
+// until the test ends
+func stubSSM(t *testing.T, client *fakeSSM) {
+	t.Helper()
+	original := newSSMClient
+	newSSMClient = func(ctx context.Context) (ssmAPI, error) { return client, nil }
+	webhookParamCache = valueCache{}
+	t.Cleanup(func() {
+		newSSMClient = original
+		webhookParamCache = valueCache{}
+	})
+}
+
+func TestLoadConfigSecureStringWebhook(t *testing.T) {
+	client := &fakeSSM{name: "/dispatcher/webhook", value: " https://discord.com/api/webhooks/2/secret \n"}
+	stubSSM(t, client)
+	setenvConfig(t, map[string]string{"WEBHOOK_URL": "", "WEBHOOK_URL_SSM_PARAM": "/dispatcher/webhook"})
+
+	for i := 0; i < 2; i++ {
+		cfg, err := loadConfig(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.WebhookURLs) != 1 || cfg.WebhookURLs[0] != "https://discord.com/api/webhooks/2/secret" {
+			t.Fatalf("webhooks = %v", cfg.WebhookURLs)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("GetParameter called %d times, want 1 across warm invocations", client.calls)
+	}
+}
+
+func TestLoadConfigSSMFailure(t *testing.T) {
+	stubSSM(t, &fakeSSM{err: errors.New("AccessDeniedException")})
+	setenvConfig(t, map[string]string{"WEBHOOK_URL": "", "WEBHOOK_URL_SSM_PARAM": "/dispatcher/webhook"})
+
+	if _, err := loadConfig(context.Background()); !errors.Is(err, ErrWebhookLookup) {
+		t.Errorf("loadConfig error = %v, want ErrWebhookLookup", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusRange is an inclusive range of HTTP status codes
+type statusRange struct {
+	Min, Max int
+}
+
+// statusRanges lists the response status codes that count as a successful
+// delivery. A nil list accepts any 2xx status.
+type statusRanges []statusRange
+
+// accepts reports whether code counts as a successful delivery
+func (r statusRanges) accepts(code int) bool {
+	if r == nil {
+		return code >= 200 && code <= 299
+	}
+	for _, sr := range r {
+		if code >= sr.Min && code <= sr.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsRedirect reports whether any 3xx status counts as success, in which
+// case the response must be kept instead of following the redirect
+func (r statusRanges) acceptsRedirect() bool {
+	for _, sr := range r {
+		if sr.Min <= 399 && sr.Max >= 300 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAcceptedStatusCodes parses ACCEPTED_STATUS_CODES, a comma-separated
+// list of status codes and ranges such as "200-204,302". An empty value keeps
+// the default of any 2xx status.
+func parseAcceptedStatusCodes(raw string) (statusRanges, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var ranges statusRanges
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		low, high, isRange := strings.Cut(item, "-")
+		if !isRange {
+			high = low
+		}
+		first, err := parseStatusCode(low)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ACCEPTED_STATUS_CODES entry %q: %w", item, err)
+		}
+		last, err := parseStatusCode(high)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ACCEPTED_STATUS_CODES entry %q: %w", item, err)
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid ACCEPTED_STATUS_CODES entry %q: range start is after its end", item)
+		}
+		ranges = append(ranges, statusRange{Min: first, Max: last})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("ACCEPTED_STATUS_CODES lists no status codes")
+	}
+	return ranges, nil
+}
+
+// parseStatusCode parses one HTTP status code in the range 100-599
+func parseStatusCode(raw string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a status code", raw)
+	}
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("status code %d is outside 100-599", code)
+	}
+	return code, nil
+}
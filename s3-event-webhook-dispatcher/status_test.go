@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestStatusRangesAcceptsDefault(t *testing.T) {
+	var ranges statusRanges
+	for code, want := range map[int]bool{200: true, 201: true, 204: true, 299: true, 199: false, 302: false, 400: false} {
+		if got := ranges.accepts(code); got != want {
+			t.Errorf("default accepts(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestParseAcceptedStatusCodesSingle(t *testing.T) {
+	ranges, err := parseAcceptedStatusCodes("201")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (statusRanges{{Min: 201, Max: 201}}); !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("ranges = %v, want %v", ranges, want)
+	}
+	if !ranges.accepts(201) || ranges.accepts(200) || ranges.accepts(204) {
+		t.Error("a single code should accept exactly that code")
+	}
+}
+
+func TestParseAcceptedStatusCodesRange(t *testing.T) {
+	ranges, err := parseAcceptedStatusCodes(" 200-204 , 302 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[int]bool{200: true, 202: true, 204: true, 205: false, 302: true, 301: false} {
+		if got := ranges.accepts(code); got != want {
+			t.Errorf("accepts(%d) = %v, want %v", code, got, want)
+		}
+	}
+	if !ranges.acceptsRedirect() {
+		t.Error("acceptsRedirect() = false with 302 listed")
+	}
+}
+
+func TestParseAcceptedStatusCodesInvalid(t *testing.T) {
+	if ranges, err := parseAcceptedStatusCodes(""); err != nil || ranges != nil {
+		t.Errorf("parseAcceptedStatusCodes(\"\") = %v, %v, want the 2xx default", ranges, err)
+	}
+	for _, raw := range []string{"ok", "204-200", "99", "600", "200-", ",", "200-abc"} {
+		if _, err := parseAcceptedStatusCodes(raw); err == nil {
+			t.Errorf("parseAcceptedStatusCodes(%q): want an error", raw)
+		}
+	}
+
+	setenvConfig(t, map[string]string{"ACCEPTED_STATUS_CODES": "2xx"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted an invalid ACCEPTED_STATUS_CODES")
+	}
+}
+
+func TestSendAcceptedStatusCodes(t *testing.T) {
+	srv := newWebhookServer(t, http.StatusAccepted)
+	cfg := testConfig(t, srv.URL)
+	cfg.AcceptedStatusCodes = statusRanges{{Min: 200, Max: 201}}
+	d := newTestDispatcher(cfg, srv)
+
+	var statusErr *WebhookStatusError
+	if err := d.Send(context.Background(), []byte(`{}`)); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusAccepted {
+		t.Errorf("err = %v, want 202 rejected outside ACCEPTED_STATUS_CODES", err)
+	}
+
+	d.Config.AcceptedStatusCodes = nil
+	if err := d.Send(context.Background(), []byte(`{}`)); err != nil {
+		t.Errorf("202 with the default: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ExtensionStyle overrides the embed color and title emoji for one file extension
+type ExtensionStyle struct {
+	Color    int
+	ColorSet bool
+	Emoji    string
+}
+
+// parseExtensionStyles decodes EXTENSION_STYLES, a JSON object mapping an
+// extension to {"color": ..., "emoji": ...}. Colors may be numbers or the same
+// decimal/hex strings EMBED_COLOR accepts; an omitted color keeps EMBED_COLOR.
+func parseExtensionStyles(raw string) (map[string]ExtensionStyle, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var entries map[string]struct {
+		Color json.RawMessage `json:"color"`
+		Emoji string          `json:"emoji"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("invalid EXTENSION_STYLES: %w", err)
+	}
+
+	styles := make(map[string]ExtensionStyle, len(entries))
+	for ext, entry := range entries {
+		color, set, err := parseJSONColor(entry.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXTENSION_STYLES color for %q: %w", ext, err)
+		}
+		styles[normalizeExtension(ext)] = ExtensionStyle{Color: color, ColorSet: set, Emoji: entry.Emoji}
+	}
+	return styles, nil
+}
+
+// parseJSONColor parses a color given in a JSON setting and reports whether
+// it was set at all, so an explicit "random" differs from an omitted or null color
+func parseJSONColor(raw json.RawMessage) (color int, set bool, err error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return randomEmbedColor, false, nil
+	}
+	// Accept both 3447003 and "#3498DB" by parsing the unquoted text
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		text = string(raw)
+	}
+	color, err = parseEmbedColor(text)
+	return color, err == nil, err
+}
+
+// normalizeExtension lowercases an extension and strips any leading dot
+func normalizeExtension(ext string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+}
+
+// fileExtension returns the normalized extension of a file name, or "" when it has none
+func fileExtension(name string) string {
+	return normalizeExtension(path.Ext(name))
+}
+
+// extensionStyle returns the style configured for the file's extension, if any
+func extensionStyle(cfg Config, fileName string) (ExtensionStyle, bool) {
+	ext := fileExtension(fileName)
+	if ext == "" {
+		return ExtensionStyle{}, false
+	}
+	style, ok := cfg.ExtensionStyles[ext]
+	return style, ok
+}
+
+// MIMEEmoji is the title emoji for content types starting with Prefix
+type MIMEEmoji struct {
+	Prefix string
+	Emoji  string
+}
+
+// parseMIMEEmojiMap decodes MIME_EMOJI_MAP, a JSON object mapping a content
+// type or prefix such as "image/" or "application/pdf" to an emoji. Entries
+// are ordered longest prefix first so the most specific one wins.
+func parseMIMEEmojiMap(raw string) ([]MIMEEmoji, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("invalid MIME_EMOJI_MAP: %w", err)
+	}
+
+	emojis := make([]MIMEEmoji, 0, len(entries))
+	for prefix, emoji := range entries {
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if prefix == "" || emoji == "" {
+			return nil, fmt.Errorf("invalid MIME_EMOJI_MAP entry %q: both the content type and the emoji must be set", prefix)
+		}
+		emojis = append(emojis, MIMEEmoji{Prefix: prefix, Emoji: emoji})
+	}
+	sort.Slice(emojis, func(i, j int) bool {
+		if len(emojis[i].Prefix) != len(emojis[j].Prefix) {
+			return len(emojis[i].Prefix) > len(emojis[j].Prefix)
+		}
+		return emojis[i].Prefix < emojis[j].Prefix
+	})
+	return emojis, nil
+}
+
+// titleEmoji picks the emoji prefixed to the title: the MIME_EMOJI_MAP entry
+// for the payload's content type, else the EXTENSION_STYLES emoji for its
+// extension, or "" when neither has one
+func titleEmoji(cfg Config, payload FilePayload) string {
+	// Parameters such as "; charset=utf-8" don't change the type
+	mediaType, _, _ := strings.Cut(payload.ContentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType != "" {
+		for _, entry := range cfg.MIMEEmojis {
+			if strings.HasPrefix(mediaType, entry.Prefix) {
+				return entry.Emoji
+			}
+		}
+	}
+
+	style, _ := extensionStyle(cfg, payload.FileName)
+	return style.Emoji
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtensionStyles(t *testing.T) {
+	styles, err := parseExtensionStyles(`{".PNG": {"color": "#2ECC71", "emoji": "🖼️"}, "zip": {"color": 15105570, "emoji": "📦"}, "txt": {"emoji": "📝"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.EmbedColor = 0x3498DB
+	cfg.ExtensionStyles = styles
+
+	tests := []struct {
+		fileName string
+		color    int
+		title    string
+	}{
+		{"photos/cat.png", 0x2ECC71, "🖼️ New File Uploaded"},
+		{"backup.ZIP", 15105570, "📦 New File Uploaded"},
+		{"notes.txt", 0x3498DB, "📝 New File Uploaded"},
+		{"report.pdf", 0x3498DB, "New File Uploaded"},
+	}
+	for _, tt := range tests {
+		body, err := buildDiscordMessage(cfg, FilePayload{FileName: tt.fileName, FileURL: "https://example.com/f"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		embed := decodeDiscordMessage(t, body).Embeds[0]
+		if embed.Color != tt.color || embed.Title != tt.title {
+			t.Errorf("%s: color %#x, title %q; want %#x, %q", tt.fileName, embed.Color, embed.Title, tt.color, tt.title)
+		}
+	}
+}
+
+func TestParseExtensionStylesInvalid(t *testing.T) {
+	for _, raw := range []string{`{"png": {"color": "green"}}`, `["png"]`} {
+		if _, err := parseExtensionStyles(raw); err == nil {
+			t.Errorf("parseExtensionStyles(%s): want an error", raw)
+		}
+	}
+}
+
+func TestTitleEmojiFromContentType(t *testing.T) {
+	emojis, err := parseMIMEEmojiMap(`{"image/": "🖼️", "video/": "🎬", "application/pdf": "📄", "application/": "📦"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	styles, err := parseExtensionStyles(`{"txt": {"emoji": "📝"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.MIMEEmojis = emojis
+	cfg.ExtensionStyles = styles
+
+	tests := []struct {
+		fileName    string
+		contentType string
+		want        string
+	}{
+		{"cat", "image/png", "🖼️ New File Uploaded"},
+		{"cat.txt", "IMAGE/PNG; charset=binary", "🖼️ New File Uploaded"},
+		{"report", "application/pdf", "📄 New File Uploaded"},
+		{"archive", "application/zip", "📦 New File Uploaded"},
+		{"notes.txt", "text/x-unknown", "📝 New File Uploaded"},
+		{"notes.txt", "", "📝 New File Uploaded"},
+		{"blob.bin", "chemical/x-unknown", "New File Uploaded"},
+	}
+	for _, tt := range tests {
+		body, err := buildDiscordMessage(cfg, FilePayload{FileName: tt.fileName, FileURL: "https://example.com/f", ContentType: tt.contentType})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title := decodeDiscordMessage(t, body).Embeds[0].Title; title != tt.want {
+			t.Errorf("%s (%q): title %q, want %q", tt.fileName, tt.contentType, title, tt.want)
+		}
+	}
+}
+
+func TestParseMIMEEmojiMap(t *testing.T) {
+	emojis, err := parseMIMEEmojiMap(`{"Image/": "🖼️", "image/png": "🎨"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MIMEEmoji{{Prefix: "image/png", Emoji: "🎨"}, {Prefix: "image/", Emoji: "🖼️"}}
+	if !reflect.DeepEqual(emojis, want) {
+		t.Errorf("emojis = %+v, want the longest prefix first", emojis)
+	}
+	for _, raw := range []string{`{"image/": ""}`, `{"": "🖼️"}`, `["image/"]`} {
+		if _, err := parseMIMEEmojiMap(raw); err == nil {
+			t.Errorf("parseMIMEEmojiMap(%s): want an error", raw)
+		}
+	}
+}
+
+func TestFilePayloadContentType(t *testing.T) {
+	var payload FilePayload
+	if err := json.Unmarshal([]byte(`{"fileName": "cat", "contentType": "image/png"}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ContentType != "image/png" {
+		t.Errorf("ContentType = %q, want image/png from the detail", payload.ContentType)
+	}
+}
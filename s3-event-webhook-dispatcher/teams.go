@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TeamsFact represents a name/value pair displayed in a MessageCard section
+type TeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TeamsSection represents a section of a Microsoft Teams MessageCard
+type TeamsSection struct {
+	ActivityTitle    string      `json:"activityTitle,omitempty"`
+	ActivitySubtitle string      `json:"activitySubtitle,omitempty"`
+	Facts            []TeamsFact `json:"facts"`
+}
+
+// TeamsActionTarget represents a platform-specific URI for an OpenUri action
+type TeamsActionTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+// TeamsAction represents a button on a MessageCard
+type TeamsAction struct {
+	Type    string              `json:"@type"`
+	Name    string              `json:"name"`
+	Targets []TeamsActionTarget `json:"targets"`
+}
+
+// TeamsMessage represents a legacy Office 365 connector MessageCard
+type TeamsMessage struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	ThemeColor      string         `json:"themeColor"`
+	Summary         string         `json:"summary"`
+	Sections        []TeamsSection `json:"sections"`
+	PotentialAction []TeamsAction  `json:"potentialAction,omitempty"`
+}
+
+// buildTeamsMessage formats the payload as a Microsoft Teams MessageCard, with
+// the rendered message as the subtitle of its section
+func buildTeamsMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	title, err := renderTitle(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	subtitle, err := renderMessage(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Teams requires a summary even when the title is disabled
+	summary := payload.FileName
+	if title != "" {
+		summary = fmt.Sprintf("%s: %s", title, payload.FileName)
+	}
+
+	message := TeamsMessage{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: colorHex(embedColor(cfg, payload)),
+		Summary:    summary,
+		Sections: []TeamsSection{
+			{
+				ActivityTitle:    title,
+				ActivitySubtitle: subtitle,
+				Facts: []TeamsFact{
+					{Name: "File Name", Value: payload.FileName},
+					{Name: "Bucket", Value: payload.Bucket},
+					{Name: "Link Expires", Value: "After " + payload.ExpirationTime},
+				},
+			},
+		},
+	}
+
+	// Link the presigned URL from a button rather than inline text
+	if payload.FileURL != "" {
+		message.PotentialAction = []TeamsAction{
+			{
+				Type: "OpenUri",
+				Name: "Download File",
+				Targets: []TeamsActionTarget{
+					{OS: "default", URI: payload.FileURL},
+				},
+			},
+		}
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Teams message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
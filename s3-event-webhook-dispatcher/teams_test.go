@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildTeamsMessage(t *testing.T) {
+	cfg := testConfig(t, "https://example.webhook.office.com/webhookb2/x")
+	cfg.Platform = platformTeams
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+	cfg.EmbedColor = 0x3498DB
+	presigned := "https://example-bucket.s3.amazonaws.com/q1.pdf?X-Amz-Signature=abc"
+
+	body, err := buildMessage(cfg, FilePayload{FileName: "q1.pdf", FileURL: presigned, Bucket: "example-bucket", ExpirationTime: "24 hours"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var card TeamsMessage
+	if err := json.Unmarshal(body, &card); err != nil {
+		t.Fatal(err)
+	}
+
+	if card.Type != "MessageCard" || card.ThemeColor != "3498DB" || card.Summary != "New File Uploaded: q1.pdf" {
+		t.Errorf("card = %+v", card)
+	}
+	if len(card.Sections) != 1 || card.Sections[0].ActivityTitle != "New File Uploaded" || len(card.Sections[0].Facts) != 3 {
+		t.Fatalf("sections = %+v", card.Sections)
+	}
+	if len(card.PotentialAction) != 1 || card.PotentialAction[0].Type != "OpenUri" {
+		t.Fatalf("potentialAction = %+v", card.PotentialAction)
+	}
+	if targets := card.PotentialAction[0].Targets; len(targets) != 1 || targets[0].URI != presigned {
+		t.Errorf("button targets %+v, want the presigned URL", targets)
+	}
+}
+
+func TestColorHex(t *testing.T) {
+	for color, want := range map[int]string{0x3498DB: "3498DB", 0: "000000", 0xFF: "0000FF"} {
+		if got := colorHex(color); got != want {
+			t.Errorf("colorHex(%#x) = %s, want %s", color, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// telegramParseMode is the Telegram formatting mode the message text is written in
+const telegramParseMode = "MarkdownV2"
+
+// TelegramMessage represents the body of a Telegram Bot API sendMessage request
+type TelegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+// telegramEscaper escapes every character MarkdownV2 reserves in ordinary text
+var telegramEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// telegramURLEscaper escapes the characters MarkdownV2 reserves inside a link target
+var telegramURLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// buildTelegramMessage formats the payload as a Telegram sendMessage request
+// for TELEGRAM_CHAT_ID: the title in bold over the rendered message, which is
+// written in MarkdownV2 with every payload value escaped for it
+func buildTelegramMessage(cfg Config, payload FilePayload) ([]byte, error) {
+	if cfg.TelegramChatID == "" {
+		return nil, fmt.Errorf("TELEGRAM_CHAT_ID must be set when PLATFORM is %q", platformTelegram)
+	}
+
+	title, err := renderTitle(cfg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// FileURL is a link target in the default template, escaped as one
+	rendered := escapePayloadText(payload, telegramEscaper)
+	rendered.FileURL = telegramURLEscaper.Replace(payload.FileURL)
+	body, err := renderMessage(cfg, rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	text := body
+	if title != "" {
+		text = fmt.Sprintf("*%s*\n%s", telegramEscaper.Replace(title), body)
+	}
+
+	message := TelegramMessage{
+		ChatID:    cfg.TelegramChatID,
+		Text:      text,
+		ParseMode: telegramParseMode,
+	}
+
+	// Serialize message to JSON for HTTP request
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Telegram message to JSON: %w", err)
+	}
+	return messageJSON, nil
+}
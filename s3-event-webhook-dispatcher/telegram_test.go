@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildTelegramMessage(t *testing.T) {
+	cfg := Config{Platform: platformTelegram, TelegramChatID: "-1001234567890"}
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+	cfg.TitleTemplate, _ = parseTitleTemplate("", false)
+
+	body, err := buildTelegramMessage(cfg, FilePayload{
+		FileName:       "q1_report-v2.final(1).pdf",
+		FileURL:        "https://example.com/a_(1).pdf",
+		ExpirationTime: "1.5 hours",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var message TelegramMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.ChatID != "-1001234567890" {
+		t.Errorf("chat_id = %q, want -1001234567890", message.ChatID)
+	}
+	if message.ParseMode != "MarkdownV2" {
+		t.Errorf("parse_mode = %q, want MarkdownV2", message.ParseMode)
+	}
+	want := "*New File Uploaded*\n" +
+		"A new file has been uploaded to S3\\.\n\n" +
+		"*File Name:* q1\\_report\\-v2\\.final\\(1\\)\\.pdf\n" +
+		"*Temporary Link:* [Download File](https://example.com/a_(1\\).pdf)\n" +
+		"*Link Expires:* After 1\\.5 hours"
+	if message.Text != want {
+		t.Errorf("text =\n%s\nwant\n%s", message.Text, want)
+	}
+}
+
+func TestBuildTelegramMessageTemplate(t *testing.T) {
+	tmpl, err := parseMessageTemplate("_{{.FileName}}_ in {{.Bucket}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Platform: platformTelegram, TelegramChatID: "@uploads", MessageTemplate: tmpl}
+
+	body, err := buildTelegramMessage(cfg, FilePayload{FileName: "a*b.txt", Bucket: "my-bucket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message TelegramMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if want := "_a\\*b\\.txt_ in my\\-bucket"; message.Text != want {
+		t.Errorf("text = %q, want %q", message.Text, want)
+	}
+}
+
+func TestBuildTelegramMessageRequiresChatID(t *testing.T) {
+	cfg := Config{Platform: platformTelegram, MessageTemplate: telegramMessageTemplate}
+	if _, err := buildTelegramMessage(cfg, FilePayload{FileName: "a.txt"}); err == nil {
+		t.Error("missing TELEGRAM_CHAT_ID: want an error")
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMessageTemplate reproduces the original fixed message format, leaving
+// out the link lines when the payload has no URL rather than rendering an empty link
+const defaultMessageTemplate = "A new file has been uploaded to S3.\n\n" +
+	"**File Name:** {{.FileName}}" +
+	"{{if .FileURL}}\n**Temporary Link:** [Download File]({{.FileURL}})\n" +
+	"**Link Expires:** After {{.ExpirationTime}}{{end}}"
+
+// defaultFieldsMessageTemplate is the default with EMBED_FIELDS, leaving the
+// bucket, size and expiry to the embed's fields
+const defaultFieldsMessageTemplate = "A new file has been uploaded to S3.\n\n" +
+	"**File Name:** {{.FileName}}" +
+	"{{if .FileURL}}\n**Temporary Link:** [Download File]({{.FileURL}}){{end}}"
+
+// defaultAlertSummaryTemplate is the default MESSAGE_TEMPLATE for alerting
+// platforms, whose summaries are a single line of plain text
+const defaultAlertSummaryTemplate = "New file in {{.Bucket}}: {{.FileName}}"
+
+// defaultSlackMessageTemplate is the default attachment text for Slack mrkdwn,
+// which uses single asterisks for bold
+const defaultSlackMessageTemplate = "*File Name:* {{.FileName}}\n*Link Expires:* After {{.ExpirationTime}}"
+
+// defaultSummaryMessageTemplate is the default card subtitle for Teams and Google Chat
+const defaultSummaryMessageTemplate = "{{.Summary}}"
+
+// defaultGoogleChatTextTemplate is the default GOOGLECHAT_SIMPLE text, which
+// uses *bold* and <url|text> links like Slack
+const defaultGoogleChatTextTemplate = "{{.Summary}}\n\n*File Name:* {{.FileName}}" +
+	"{{if .FileURL}}\n*Temporary Link:* <{{.FileURL}}|Download File>\n" +
+	"*Link Expires:* After {{.ExpirationTime}}{{end}}"
+
+// defaultTelegramMessageTemplate is the default Telegram text in MarkdownV2,
+// which uses single asterisks for bold
+const defaultTelegramMessageTemplate = "{{.Summary}}\n\n*File Name:* {{.FileName}}" +
+	"{{if .FileURL}}\n*Temporary Link:* [Download File]({{.FileURL}})\n" +
+	"*Link Expires:* After {{.ExpirationTime}}{{end}}"
+
+// templateFuncs are available in every template
+var templateFuncs = template.FuncMap{
+	"json":     toJSON,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"title":    titleCase,
+	"trim":     strings.TrimSpace,
+	"truncate": truncateFunc,
+	"default":  defaultValue,
+}
+
+// titleCase capitalizes the first letter of every space-separated word
+func titleCase(s string) string {
+	words := strings.Split(s, " ")
+	for i, word := range words {
+		if r, size := utf8.DecodeRuneInString(word); size > 0 {
+			words[i] = string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// truncateFunc cuts s to at most n characters with an ellipsis, argument order
+// suiting pipelines such as {{.FileName | truncate 40}}
+func truncateFunc(n int, s string) string {
+	return truncateText(s, n)
+}
+
+// defaultValue returns def when value is empty or zero, e.g.
+// {{.ExpirationTime | default "unknown"}}
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		return def
+	}
+	return value
+}
+
+// templateFuncNames lists the registered template functions in sorted order
+func templateFuncNames() []string {
+	names := make([]string, 0, len(templateFuncs))
+	for name := range templateFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// toJSON encodes a value as JSON so it can be embedded safely in a JSON body template
+func toJSON(v interface{}) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// parseTemplate compiles a template read from the named environment variable
+func parseTemplate(envName, text string) (*template.Template, error) {
+	tmpl, err := template.New(envName).Funcs(templateFuncs).Parse(text)
+	if err != nil && strings.Contains(err.Error(), "not defined") {
+		return nil, fmt.Errorf("invalid %s: %w (available functions: %s)", envName, err, strings.Join(templateFuncNames(), ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", envName, err)
+	}
+	return tmpl, nil
+}
+
+// Platform defaults for an unset MESSAGE_TEMPLATE, compiled once at package init
+var (
+	discordMessageTemplate  = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultMessageTemplate))
+	fieldsMessageTemplate   = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultFieldsMessageTemplate))
+	alertSummaryTemplate    = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultAlertSummaryTemplate))
+	slackMessageTemplate    = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultSlackMessageTemplate))
+	summaryMessageTemplate  = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultSummaryMessageTemplate))
+	googleChatTextTemplate  = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultGoogleChatTextTemplate))
+	telegramMessageTemplate = template.Must(parseTemplate("MESSAGE_TEMPLATE", defaultTelegramMessageTemplate))
+)
+
+// platformMessageTemplate returns the default MESSAGE_TEMPLATE for the
+// configured platform, written in its markup and reproducing its standard layout
+func platformMessageTemplate(cfg Config) *template.Template {
+	switch {
+	case cfg.Platform == platformPagerDuty || cfg.Platform == platformOpsgenie:
+		return alertSummaryTemplate
+	case cfg.Platform == platformSlack:
+		return slackMessageTemplate
+	case cfg.Platform == platformTeams:
+		return summaryMessageTemplate
+	case cfg.Platform == platformGoogleChat && cfg.GoogleChatSimple:
+		return googleChatTextTemplate
+	case cfg.Platform == platformGoogleChat:
+		return summaryMessageTemplate
+	case cfg.Platform == platformTelegram:
+		return telegramMessageTemplate
+	case cfg.EmbedFields:
+		return fieldsMessageTemplate
+	}
+	return discordMessageTemplate
+}
+
+// envTemplates are the templates read from the environment, together with the
+// DESTINATIONS and ROUTING_RULES entries that carry their own
+type envTemplates struct {
+	// messageText is the MESSAGE_TEMPLATE chosen for LOCALE, "" when unset
+	messageText string
+
+	message       *template.Template
+	title         *template.Template
+	deleteMessage *template.Template
+	deleteTitle   *template.Template
+	body          *template.Template
+	author        *template.Template
+	footer        *template.Template
+
+	routingRules []RoutingRule
+	destinations []Destination
+}
+
+// coldStartTemplates holds the templates compiled by the first loadTemplates
+// call, which checkConfigAtStartup makes during init. Templates are safe to
+// execute concurrently, so every invocation shares them.
+var coldStartTemplates struct {
+	once      sync.Once
+	templates envTemplates
+	err       error
+}
+
+// loadTemplates compiles the templates in the environment on the first call
+// and returns the same result, error included, on every later one. The
+// environment of a Lambda container doesn't change after init.
+func loadTemplates() (envTemplates, error) {
+	coldStartTemplates.once.Do(func() {
+		coldStartTemplates.templates, coldStartTemplates.err = parseEnvTemplates()
+	})
+	return coldStartTemplates.templates, coldStartTemplates.err
+}
+
+// parseEnvTemplates compiles every template variable, choosing the LOCALE
+// variants of MESSAGE_TEMPLATE and TITLE_TEMPLATE when they are set
+func parseEnvTemplates() (envTemplates, error) {
+	locale, err := parseLocale(os.Getenv("LOCALE"))
+	if err != nil {
+		return envTemplates{}, err
+	}
+
+	var t envTemplates
+	// Without MESSAGE_TEMPLATE, loadConfig picks the platform default
+	t.messageText, _ = localeEnv(locale, "MESSAGE_TEMPLATE")
+	t.message = discordMessageTemplate
+	if t.messageText != "" {
+		if t.message, err = parseMessageTemplate(t.messageText); err != nil {
+			return envTemplates{}, err
+		}
+	}
+	titleText, titleSet := localeEnv(locale, "TITLE_TEMPLATE")
+	if t.title, err = parseTitleTemplate(titleText, titleSet); err != nil {
+		return envTemplates{}, err
+	}
+
+	optional := []struct {
+		envName string
+		tmpl    **template.Template
+	}{
+		{"DELETE_MESSAGE_TEMPLATE", &t.deleteMessage},
+		{"DELETE_TITLE", &t.deleteTitle},
+		{"BODY_TEMPLATE", &t.body},
+		{"AUTHOR_NAME", &t.author},
+		{"FOOTER_TEMPLATE", &t.footer},
+	}
+	for _, o := range optional {
+		if *o.tmpl, err = parseOptionalTemplate(o.envName, os.Getenv(o.envName)); err != nil {
+			return envTemplates{}, err
+		}
+	}
+
+	if t.routingRules, err = parseRoutingRules(os.Getenv("ROUTING_RULES")); err != nil {
+		return envTemplates{}, err
+	}
+	if t.destinations, err = parseDestinations(os.Getenv("DESTINATIONS")); err != nil {
+		return envTemplates{}, err
+	}
+	return t, nil
+}
+
+// parseMessageTemplate compiles a MESSAGE_TEMPLATE value, falling back to the default when empty
+func parseMessageTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultMessageTemplate
+	}
+	return parseTemplate("MESSAGE_TEMPLATE", text)
+}
+
+// parseTitleTemplate compiles a TITLE_TEMPLATE value. When the variable is
+// unset the default title is used; when it is set but empty no title is sent
+// and a nil template is returned.
+func parseTitleTemplate(text string, set bool) (*template.Template, error) {
+	if !set {
+		text = messageTitle
+	}
+	if text == "" {
+		return nil, nil
+	}
+	return parseTemplate("TITLE_TEMPLATE", text)
+}
+
+// linkExpiryWarning is appended to messages whose link is close to or past expiry
+const linkExpiryWarning = "⚠️ Link may be expired"
+
+// renderMessage renders the message template for the payload's event type,
+// followed by linkExpiryWarning when the link is within EXPIRY_WARN_SECONDS
+// of expiring
+func renderMessage(cfg Config, payload FilePayload) (string, error) {
+	tmpl := cfg.MessageTemplate
+	if payload.isDeleted() && cfg.DeleteMessageTemplate != nil {
+		tmpl = cfg.DeleteMessageTemplate
+	}
+	text, err := renderTemplate(cfg, tmpl, payload)
+	if err != nil {
+		return "", err
+	}
+	if linkMayBeExpired(cfg, payload, time.Now()) {
+		text += "\n\n" + linkExpiryWarning
+	}
+	return text, nil
+}
+
+// renderTitle renders the title for the payload's event type, or returns ""
+// when titles are disabled
+func renderTitle(cfg Config, payload FilePayload) (string, error) {
+	tmpl := cfg.TitleTemplate
+	if payload.isDeleted() && cfg.DeleteTitleTemplate != nil {
+		tmpl = cfg.DeleteTitleTemplate
+	}
+	if tmpl == nil {
+		return "", nil
+	}
+	return renderTemplate(cfg, tmpl, payload)
+}
+
+// renderFooter renders FOOTER_TEMPLATE for the payload, or uses FOOTER_TEXT
+// when it is unset, and appends the message time for FOOTER_SHOW_TIMESTAMP
+func renderFooter(cfg Config, payload FilePayload) (string, error) {
+	footer := cfg.FooterText
+	if cfg.FooterTemplate != nil {
+		rendered, err := renderTemplate(cfg, cfg.FooterTemplate, payload)
+		if err != nil {
+			return "", err
+		}
+		footer = strings.TrimSpace(rendered)
+	}
+	if !cfg.FooterShowTimestamp {
+		return footer, nil
+	}
+
+	loc := cfg.DisplayTimezone
+	if loc == nil {
+		loc = time.UTC
+	}
+	layout := cfg.TimestampLayout
+	if layout == "" {
+		layout = defaultTimestampLayout
+	}
+	stamp := messageTime(cfg, payload).In(loc).Format(layout)
+	if footer == "" {
+		return stamp, nil
+	}
+	return footer + " • " + stamp, nil
+}
+
+// parseOptionalTemplate compiles an override template, returning nil when the value is empty
+func parseOptionalTemplate(envName, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return parseTemplate(envName, text)
+}
+
+// FileSizeHuman formats the file size for templates, or returns "" when the size is unknown
+func (p FilePayload) FileSizeHuman() string {
+	if p.FileSize <= 0 {
+		return ""
+	}
+	return humanizeBytes(p.FileSize)
+}
+
+// DisplayPath renders the object key as a breadcrumb, e.g. "reports › 2024 › summary.pdf"
+func (p FilePayload) DisplayPath() string {
+	var segments []string
+	for _, segment := range strings.Split(p.FileName, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, " › ")
+}
+
+// CleanURL returns the file URL without its query string, hiding presign
+// signature parameters when the link is displayed as text. Use FileURL as the
+// actual link target, e.g. [{{.CleanURL}}]({{.FileURL}}).
+func (p FilePayload) CleanURL() string {
+	clean := p.FileURL
+	if u, err := url.Parse(p.FileURL); err == nil {
+		u.RawQuery = ""
+		u.Fragment = ""
+		clean = u.String()
+	} else if i := strings.IndexAny(p.FileURL, "?#"); i >= 0 {
+		clean = p.FileURL[:i]
+	}
+
+	if p.textEscaper != nil {
+		return p.textEscaper.Replace(clean)
+	}
+	return clean
+}
+
+// Summary returns the one-line description of the event, such as "A new file
+// has been uploaded to S3.", which the platform defaults open with
+func (p FilePayload) Summary() string {
+	if p.textEscaper != nil {
+		return p.textEscaper.Replace(eventSummary(p))
+	}
+	return eventSummary(p)
+}
+
+// escapePayloadText returns a copy of the payload for template rendering with
+// the text taken from the object escaped for the platform's markup. FileURL
+// stays usable as a link target.
+func escapePayloadText(payload FilePayload, escaper *strings.Replacer) FilePayload {
+	payload.rawFileName = payload.FileName
+	payload.rawExpirationTime = payload.ExpirationTime
+	payload.FileName = escaper.Replace(payload.FileName)
+	payload.Bucket = escaper.Replace(payload.Bucket)
+	payload.ExpirationTime = escaper.Replace(payload.ExpirationTime)
+	payload.textEscaper = escaper
+	return payload
+}
+
+// humanizeBytes formats a byte count using binary units with two decimals (e.g. "4.19 MB")
+func humanizeBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	units := []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+	i := -1
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
+
+// renderTemplate executes a template against the payload fields, with
+// {{.LocalTime}} shown in DISPLAY_TIMEZONE using TIMESTAMP_LAYOUT
+func renderTemplate(cfg Config, tmpl *template.Template, payload FilePayload) (string, error) {
+	payload.displayLocation = cfg.DisplayTimezone
+	payload.displayLayout = cfg.TimestampLayout
+	payload.keyRegex = cfg.KeyRegex
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, payload); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+	}
+	return sb.String(), nil
+}
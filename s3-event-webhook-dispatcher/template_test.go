@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigParsesTemplatesOnce(t *testing.T) {
+	setenvConfig(t, map[string]string{"MESSAGE_TEMPLATE": "{{.FileName}} in {{.Bucket}}"})
+
+	first, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Warm invocations reuse the cold start's template, even if the text changed
+	t.Setenv("MESSAGE_TEMPLATE", "{{.Bucket}}")
+	for i := 0; i < 3; i++ {
+		cfg, err := loadConfig(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.MessageTemplate != first.MessageTemplate {
+			t.Fatalf("invocation %d compiled MESSAGE_TEMPLATE again", i+1)
+		}
+	}
+}
+
+func TestLoadTemplatesKeepsParseError(t *testing.T) {
+	setenvConfig(t, map[string]string{"MESSAGE_TEMPLATE": "{{.FileName"})
+
+	if _, err := loadTemplates(); err == nil || !strings.Contains(err.Error(), "invalid MESSAGE_TEMPLATE") {
+		t.Fatalf("err = %v, want invalid MESSAGE_TEMPLATE", err)
+	}
+	if err := checkConfigAtStartup(context.Background()); err == nil {
+		t.Error("checkConfigAtStartup accepted a malformed template")
+	}
+}
+func TestRenderTemplateReorderedFields(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.Timestamp}} {{.Bucket}}/{{.FileName}} expires after {{.ExpirationTime}}: {{.FileURL}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderTemplate(Config{}, tmpl, FilePayload{
+		FileName:       "report.pdf",
+		FileURL:        "https://example.com/report.pdf",
+		Bucket:         "uploads",
+		ExpirationTime: "1 hour",
+		Timestamp:      "2024-05-01T14:30:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2024-05-01T14:30:00Z uploads/report.pdf expires after 1 hour: https://example.com/report.pdf"
+	if got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+func TestParseMessageTemplateInvalid(t *testing.T) {
+	if _, err := parseMessageTemplate("{{.FileName"); err == nil {
+		t.Error("unclosed action: want an error")
+	}
+	_, err := parseMessageTemplate("{{.FileName | shout}}")
+	if err == nil || !strings.Contains(err.Error(), "available functions") {
+		t.Errorf("unknown function: err = %v, want the available functions listed", err)
+	}
+}
+
+func TestParseMessageTemplateDefault(t *testing.T) {
+	tmpl, err := parseMessageTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderTemplate(Config{}, tmpl, FilePayload{FileName: "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "Download File") {
+		t.Errorf("default template linked a missing URL: %q", got)
+	}
+}
+
+func TestPlatformBuildersRenderMessageTemplate(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.Bucket}} got {{.FileName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := FilePayload{FileName: "a<b>.pdf", Bucket: "uploads", FileURL: "https://example.com/a.pdf", ExpirationTime: "1 hour"}
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		expect string
+	}{
+		{"slack", Config{Platform: platformSlack}, "uploads got a&lt;b&gt;.pdf"},
+		{"slack blocks", Config{Platform: platformSlack, SlackBlocks: true}, "uploads got a&lt;b&gt;.pdf"},
+		{"teams", Config{Platform: platformTeams}, "uploads got a<b>.pdf"},
+		{"google chat card", Config{Platform: platformGoogleChat}, "uploads got a<b>.pdf"},
+		{"google chat text", Config{Platform: platformGoogleChat, GoogleChatSimple: true}, "*New File Uploaded*\nuploads got a&lt;b&gt;.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.MessageTemplate = tmpl
+			cfg.TitleTemplate, _ = parseTitleTemplate("", false)
+			body, err := buildMessage(cfg, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(jsonStrings(t, body), tt.expect) {
+				t.Errorf("body %s\nhas no string %q", body, tt.expect)
+			}
+		})
+	}
+}
+
+func TestPlatformMessageTemplateDefaults(t *testing.T) {
+	payload := FilePayload{FileName: "a.pdf", FileURL: "https://example.com/a.pdf", ExpirationTime: "1 hour"}
+
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Platform: platformDiscord}, "A new file has been uploaded to S3.\n\n**File Name:** a.pdf\n**Temporary Link:** [Download File](https://example.com/a.pdf)\n**Link Expires:** After 1 hour"},
+		{Config{Platform: platformSlack}, "*File Name:* a.pdf\n*Link Expires:* After 1 hour"},
+		{Config{Platform: platformTeams}, "A new file has been uploaded to S3."},
+		{Config{Platform: platformGoogleChat, GoogleChatSimple: true}, "A new file has been uploaded to S3.\n\n*File Name:* a.pdf\n*Temporary Link:* <https://example.com/a.pdf|Download File>\n*Link Expires:* After 1 hour"},
+		{Config{Platform: platformPagerDuty}, "New file in : a.pdf"},
+	}
+	for _, tt := range tests {
+		got, err := renderTemplate(tt.cfg, platformMessageTemplate(tt.cfg), payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s default rendered %q, want %q", tt.cfg.Platform, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigPlatformDefaultTemplate(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"PLATFORM":     platformSlack,
+		"WEBHOOK_URL":  "https://hooks.slack.com/services/T/B/x",
+		"DESTINATIONS": `[{"url": "https://discord.com/api/webhooks/2/y", "platform": "discord"}]`,
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MessageTemplate != slackMessageTemplate {
+		t.Error("PLATFORM=slack without MESSAGE_TEMPLATE didn't get the Slack default")
+	}
+	if dest := destinationConfig(cfg, cfg.Destinations[0]); dest.MessageTemplate != discordMessageTemplate {
+		t.Error("Discord destination kept the Slack default")
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1024:                   "1.00 KB",
+		4393533:                "4.19 MB",
+		5 * 1024 * 1024 * 1024: "5.00 GB",
+	} {
+		if got := humanizeBytes(n); got != want {
+			t.Errorf("humanizeBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestFileSizeHuman(t *testing.T) {
+	tmpl, err := parseMessageTemplate("size={{.FileSizeHuman}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{MessageTemplate: tmpl}
+	for size, want := range map[int64]string{0: "size=", 4393533: "size=4.19 MB"} {
+		if got, err := renderMessage(cfg, FilePayload{FileSize: size}); err != nil || got != want {
+			t.Errorf("size %d rendered %q, %v; want %q", size, got, err, want)
+		}
+	}
+}
+
+func TestDisplayPath(t *testing.T) {
+	for name, want := range map[string]string{
+		"reports/2024/q3/summary.pdf": "reports › 2024 › q3 › summary.pdf",
+		"/leading//double/slash.txt":  "leading › double › slash.txt",
+		"top.txt":                     "top.txt",
+	} {
+		if got := (FilePayload{FileName: name}).DisplayPath(); got != want {
+			t.Errorf("DisplayPath(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCleanURLLinksFullURL(t *testing.T) {
+	presigned := "https://bucket.s3.amazonaws.com/reports/q3.pdf?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Signature=abc123"
+	if got := (FilePayload{FileURL: presigned}).CleanURL(); got != "https://bucket.s3.amazonaws.com/reports/q3.pdf" {
+		t.Errorf("CleanURL = %q", got)
+	}
+
+	tmpl, err := parseMessageTemplate("[{{.CleanURL}}]({{.FileURL}})")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderMessage(Config{MessageTemplate: tmpl}, FilePayload{FileURL: presigned})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[https://bucket.s3.amazonaws.com/reports/q3.pdf](" + presigned + ")"; got != want {
+		t.Errorf("link = %q, want %q", got, want)
+	}
+}
+
+func TestTitleTemplate(t *testing.T) {
+	payload := FilePayload{FileName: "report.pdf", FileURL: "https://example.com/a", Bucket: "invoices"}
+
+	setenvConfig(t, map[string]string{"TITLE_TEMPLATE": "Upload to {{.Bucket}}"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := decodeDiscordMessage(t, body).Embeds[0].Title; title != "Upload to invoices" {
+		t.Errorf("title = %q, want %q", title, "Upload to invoices")
+	}
+
+	// A set but empty TITLE_TEMPLATE leaves the title out of the embed
+	setenvConfig(t, map[string]string{"TITLE_TEMPLATE": ""})
+	cfg, err = loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), `"title"`) {
+		t.Errorf("body %s has a title, want it omitted", body)
+	}
+}
+
+func TestTitleTemplateDefault(t *testing.T) {
+	setenvConfig(t, nil)
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := renderTitle(cfg, FilePayload{FileName: "report.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != messageTitle {
+		t.Errorf("title = %q, want the default %q", title, messageTitle)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{.Bucket | upper}}`, "MY-BUCKET"},
+		{`{{"ABC" | lower}}`, "abc"},
+		{`{{"hello big world" | title}}`, "Hello Big World"},
+		{`{{"  x  " | trim}}`, "x"},
+		{`{{.FileName | truncate 4}}`, "abc…"},
+		{`{{.FileName | truncate 40}}`, "abcdefgh"},
+		{`{{.ExpirationTime | default "unknown"}}`, "unknown"},
+		{`{{.Bucket | default "unknown"}}`, "my-bucket"},
+		{`{{.FileSize | default "?"}}`, "?"},
+		{`{{json .FileName}}`, `"abcdefgh"`},
+	}
+	payload := FilePayload{Bucket: "my-bucket", FileName: "abcdefgh"}
+	for _, tt := range tests {
+		tmpl, err := parseTemplate("MESSAGE_TEMPLATE", tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := renderTemplate(Config{}, tmpl, payload)
+		if err != nil || got != tt.want {
+			t.Errorf("%s rendered %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseTemplateUnknownFunction(t *testing.T) {
+	_, err := parseTemplate("MESSAGE_TEMPLATE", "{{.Bucket | shout}}")
+	if err == nil {
+		t.Fatal("parseTemplate accepted an unknown function")
+	}
+	want := "available functions: " + strings.Join(templateFuncNames(), ", ")
+	if !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "upper") {
+		t.Errorf("err = %v, want it to list the %s", err, want)
+	}
+}
+
+func TestTemplateConditionallyOmitsLink(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.FileName}}{{if .FileURL}} [Download File]({{.FileURL}}){{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(testConfig(t, "https://discord.com/api/webhooks/1/token"))
+	d.Config.MessageTemplate = tmpl
+
+	tests := []struct {
+		payload FilePayload
+		want    string
+	}{
+		{FilePayload{FileName: "a.txt", FileURL: "https://example.com/a"}, "a.txt [Download File](https://example.com/a)"},
+		{FilePayload{FileName: "a.txt", EventType: eventTypeDeleted}, "a.txt"},
+	}
+	for _, tt := range tests {
+		body, err := d.BuildMessage(tt.payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decodeDiscordMessage(t, body).Embeds[0].Description; got != tt.want {
+			t.Errorf("description = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestDefaultTemplateOmitsEmptyLink(t *testing.T) {
+	tmpl, err := parseMessageTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderTemplate(Config{}, tmpl, FilePayload{FileName: "a.txt", EventType: eventTypeDeleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "Download File") || strings.Contains(got, "()") {
+		t.Errorf("rendered %q, want no link for a payload without FileURL", got)
+	}
+}
+
+func TestFooterTemplateFromConfig(t *testing.T) {
+	setenvConfig(t, map[string]string{"FOOTER_TEMPLATE": "  {{.Bucket}} • {{.FileName}}\n"})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer, err := renderFooter(cfg, FilePayload{Bucket: "uploads", FileName: "q1.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if footer != "uploads • q1.pdf" {
+		t.Errorf("footer = %q, want the trimmed template output", footer)
+	}
+
+	setenvConfig(t, map[string]string{"FOOTER_TEMPLATE": "{{.Bucket"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted an invalid FOOTER_TEMPLATE")
+	}
+}
+
+func TestRenderFooterAppendsTimestamp(t *testing.T) {
+	payload := FilePayload{Bucket: "uploads", Timestamp: "2024-07-01T10:30:00Z"}
+	cfg := Config{FooterText: "Uploads", TimestampSource: timestampSourceEvent}
+	tests := []struct {
+		name string
+		cfg  func(Config) Config
+		want string
+	}{
+		{"off", func(c Config) Config { return c }, "Uploads"},
+		{"static text", func(c Config) Config { c.FooterShowTimestamp = true; return c }, "Uploads • 2024-07-01 10:30 UTC"},
+		{"template", func(c Config) Config {
+			c.FooterShowTimestamp = true
+			c.FooterTemplate, _ = parseOptionalTemplate("FOOTER_TEMPLATE", "{{.Bucket}}")
+			return c
+		}, "uploads • 2024-07-01 10:30 UTC"},
+		{"no text", func(c Config) Config { c.FooterText = ""; c.FooterShowTimestamp = true; return c }, "2024-07-01 10:30 UTC"},
+	}
+	for _, tt := range tests {
+		got, err := renderFooter(tt.cfg(cfg), payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: footer = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFooterTimestampIndependentOfEmbedTimestamp(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.TimestampSource = timestampSourceEvent
+	payload := FilePayload{FileName: "q1.pdf", FileURL: "https://example.com/a", Timestamp: "2024-07-01T10:30:00Z"}
+
+	cfg.EmbedTimestamp, cfg.FooterShowTimestamp = false, true
+	body, err := buildDiscordMessage(cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embed := decodeDiscordMessage(t, body).Embeds[0]
+	if embed.Timestamp != "" || embed.Footer.Text != footerText+" • 2024-07-01 10:30 UTC" {
+		t.Errorf("timestamp = %q, footer = %q, want only the footer timestamp", embed.Timestamp, embed.Footer.Text)
+	}
+
+	cfg.EmbedTimestamp, cfg.FooterShowTimestamp = true, false
+	if body, err = buildDiscordMessage(cfg, payload); err != nil {
+		t.Fatal(err)
+	}
+	embed = decodeDiscordMessage(t, body).Embeds[0]
+	if embed.Timestamp != "2024-07-01T10:30:00Z" || embed.Footer.Text != footerText {
+		t.Errorf("timestamp = %q, footer = %q, want only the embed timestamp", embed.Timestamp, embed.Footer.Text)
+	}
+}
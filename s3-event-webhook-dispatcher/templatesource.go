@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// maxTemplateObjectBytes bounds how much of the TEMPLATE_S3_URI object is read
+const maxTemplateObjectBytes = 64 << 10
+
+// templateObjectCache holds the message template read from TEMPLATE_S3_URI
+var templateObjectCache valueCache
+
+// compiledTemplateObject holds the template compiled from the TEMPLATE_S3_URI
+// object, so warm invocations reuse it like the environment's templates
+var compiledTemplateObject struct {
+	sync.Mutex
+	uri  string
+	tmpl *template.Template
+}
+
+// loadTemplateObject returns the compiled message template stored at uri,
+// fetching and compiling it only on the first successful call for that URI
+func loadTemplateObject(ctx context.Context, uri string) (*template.Template, error) {
+	compiledTemplateObject.Lock()
+	defer compiledTemplateObject.Unlock()
+
+	if compiledTemplateObject.uri == uri && compiledTemplateObject.tmpl != nil {
+		return compiledTemplateObject.tmpl, nil
+	}
+
+	text, err := resolveTemplateObject(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := parseTemplate("TEMPLATE_S3_URI", text)
+	if err != nil {
+		return nil, err
+	}
+	compiledTemplateObject.uri, compiledTemplateObject.tmpl = uri, tmpl
+	return tmpl, nil
+}
+
+// parseS3URI splits an s3://bucket/key URI into its bucket and key
+func parseS3URI(raw string) (bucket, key string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+		return "", "", fmt.Errorf("invalid TEMPLATE_S3_URI %q: must be s3://bucket/key", raw)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// resolveTemplateObject returns the message template stored at an s3://bucket/key
+// URI, fetching it only on the first call for that URI
+func resolveTemplateObject(ctx context.Context, uri string) (string, error) {
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		return "", err
+	}
+
+	return templateObjectCache.get(uri, func() (string, error) {
+		client, err := newS3ObjectClient(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create S3 client: %w", err)
+		}
+
+		object, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch message template from %s: %w", uri, err)
+		}
+		defer object.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(object.Body, maxTemplateObjectBytes+1))
+		if err != nil {
+			return "", fmt.Errorf("failed to read message template from %s: %w", uri, err)
+		}
+		if len(body) > maxTemplateObjectBytes {
+			return "", fmt.Errorf("message template at %s exceeds %d bytes", uri, maxTemplateObjectBytes)
+		}
+		if strings.TrimSpace(string(body)) == "" {
+			return "", fmt.Errorf("message template at %s is empty", uri)
+		}
+		return string(body), nil
+	})
+}
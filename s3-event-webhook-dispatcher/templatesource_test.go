@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resetTemplateObject empties the TEMPLATE_S3_URI caches until the test ends
+func resetTemplateObject(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		templateObjectCache = valueCache{}
+		compiledTemplateObject.Lock()
+		compiledTemplateObject.uri, compiledTemplateObject.tmpl = "", nil
+		compiledTemplateObject.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestLoadConfigTemplateFromS3(t *testing.T) {
+	resetTemplateObject(t)
+	fake := &fakeS3Objects{objects: map[string]string{"templates/uploads.tmpl": "Central: {{.FileName}} in {{.Bucket}}"}}
+	stubS3Objects(t, fake)
+	setenvConfig(t, map[string]string{
+		"TEMPLATE_S3_URI":  "s3://templates/uploads.tmpl",
+		"MESSAGE_TEMPLATE": "Local: {{.FileName}}",
+	})
+
+	// Warm invocations reuse the object fetched at cold start
+	for i := 0; i < 2; i++ {
+		cfg, err := loadConfig(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.CustomMessageTemplate {
+			t.Error("CustomMessageTemplate = false for a TEMPLATE_S3_URI template")
+		}
+		text, err := renderMessage(cfg, FilePayload{FileName: "a.txt", Bucket: "invoices"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "Central: a.txt in invoices" {
+			t.Errorf("rendered %q, want the S3 template to override MESSAGE_TEMPLATE", text)
+		}
+	}
+	if fake.gets != 1 {
+		t.Errorf("fetched the template %d times, want once per container", fake.gets)
+	}
+}
+
+func TestCheckConfigAtStartupTemplateFetchFails(t *testing.T) {
+	resetTemplateObject(t)
+	stubS3Objects(t, &fakeS3Objects{objects: map[string]string{}})
+	setenvConfig(t, map[string]string{"TEMPLATE_S3_URI": "s3://templates/missing.tmpl"})
+
+	if err := checkConfigAtStartup(context.Background()); err == nil || !strings.Contains(err.Error(), "s3://templates/missing.tmpl") {
+		t.Errorf("checkConfigAtStartup = %v, want the fetch failure naming the URI", err)
+	}
+}
+
+func TestResolveTemplateObjectRejectsBadObjects(t *testing.T) {
+	resetTemplateObject(t)
+	stubS3Objects(t, &fakeS3Objects{objects: map[string]string{
+		"templates/empty.tmpl": " \n",
+		"templates/huge.tmpl":  strings.Repeat("x", maxTemplateObjectBytes+1),
+	}})
+	for _, uri := range []string{"s3://templates/empty.tmpl", "s3://templates/huge.tmpl"} {
+		if _, err := resolveTemplateObject(context.Background(), uri); err == nil {
+			t.Errorf("resolveTemplateObject(%q): want an error", uri)
+		}
+	}
+	if _, err := loadTemplateObject(context.Background(), "s3://templates/broken.tmpl"); err == nil {
+		t.Error("loadTemplateObject accepted a missing object")
+	}
+}
+
+func TestParseS3URI(t *testing.T) {
+	bucket, key, err := parseS3URI("s3://templates/team/uploads.tmpl")
+	if err != nil || bucket != "templates" || key != "team/uploads.tmpl" {
+		t.Errorf("parseS3URI = %q, %q, %v", bucket, key, err)
+	}
+	for _, raw := range []string{"https://templates/uploads.tmpl", "s3://templates", "s3://templates/", "s3:///uploads.tmpl"} {
+		if _, _, err := parseS3URI(raw); err == nil {
+			t.Errorf("parseS3URI(%q): want an error", raw)
+		}
+	}
+}
+
+func TestLoadTemplateObjectConcurrent(t *testing.T) {
+	resetTemplateObject(t)
+	fake := &fakeS3Objects{objects: map[string]string{"templates/uploads.tmpl": "{{.FileName}}"}}
+	stubS3Objects(t, fake)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := loadTemplateObject(context.Background(), "s3://templates/uploads.tmpl"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fake.gets != 1 {
+		t.Errorf("fetched the template %d times, want once", fake.gets)
+	}
+}
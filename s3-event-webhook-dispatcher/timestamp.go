@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// Supported values for the TIMESTAMP_SOURCE environment variable
+const (
+	timestampSourceEvent = "event"
+	timestampSourceNow   = "now"
+)
+
+// payloadTimeLayouts are tried in order when parsing FilePayload.Timestamp.
+// The Link Generator's Python isoformat() output has no zone and is UTC.
+var payloadTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// parseTimestampSource validates a TIMESTAMP_SOURCE value, defaulting to the event time
+func parseTimestampSource(raw string) (string, error) {
+	switch source := strings.ToLower(strings.TrimSpace(raw)); source {
+	case "":
+		return timestampSourceEvent, nil
+	case timestampSourceEvent, timestampSourceNow:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unsupported TIMESTAMP_SOURCE %q (supported: event, now)", raw)
+	}
+}
+
+// parsePayloadTime parses the payload timestamp, reporting false when it is missing or malformed
+func parsePayloadTime(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range payloadTimeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// messageTime returns the time a message should display: the upload time from
+// the payload, or the dispatch time when configured or when the payload has none
+func messageTime(cfg Config, payload FilePayload) time.Time {
+	if cfg.TimestampSource != timestampSourceNow {
+		if t, ok := parsePayloadTime(payload.Timestamp); ok {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// UploadedAgo describes how long ago the payload timestamp was, e.g.
+// "3 minutes ago", or returns "" when the timestamp is missing or malformed
+func (p FilePayload) UploadedAgo() string {
+	t, ok := parsePayloadTime(p.Timestamp)
+	if !ok {
+		return ""
+	}
+	return relativeTime(time.Since(t))
+}
+
+// relativeTime renders an elapsed duration in its largest whole unit. Anything
+// under a second, including timestamps slightly ahead of our clock, is "just now".
+func relativeTime(elapsed time.Duration) string {
+	switch {
+	case elapsed < time.Second:
+		return "just now"
+	case elapsed < time.Minute:
+		return pluralAgo(int(elapsed/time.Second), "second")
+	case elapsed < time.Hour:
+		return pluralAgo(int(elapsed/time.Minute), "minute")
+	case elapsed < 24*time.Hour:
+		return pluralAgo(int(elapsed/time.Hour), "hour")
+	default:
+		return pluralAgo(int(elapsed/(24*time.Hour)), "day")
+	}
+}
+
+// pluralAgo formats a count of units as "1 minute ago" or "3 minutes ago"
+func pluralAgo(n int, unit string) string {
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
+
+// defaultTimestampLayout formats {{.LocalTime}} when TIMESTAMP_LAYOUT is unset
+const defaultTimestampLayout = "2006-01-02 15:04 MST"
+
+// parseDisplayTimezone loads the DISPLAY_TIMEZONE location, defaulting to UTC.
+// The time zone database is embedded, since the Lambda runtime may not ship one.
+func parseDisplayTimezone(raw string) (*time.Location, error) {
+	name := strings.TrimSpace(raw)
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DISPLAY_TIMEZONE %q: expected an IANA time zone name such as Europe/Berlin", raw)
+	}
+	return loc, nil
+}
+
+// LocalTime renders the payload timestamp in DISPLAY_TIMEZONE using
+// TIMESTAMP_LAYOUT, falling back to the raw timestamp when it can't be parsed
+func (p FilePayload) LocalTime() string {
+	t, ok := parsePayloadTime(p.Timestamp)
+	if !ok {
+		return p.Timestamp
+	}
+	loc := p.displayLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	layout := p.displayLayout
+	if layout == "" {
+		layout = defaultTimestampLayout
+	}
+	return t.In(loc).Format(layout)
+}
+
+// DiscordTimestamp renders the payload timestamp as Discord markup that each
+// client shows as a full date and time in the reader's own time zone, falling
+// back to the raw timestamp when it can't be parsed
+func (p FilePayload) DiscordTimestamp() string {
+	return discordTimestamp(p.Timestamp, "f")
+}
+
+// DiscordTimestampRelative is DiscordTimestamp shown as a relative time, e.g. "3 minutes ago"
+func (p FilePayload) DiscordTimestampRelative() string {
+	return discordTimestamp(p.Timestamp, "R")
+}
+
+// discordTimestamp wraps the unix time of raw in Discord's <t:seconds:style> markup
+func discordTimestamp(raw, style string) string {
+	t, ok := parsePayloadTime(raw)
+	if !ok {
+		return raw
+	}
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
+
+// expiryUnits maps the units of an ExpirationTime phrase such as "24 hours" to their length
+var expiryUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// parseExpiresIn parses a relative ExpirationTime, either a phrase like
+// "24 hours" as the Link Generator writes it or a Go duration like "24h"
+func parseExpiresIn(raw string) (time.Duration, bool) {
+	raw = strings.TrimSpace(raw)
+	if d, err := time.ParseDuration(raw); err == nil {
+		return d, true
+	}
+
+	count, unit, ok := strings.Cut(raw, " ")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, false
+	}
+	length, ok := expiryUnits[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(unit)), "s")]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(n) * length, true
+}
+
+// linkValidity returns how much longer the payload's presigned link is valid
+// at now, negative once it has expired. ExpirationTime is either an absolute
+// time or a duration counted from Timestamp; false means neither could be read.
+func linkValidity(payload FilePayload, now time.Time) (time.Duration, bool) {
+	if expires, ok := parsePayloadTime(payload.ExpirationTime); ok {
+		return expires.Sub(now), true
+	}
+	expiresIn, ok := parseExpiresIn(payload.ExpirationTime)
+	if !ok {
+		return 0, false
+	}
+	issued, ok := parsePayloadTime(payload.Timestamp)
+	if !ok {
+		return 0, false
+	}
+	return issued.Add(expiresIn).Sub(now), true
+}
+
+// linkMayBeExpired reports whether the link has less than EXPIRY_WARN_SECONDS left
+func linkMayBeExpired(cfg Config, payload FilePayload, now time.Time) bool {
+	if cfg.ExpiryWarn <= 0 || payload.isDeleted() || payload.FileURL == "" {
+		return false
+	}
+	if payload.textEscaper != nil {
+		payload.ExpirationTime = payload.rawExpirationTime
+	}
+	remaining, ok := linkValidity(payload, now)
+	return ok && remaining < cfg.ExpiryWarn
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		stamp   string
+		want    time.Time
+		fromNow bool
+	}{
+		{"event time", timestampSourceEvent, "2025-05-17T10:00:00Z", time.Date(2025, 5, 17, 10, 0, 0, 0, time.UTC), false},
+		{"event time with offset", timestampSourceEvent, "2025-05-17T10:00:00+02:00", time.Date(2025, 5, 17, 8, 0, 0, 0, time.UTC), false},
+		{"zoneless isoformat", timestampSourceEvent, "2025-05-17T10:00:00.123456", time.Date(2025, 5, 17, 10, 0, 0, 123456000, time.UTC), false},
+		{"now source ignores payload", timestampSourceNow, "2025-05-17T10:00:00Z", time.Time{}, true},
+		{"malformed timestamp", timestampSourceEvent, "yesterday", time.Time{}, true},
+		{"missing timestamp", timestampSourceEvent, "", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got := messageTime(Config{TimestampSource: tt.source}, FilePayload{Timestamp: tt.stamp})
+		if tt.fromNow {
+			if since := time.Since(got); since < 0 || since > time.Minute {
+				t.Errorf("%s: messageTime = %v, want the current time", tt.name, got)
+			}
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: messageTime = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimestampSource(t *testing.T) {
+	for raw, want := range map[string]string{"": timestampSourceEvent, "event": timestampSourceEvent, " NOW ": timestampSourceNow} {
+		got, err := parseTimestampSource(raw)
+		if err != nil || got != want {
+			t.Errorf("parseTimestampSource(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseTimestampSource("upload"); err == nil {
+		t.Error("parseTimestampSource accepted an unsupported source")
+	}
+}
+
+func TestBuildDiscordMessageEventTimestamp(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	cfg.TimestampSource = timestampSourceEvent
+	body, err := buildDiscordMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", Timestamp: "2025-05-17T10:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeDiscordMessage(t, body).Embeds[0].Timestamp; got != "2025-05-17T10:00:00Z" {
+		t.Errorf("embed timestamp = %q, want the upload time", got)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	tests := []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{-30 * time.Second, "just now"},
+		{0, "just now"},
+		{500 * time.Millisecond, "just now"},
+		{time.Second, "1 second ago"},
+		{45 * time.Second, "45 seconds ago"},
+		{time.Minute, "1 minute ago"},
+		{3*time.Minute + 40*time.Second, "3 minutes ago"},
+		{2 * time.Hour, "2 hours ago"},
+		{36 * time.Hour, "1 day ago"},
+		{10 * 24 * time.Hour, "10 days ago"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(tt.elapsed); got != tt.want {
+			t.Errorf("relativeTime(%v) = %q, want %q", tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestUploadedAgo(t *testing.T) {
+	stamp := func(d time.Duration) string { return time.Now().Add(d).UTC().Format(time.RFC3339) }
+	tests := []struct {
+		timestamp string
+		want      string
+	}{
+		{stamp(-3*time.Minute - 5*time.Second), "3 minutes ago"},
+		{stamp(-5 * time.Hour), "5 hours ago"},
+		{stamp(2 * time.Minute), "just now"}, // the Link Generator's clock is ahead of ours
+		{"not a time", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (FilePayload{Timestamp: tt.timestamp}).UploadedAgo(); got != tt.want {
+			t.Errorf("UploadedAgo(%q) = %q, want %q", tt.timestamp, got, tt.want)
+		}
+	}
+}
+
+func TestDiscordTimestamp(t *testing.T) {
+	payload := FilePayload{Timestamp: "2023-11-14T22:13:20Z"}
+	if got := payload.DiscordTimestamp(); got != "<t:1700000000:f>" {
+		t.Errorf("DiscordTimestamp = %q, want <t:1700000000:f>", got)
+	}
+	if got := payload.DiscordTimestampRelative(); got != "<t:1700000000:R>" {
+		t.Errorf("DiscordTimestampRelative = %q, want <t:1700000000:R>", got)
+	}
+
+	// Offsets and the Link Generator's zoneless UTC timestamps convert to the same instant
+	for _, stamp := range []string{"2023-11-15T00:13:20+02:00", "2023-11-14T22:13:20.000000"} {
+		if got := (FilePayload{Timestamp: stamp}).DiscordTimestamp(); got != "<t:1700000000:f>" {
+			t.Errorf("DiscordTimestamp(%q) = %q, want <t:1700000000:f>", stamp, got)
+		}
+	}
+
+	unparsed := FilePayload{Timestamp: "last Tuesday"}
+	if got := unparsed.DiscordTimestamp(); got != "last Tuesday" {
+		t.Errorf("DiscordTimestamp = %q, want the raw timestamp", got)
+	}
+	if got := unparsed.DiscordTimestampRelative(); got != "last Tuesday" {
+		t.Errorf("DiscordTimestampRelative = %q, want the raw timestamp", got)
+	}
+}
+
+func TestRenderDiscordTimestampTemplate(t *testing.T) {
+	tmpl, err := parseMessageTemplate("Uploaded {{.DiscordTimestampRelative}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderTemplate(Config{}, tmpl, FilePayload{Timestamp: "2023-11-14T22:13:20Z"})
+	if err != nil || got != "Uploaded <t:1700000000:R>" {
+		t.Errorf("renderTemplate = %q, %v", got, err)
+	}
+}
+
+func TestLinkMayBeExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg := Config{ExpiryWarn: 10 * time.Minute}
+	tests := []struct {
+		name       string
+		expiration string
+		timestamp  string
+		want       bool
+	}{
+		{"fresh duration", "24 hours", "2024-05-01T11:00:00Z", false},
+		{"near expiry duration", "1h", "2024-05-01T11:55:00Z", false},
+		{"near expiry phrase", "1 hour", "2024-05-01T11:05:00Z", true},
+		{"expired phrase", "30 minutes", "2024-05-01T10:00:00Z", true},
+		{"fresh absolute", "2024-05-02T12:00:00Z", "", false},
+		{"near expiry absolute", "2024-05-01T12:05:00Z", "", true},
+		{"expired absolute", "2024-05-01T11:00:00Z", "", true},
+		{"duration without timestamp", "1 hour", "", false},
+		{"unreadable", "soon", "2024-05-01T11:00:00Z", false},
+	}
+	for _, tt := range tests {
+		payload := FilePayload{FileURL: "https://example.com/a", ExpirationTime: tt.expiration, Timestamp: tt.timestamp}
+		if got := linkMayBeExpired(cfg, payload, now); got != tt.want {
+			t.Errorf("%s: linkMayBeExpired = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	expired := FilePayload{FileURL: "https://example.com/a", ExpirationTime: "2024-05-01T11:00:00Z"}
+	if linkMayBeExpired(Config{}, expired, now) {
+		t.Error("warned about an expired link with EXPIRY_WARN_SECONDS unset")
+	}
+	expired.FileURL = ""
+	if linkMayBeExpired(cfg, expired, now) {
+		t.Error("warned about the expiry of a payload without a link")
+	}
+}
+
+func TestTelegramMessageWarnsOfExpiredLink(t *testing.T) {
+	cfg := Config{Platform: platformTelegram, TelegramChatID: "-1001234567890", ExpiryWarn: 10 * time.Minute}
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+
+	// MarkdownV2 escapes the - and . of an absolute expiration, which must
+	// still be read as a time
+	body, err := buildTelegramMessage(cfg, FilePayload{
+		FileName:       "q1.pdf",
+		FileURL:        "https://example.com/q1.pdf",
+		ExpirationTime: "2024-01-01T00:00:00.000Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message TelegramMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(message.Text, "\n\n"+linkExpiryWarning) {
+		t.Errorf("text = %q, want the expiry warning", message.Text)
+	}
+	if !strings.Contains(message.Text, `2024\-01\-01T00:00:00\.000Z`) {
+		t.Errorf("text = %q, want the expiration escaped for MarkdownV2", message.Text)
+	}
+}
+
+func TestLinkValidity(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	remaining, ok := linkValidity(FilePayload{ExpirationTime: "2 days", Timestamp: "2024-05-01T12:00:00Z"}, now)
+	if !ok || remaining != 48*time.Hour {
+		t.Errorf("linkValidity = %v, %v, want 48h", remaining, ok)
+	}
+	remaining, ok = linkValidity(FilePayload{ExpirationTime: "2024-05-01T11:30:00Z"}, now)
+	if !ok || remaining != -30*time.Minute {
+		t.Errorf("linkValidity of an expired link = %v, %v, want -30m", remaining, ok)
+	}
+}
+
+func TestRenderMessageExpiryWarning(t *testing.T) {
+	tmpl, err := parseMessageTemplate("{{.FileName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{MessageTemplate: tmpl, ExpiryWarn: 5 * time.Minute}
+	issued := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+
+	text, err := renderMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", ExpirationTime: "1 hour", Timestamp: issued})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "a.txt\n\n"+linkExpiryWarning {
+		t.Errorf("expired link rendered %q, want the expiry warning appended", text)
+	}
+
+	text, err = renderMessage(cfg, FilePayload{FileName: "a.txt", FileURL: "https://example.com/a", ExpirationTime: "24 hours", Timestamp: issued})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "a.txt" {
+		t.Errorf("fresh link rendered %q, want no warning", text)
+	}
+}
+
+func TestRenderLocalTime(t *testing.T) {
+	setenvConfig(t, map[string]string{
+		"DISPLAY_TIMEZONE": "Europe/Berlin",
+		"TIMESTAMP_LAYOUT": "02.01.2006 15:04 MST",
+		"MESSAGE_TEMPLATE": "Uploaded {{.LocalTime}}",
+	})
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Berlin is two hours ahead of UTC in summer and one in winter
+	for stamp, want := range map[string]string{
+		"2024-07-01T10:30:00Z": "Uploaded 01.07.2024 12:30 CEST",
+		"2024-01-15T10:30:00Z": "Uploaded 15.01.2024 11:30 CET",
+	} {
+		text, err := renderMessage(cfg, FilePayload{FileName: "a.txt", Timestamp: stamp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != want {
+			t.Errorf("rendered %s as %q, want %q", stamp, text, want)
+		}
+	}
+}
+
+func TestLocalTimeDefaults(t *testing.T) {
+	if got := (FilePayload{Timestamp: "2024-07-01T10:30:00+02:00"}).LocalTime(); got != "2024-07-01 08:30 UTC" {
+		t.Errorf("LocalTime without a zone or layout = %q, want UTC in the default layout", got)
+	}
+	if got := (FilePayload{Timestamp: "yesterday"}).LocalTime(); got != "yesterday" {
+		t.Errorf("LocalTime of an unparseable timestamp = %q, want it unchanged", got)
+	}
+}
+
+func TestParseDisplayTimezone(t *testing.T) {
+	if loc, err := parseDisplayTimezone(""); err != nil || loc != time.UTC {
+		t.Errorf("parseDisplayTimezone(\"\") = %v, %v, want UTC", loc, err)
+	}
+	if loc, err := parseDisplayTimezone(" America/New_York "); err != nil || loc.String() != "America/New_York" {
+		t.Errorf("parseDisplayTimezone = %v, %v", loc, err)
+	}
+	if _, err := parseDisplayTimezone("Mars/Olympus_Mons"); err == nil {
+		t.Error("parseDisplayTimezone accepted an unknown zone")
+	}
+
+	setenvConfig(t, map[string]string{"DISPLAY_TIMEZONE": "CEST+2"})
+	if _, err := loadConfig(context.Background()); err == nil {
+		t.Error("loadConfig accepted an invalid DISPLAY_TIMEZONE")
+	}
+}
+
+func TestRenderFooterTimestampInDisplayTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{FooterText: "Uploads", FooterShowTimestamp: true, DisplayTimezone: berlin, TimestampLayout: "15:04", TimestampSource: timestampSourceEvent}
+	footer, err := renderFooter(cfg, FilePayload{Timestamp: "2024-07-01T10:30:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if footer != "Uploads • 12:30" {
+		t.Errorf("footer = %q, want the time in Berlin", footer)
+	}
+}
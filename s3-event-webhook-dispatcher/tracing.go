@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+// dispatchSegmentName names the X-Ray subsegment wrapping each webhook delivery
+const dispatchSegmentName = "webhook.dispatch"
+
+// sendTraced delivers the body to one webhook inside a webhook.dispatch
+// subsegment when ENABLE_XRAY is set, annotated with the host and final
+// status code. Without X-Ray it is just sendWithRetry.
+func (d *Dispatcher) sendTraced(ctx context.Context, webhookURL string, body []byte, contentType string) (deliveryResult, error) {
+	if !d.Config.EnableXRay {
+		return d.sendWithRetry(ctx, webhookURL, body, contentType)
+	}
+
+	var result deliveryResult
+	err := xray.Capture(ctx, dispatchSegmentName, func(ctx context.Context) error {
+		// Without a trace header or active segment, as in local runs or with
+		// Lambda tracing off, Capture runs this with no segment at all
+		seg := xray.GetSegment(ctx)
+		if seg != nil {
+			seg.AddAnnotation("webhookHost", webhookHost(webhookURL))
+		}
+
+		var err error
+		result, err = d.sendWithRetry(ctx, webhookURL, body, contentType)
+
+		if seg != nil {
+			seg.AddAnnotation("statusCode", result.StatusCode)
+			seg.AddMetadata("attempts", result.Attempts)
+		}
+		return err
+	})
+	return result, err
+}
+
+// xrayURLRedactor sits beneath the X-Ray round tripper and replaces the URL it
+// recorded on the HTTP subsegment with scheme and host, since webhook paths
+// carry the token
+type xrayURLRedactor struct {
+	base http.RoundTripper
+}
+
+// RoundTrip redacts the traced URL and forwards the request unchanged
+func (t xrayURLRedactor) RoundTrip(req *http.Request) (*http.Response, error) {
+	if seg := xray.GetSegment(req.Context()); seg != nil {
+		seg.Lock()
+		seg.GetHTTP().GetRequest().URL = req.URL.Scheme + "://" + req.URL.Host
+		seg.Unlock()
+	}
+	return t.base.RoundTrip(req)
+}
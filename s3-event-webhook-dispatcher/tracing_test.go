@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+func TestNewHTTPClientXRayRoundTripper(t *testing.T) {
+	if _, ok := newHTTPClient(transportSettings{}).Transport.(*http.Transport); !ok {
+		t.Error("transport wrapped although ENABLE_XRAY is off")
+	}
+	if _, ok := newHTTPClient(transportSettings{EnableXRay: true}).Transport.(*http.Transport); ok {
+		t.Error("transport not wrapped by the X-Ray round tripper with ENABLE_XRAY")
+	}
+}
+
+func TestSendTracedRecordsSubsegment(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.EnableXRay = true
+	d := newTestDispatcher(cfg, srv)
+	d.Client.Transport = xray.RoundTripper(xrayURLRedactor{base: d.Client.Transport})
+
+	ctx, seg := xray.BeginSegment(context.Background(), "test")
+	result, err := d.sendTraced(ctx, srv.URL+"/api/webhooks/1/TOKEN", []byte(`{}`), jsonContentType)
+	seg.Close(nil)
+	if err != nil || result.StatusCode != http.StatusNoContent {
+		t.Fatalf("sendTraced = %+v, %v", result, err)
+	}
+
+	trace, err := json.Marshal(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(trace), dispatchSegmentName) {
+		t.Errorf("trace has no %s subsegment: %s", dispatchSegmentName, trace)
+	}
+	if strings.Contains(string(trace), "TOKEN") || strings.Contains(string(trace), "/api/webhooks/") {
+		t.Errorf("trace contains the webhook path: %s", trace)
+	}
+}
+
+func TestSendTracedWithoutSegment(t *testing.T) {
+	srv := newWebhookServer(t)
+	cfg := testConfig(t, srv.URL)
+	cfg.EnableXRay = true
+	d := newTestDispatcher(cfg, srv)
+
+	// Local runs have no trace context, which must not break delivery
+	if _, err := d.sendTraced(context.Background(), srv.URL, []byte(`{}`), jsonContentType); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
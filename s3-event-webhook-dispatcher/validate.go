@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ValidateConfig reports the first setting that leaves the dispatcher unable
+// to deliver anything, such as a missing webhook URL or template, or a
+// combination of settings that can't be verified while parsing a single value
+func ValidateConfig(cfg Config) error {
+	if len(cfg.WebhookURLs) == 0 && len(cfg.Destinations) == 0 {
+		return fmt.Errorf("%w: neither WEBHOOK_URL, WEBHOOK_URLS nor DESTINATIONS environment variable is set", ErrNoWebhookURL)
+	}
+	if cfg.MessageTemplate == nil {
+		return fmt.Errorf("MESSAGE_TEMPLATE is not set")
+	}
+	if cfg.Platform == platformGeneric && cfg.BodyTemplate == nil {
+		return fmt.Errorf("BODY_TEMPLATE must be set when PLATFORM is %q", platformGeneric)
+	}
+	if cfg.Platform == platformTelegram && cfg.TelegramChatID == "" {
+		return fmt.Errorf("TELEGRAM_CHAT_ID must be set when PLATFORM is %q", platformTelegram)
+	}
+	if cfg.CompressBody != "" && cfg.Platform != platformGeneric {
+		return fmt.Errorf("COMPRESS_BODY is only supported when PLATFORM is %q; chat platforms reject compressed bodies", platformGeneric)
+	}
+	if cfg.Platform == platformPagerDuty && cfg.PagerDutyRoutingKey == "" {
+		return fmt.Errorf("PAGERDUTY_ROUTING_KEY must be set when PLATFORM is %q", platformPagerDuty)
+	}
+	if cfg.Platform == platformOpsgenie && cfg.OpsgenieAPIKey == "" {
+		return fmt.Errorf("OPSGENIE_API_KEY must be set when PLATFORM is %q", platformOpsgenie)
+	}
+	if cfg.Platform == platformOpsgenie && (cfg.AuthBearerToken != "" || cfg.AuthBasicUser != "" || cfg.SigV4Service != "") {
+		return fmt.Errorf("PLATFORM %q authenticates with OPSGENIE_API_KEY and can't be combined with AUTH_BEARER_TOKEN, AUTH_BASIC_USER or AWS_SIGV4_SERVICE", platformOpsgenie)
+	}
+	if cfg.AuthBearerToken != "" && cfg.AuthBasicUser != "" {
+		return fmt.Errorf("set either AUTH_BEARER_TOKEN or AUTH_BASIC_USER, not both")
+	}
+	if cfg.AuthBasicPass != "" && cfg.AuthBasicUser == "" {
+		return fmt.Errorf("AUTH_BASIC_PASS requires AUTH_BASIC_USER")
+	}
+	if cfg.SigV4Service != "" && (cfg.AuthBearerToken != "" || cfg.AuthBasicUser != "") {
+		return fmt.Errorf("AWS_SIGV4_SERVICE sets its own Authorization header and can't be combined with AUTH_BEARER_TOKEN or AUTH_BASIC_USER")
+	}
+
+	if cfg.DiscordThreadID != "" && cfg.DiscordThreadName != "" {
+		return fmt.Errorf("set either DISCORD_THREAD_ID or DISCORD_THREAD_NAME, not both")
+	}
+	if cfg.DiscordThreadID != "" && strings.Trim(cfg.DiscordThreadID, "0123456789") != "" {
+		return fmt.Errorf("DISCORD_THREAD_ID must be a numeric thread ID, got %q", cfg.DiscordThreadID)
+	}
+
+	if cfg.AuthorURL != "" && !isHTTPURL(cfg.AuthorURL) {
+		return fmt.Errorf("AUTHOR_URL must be an http(s) URL")
+	}
+	if cfg.AuthorIconURL != "" && !isHTTPURL(cfg.AuthorIconURL) {
+		return fmt.Errorf("AUTHOR_ICON_URL must be an http(s) URL")
+	}
+	if cfg.FooterIconURL != "" && !isHTTPURL(cfg.FooterIconURL) {
+		return fmt.Errorf("FOOTER_ICON_URL must be an http(s) URL")
+	}
+
+	if cfg.ShortenURL && cfg.ShortenerURL == "" {
+		return fmt.Errorf("SHORTENER_URL must be set when SHORTEN_URL is on")
+	}
+	if cfg.ShortenURL {
+		if err := validateWebhookURL(cfg.ShortenerURL, cfg.AllowPrivateTargets); err != nil {
+			return fmt.Errorf("SHORTENER_URL: %w", err)
+		}
+	}
+
+	if cfg.ProxyURL == "" {
+		if err := validateEnvironmentProxies(); err != nil {
+			return err
+		}
+	}
+
+	for i, webhookURL := range cfg.WebhookURLs {
+		if err := validateWebhookURL(webhookURL, cfg.AllowPrivateTargets); err != nil {
+			return fmt.Errorf("%s: %w", describeWebhook(i, webhookURL), err)
+		}
+	}
+	// Each destination must be deliverable on its own, with the defaults it inherits
+	for i, dest := range cfg.Destinations {
+		if err := ValidateConfig(destinationConfig(cfg, dest)); err != nil {
+			return fmt.Errorf("DESTINATIONS entry %d: %w", i, err)
+		}
+	}
+	for i, rule := range cfg.RoutingRules {
+		if err := validateWebhookURL(rule.WebhookURL, cfg.AllowPrivateTargets); err != nil {
+			return fmt.Errorf("ROUTING_RULES rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// configWarnings lists settings that are accepted but have no effect with the
+// rest of the configuration, so a typo or leftover doesn't go unnoticed
+func configWarnings(cfg Config) []string {
+	var warnings []string
+	if cfg.BatchMessages && cfg.Platform != platformDiscord {
+		warnings = append(warnings, fmt.Sprintf("BATCH_MESSAGES only applies when PLATFORM is %q", platformDiscord))
+	}
+	if cfg.AttachFiles && cfg.Platform != platformDiscord {
+		warnings = append(warnings, fmt.Sprintf("ATTACH_FILES only applies when PLATFORM is %q", platformDiscord))
+	}
+	if !cfg.UseEmbed && cfg.Platform != platformDiscord {
+		warnings = append(warnings, fmt.Sprintf("USE_EMBED only applies when PLATFORM is %q", platformDiscord))
+	}
+	if cfg.BatchMessages && len(cfg.Destinations) > 0 {
+		warnings = append(warnings, "BATCH_MESSAGES has no effect with DESTINATIONS; each file is sent separately")
+	}
+	if (cfg.DiscordThreadID != "" || cfg.DiscordThreadName != "") && cfg.Platform != platformDiscord {
+		warnings = append(warnings, fmt.Sprintf("DISCORD_THREAD_ID and DISCORD_THREAD_NAME only apply when PLATFORM is %q", platformDiscord))
+	}
+	if cfg.MentionParse != nil && cfg.AllowEveryone {
+		warnings = append(warnings, "ALLOW_EVERYONE has no effect with MENTION_PARSE; add everyone to MENTION_PARSE instead")
+	}
+	if cfg.ShowImageThumbnail && (cfg.Platform != platformDiscord || !cfg.UseEmbed) {
+		warnings = append(warnings, fmt.Sprintf("SHOW_IMAGE_THUMBNAIL only applies to embeds when PLATFORM is %q", platformDiscord))
+	}
+	if cfg.ShowImagePreview && (cfg.Platform != platformDiscord || !cfg.UseEmbed) {
+		warnings = append(warnings, fmt.Sprintf("SHOW_IMAGE_PREVIEW only applies to embeds when PLATFORM is %q", platformDiscord))
+	}
+	if !cfg.EmbedTimestamp && (cfg.Platform != platformDiscord || !cfg.UseEmbed) {
+		warnings = append(warnings, fmt.Sprintf("EMBED_TIMESTAMP only applies to embeds when PLATFORM is %q", platformDiscord))
+	}
+	if cfg.DiscordWait && cfg.Platform != platformDiscord {
+		warnings = append(warnings, fmt.Sprintf("DISCORD_WAIT only applies when PLATFORM is %q", platformDiscord))
+	}
+	if cfg.TelegramChatID != "" && cfg.Platform != platformTelegram {
+		warnings = append(warnings, fmt.Sprintf("TELEGRAM_CHAT_ID only applies when PLATFORM is %q", platformTelegram))
+	}
+	if cfg.SlackBlocks && cfg.Platform != platformSlack {
+		warnings = append(warnings, fmt.Sprintf("SLACK_BLOCKS only applies when PLATFORM is %q", platformSlack))
+	}
+	if (cfg.MattermostChannel != "" || cfg.MattermostUsername != "") && cfg.Platform != platformMattermost {
+		warnings = append(warnings, fmt.Sprintf("MATTERMOST_CHANNEL and MATTERMOST_USERNAME only apply when PLATFORM is %q", platformMattermost))
+	}
+	if cfg.GoogleChatSimple && cfg.Platform != platformGoogleChat {
+		warnings = append(warnings, fmt.Sprintf("GOOGLECHAT_SIMPLE only applies when PLATFORM is %q", platformGoogleChat))
+	}
+	if cfg.BodyTemplate != nil && cfg.Platform != platformGeneric {
+		warnings = append(warnings, fmt.Sprintf("BODY_TEMPLATE only applies when PLATFORM is %q", platformGeneric))
+	}
+	if cfg.AuthorTemplate == nil && (cfg.AuthorURL != "" || cfg.AuthorIconURL != "") {
+		warnings = append(warnings, "AUTHOR_URL and AUTHOR_ICON_URL have no effect without AUTHOR_NAME")
+	}
+	if cfg.FollowRedirects && cfg.AcceptedStatusCodes.acceptsRedirect() {
+		warnings = append(warnings, "FOLLOW_REDIRECTS has no effect while ACCEPTED_STATUS_CODES accepts a 3xx status")
+	}
+	if cfg.TLSInsecureSkipVerify {
+		warnings = append(warnings, "TLS_INSECURE_SKIP_VERIFY is on: webhook certificates are NOT verified and traffic can be intercepted; use only for local development")
+	}
+	if !cfg.ShortenURL && cfg.ShortenerURL != "" {
+		warnings = append(warnings, "SHORTENER_URL has no effect unless SHORTEN_URL is on")
+	}
+	if cfg.AttemptTimeout > 0 && cfg.AttemptTimeout >= cfg.RequestTimeout {
+		warnings = append(warnings, "PER_ATTEMPT_TIMEOUT_SECONDS is not below REQUEST_TIMEOUT_SECONDS, so a slow first attempt leaves no time for a retry")
+	}
+	if cfg.DryRun {
+		warnings = append(warnings, "DRY_RUN is on; no webhook requests will be sent")
+	}
+	return warnings
+}
+
+// validateWebhookURL requires an absolute https URL and, unless private targets
+// are allowed, rejects hosts that are internal IP addresses or localhost
+func validateWebhookURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", stripURL(err))
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("webhook URL must use https://, got scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("webhook URL has no host")
+	}
+
+	if allowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("webhook host %q is a private target; set ALLOW_PRIVATE_TARGETS=true to allow it", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return fmt.Errorf("webhook host %s is a private or link-local address; set ALLOW_PRIVATE_TARGETS=true to allow it", ip)
+	}
+	return nil
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL, which is all
+// Discord accepts for links in an embed
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isPrivateIP reports whether an address is loopback, private, link-local, or unspecified
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// blockPrivateDial is a net.Dialer control hook that refuses connections to
+// private addresses, catching hostnames that resolve to internal services
+func blockPrivateDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return fmt.Errorf("refusing to connect to private address %s; set ALLOW_PRIVATE_TARGETS=true to allow it", ip)
+	}
+	return nil
+}
+
+// dialContextFor returns the transport dialer, guarding against private targets
+// unless allowed. The proxies the transport resolved, from WEBHOOK_PROXY_URL or
+// HTTPS_PROXY and HTTP_PROXY, are always reachable since proxies usually sit on
+// a private address.
+func dialContextFor(allowPrivate bool, proxies *resolvedProxies) func(ctx context.Context, network, address string) (net.Conn, error) {
+	open := &net.Dialer{}
+	if allowPrivate {
+		return open.DialContext
+	}
+	guarded := &net.Dialer{Control: blockPrivateDial}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if proxies.has(address) {
+			return open.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+}
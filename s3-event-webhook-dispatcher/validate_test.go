@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr string
+	}{
+		{"https://discord.com/api/webhooks/1/token", ""},
+		{"http://discord.com/api/webhooks/1/token", "must use https://"},
+		{"discord.com/api/webhooks/1/token", "must use https://"},
+		{"https://169.254.169.254/latest/meta-data", "private or link-local"},
+		{"https://10.0.0.12/hook", "private or link-local"},
+		{"https://[::1]/hook", "private or link-local"},
+		{"https://localhost:8443/hook", "private target"},
+		{"https:///hook", "no host"},
+	}
+	for _, tt := range tests {
+		err := validateWebhookURL(tt.url, false)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("validateWebhookURL(%q) = %v", tt.url, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateWebhookURL(%q) = %v, want %q", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateWebhookURLAllowPrivate(t *testing.T) {
+	if err := validateWebhookURL("https://169.254.169.254/hook", true); err != nil {
+		t.Errorf("private target with ALLOW_PRIVATE_TARGETS: %v", err)
+	}
+	if err := validateWebhookURL("http://10.0.0.12/hook", true); err == nil {
+		t.Error("http:// with ALLOW_PRIVATE_TARGETS: want an error")
+	}
+}
+
+func TestHandlerRejectsPrivateWebhook(t *testing.T) {
+	setenvConfig(t, map[string]string{"WEBHOOK_URL": "https://169.254.169.254/hook"})
+	event := []byte(`{"detail-type":"File Uploaded","source":"s3-link-generator","detail":{"fileName":"a.pdf","fileUrl":"https://example.com/a.pdf"}}`)
+
+	_, err := Handler(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "ALLOW_PRIVATE_TARGETS") {
+		t.Errorf("Handler error = %v, want the private target rejected", err)
+	}
+}
+
+func TestBlockPrivateDial(t *testing.T) {
+	for address, blocked := range map[string]bool{
+		"169.254.169.254:443": true,
+		"127.0.0.1:443":       true,
+		"192.168.1.10:443":    true,
+		"162.159.135.232:443": false,
+	} {
+		if err := blockPrivateDial("tcp", address, nil); (err != nil) != blocked {
+			t.Errorf("blockPrivateDial(%s) = %v, want blocked %v", address, err, blocked)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	if err := ValidateConfig(valid); err != nil {
+		t.Fatalf("ValidateConfig(valid) = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"no webhook", func(cfg *Config) { cfg.WebhookURLs = nil }, "WEBHOOK_URL"},
+		{"no template", func(cfg *Config) { cfg.MessageTemplate = nil }, "MESSAGE_TEMPLATE"},
+		{"generic without body", func(cfg *Config) { cfg.Platform = platformGeneric }, "BODY_TEMPLATE"},
+		{"insecure webhook", func(cfg *Config) { cfg.WebhookURLs = []string{"http://discord.com/api/webhooks/1/token"} }, "https://"},
+		{"two auth modes", func(cfg *Config) { cfg.AuthBearerToken, cfg.AuthBasicUser = "t", "u" }, "not both"},
+		{"bad footer icon", func(cfg *Config) { cfg.FooterIconURL = "ftp://example.com/i.png" }, "FOOTER_ICON_URL"},
+	}
+	for _, tt := range tests {
+		cfg := valid
+		tt.modify(&cfg)
+		err := ValidateConfig(cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: ValidateConfig = %v, want an error mentioning %s", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := ValidateConfig(Config{}); !errors.Is(err, ErrNoWebhookURL) {
+		t.Errorf("ValidateConfig(empty) = %v, want ErrNoWebhookURL", err)
+	}
+}
+
+func TestCheckConfigAtStartup(t *testing.T) {
+	setenvConfig(t, nil)
+	if err := checkConfigAtStartup(context.Background()); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	setenvConfig(t, map[string]string{"WEBHOOK_URL": ""})
+	if err := checkConfigAtStartup(context.Background()); !errors.Is(err, ErrNoWebhookURL) {
+		t.Errorf("missing webhook: %v, want ErrNoWebhookURL", err)
+	}
+
+	setenvConfig(t, map[string]string{"MESSAGE_TEMPLATE": "{{.Bucket"})
+	if err := checkConfigAtStartup(context.Background()); err == nil {
+		t.Error("unparseable template passed the startup check")
+	}
+}
+
+func TestConfigWarnings(t *testing.T) {
+	cfg := testConfig(t, "https://discord.com/api/webhooks/1/token")
+	if warnings := configWarnings(cfg); len(warnings) != 0 {
+		t.Errorf("warnings for the default config: %q", warnings)
+	}
+
+	// Recoverable leftovers only warn; the config still validates
+	cfg.Platform = platformSlack
+	cfg.MessageTemplate = platformMessageTemplate(cfg)
+	cfg.AttachFiles = true
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	warnings := configWarnings(cfg)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "ATTACH_FILES") {
+		t.Errorf("warnings = %q, want one about ATTACH_FILES", warnings)
+	}
+}
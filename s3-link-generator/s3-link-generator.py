@@ -34,6 +34,7 @@ def lambda_handler(event, context):
         # Extract S3 bucket and object key from the event
         bucket_name = event.get('detail', {}).get('bucket', {}).get('name')
         object_key = event.get('detail', {}).get('object', {}).get('key')
+        object_size = event.get('detail', {}).get('object', {}).get('size')
         
         if not bucket_name or not object_key:
             logger.error("Failed to extract bucket name or object key from event")
@@ -72,6 +73,8 @@ def lambda_handler(event, context):
             'expirationTime': expiration_text,
             'timestamp': datetime.utcnow().isoformat()
         }
+        if object_size is not None:
+            payload['fileSize'] = object_size
         
         # Get EventBridge configuration from environment variables
         event_source = os.environ.get('EVENT_SOURCE', 's3-link-generator')